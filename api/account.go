@@ -2,18 +2,59 @@ package api
 
 import (
 	"database/sql"
+	"errors"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 
 	db "tech-school/db/sqlc"
+	"tech-school/money"
+	"tech-school/token"
 )
 
 type listAccountsRequest struct {
-	PageID   int32 `form:"page_id" binding:"required,min=1"`
-	PageSize int32 `form:"page_size" binding:"required,min=5,max=10"`
+	PageID   int32  `form:"page_id" binding:"omitempty,min=1"`
+	PageSize int32  `form:"page_size" binding:"required,min=5,max=100"`
+	Cursor   string `form:"cursor" binding:"excluded_with=PageID"`
 }
 
+type accountResponse struct {
+	ID      int64       `json:"id"`
+	Owner   string      `json:"owner"`
+	Balance money.Money `json:"balance"`
+	// AvailableBalance is the balance minus the active holds. It is only reported for a single account.
+	AvailableBalance *money.Money `json:"available_balance,omitempty"`
+	Status           string       `json:"status"`
+	CreatedAt        time.Time    `json:"created_at"`
+}
+
+func newAccountResponse(account db.Account) accountResponse {
+	return accountResponse{
+		ID:        account.ID,
+		Owner:     account.Owner,
+		Balance:   money.Money{Amount: account.Balance, Currency: account.Currency},
+		Status:    account.Status,
+		CreatedAt: account.CreatedAt,
+	}
+}
+
+func newAccountResponses(accounts []db.Account) []accountResponse {
+	rsp := make([]accountResponse, len(accounts))
+	for i, account := range accounts {
+		rsp[i] = newAccountResponse(account)
+	}
+
+	return rsp
+}
+
+type listAccountsResponse struct {
+	Accounts   []accountResponse `json:"accounts"`
+	NextCursor string            `json:"next_cursor,omitempty"`
+}
+
+// listAccounts pages through the caller's accounts with signed keyset cursors.
+// Requests carrying page_id keep the legacy offset behaviour and response shape.
 func (s *Server) listAccounts(ctx *gin.Context) {
 	var req listAccountsRequest
 	if err := ctx.ShouldBindQuery(&req); err != nil {
@@ -21,18 +62,59 @@ func (s *Server) listAccounts(ctx *gin.Context) {
 		return
 	}
 
-	arg := db.ListAccountsParams{
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	if req.PageID > 0 {
+		s.listAccountsByOffset(ctx, authPayload.Username, req)
+		return
+	}
+
+	after, ok := s.decodeCursor(ctx, req.Cursor)
+	if !ok {
+		return
+	}
+
+	arg := db.ListAccountsByOwnerAfterParams{
+		Owner:          authPayload.Username,
+		AfterCreatedAt: after.CreatedAt,
+		AfterID:        after.ID,
+		PageSize:       req.PageSize + 1,
+	}
+
+	accounts, err := s.store.ListAccountsByOwnerAfter(ctx, arg)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	accounts, next, err := nextPage(s.cursors, accounts, req.PageSize, func(a db.Account) pageCursor {
+		return pageCursor{CreatedAt: a.CreatedAt, ID: a.ID}
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, listAccountsResponse{
+		Accounts:   newAccountResponses(accounts),
+		NextCursor: next,
+	})
+}
+
+func (s *Server) listAccountsByOffset(ctx *gin.Context, owner string, req listAccountsRequest) {
+	arg := db.ListAccountsByOwnerParams{
+		Owner:  owner,
 		Limit:  req.PageSize,
 		Offset: (req.PageID - 1) * req.PageSize,
 	}
 
-	accounts, err := s.store.ListAccounts(ctx, arg)
+	accounts, err := s.store.ListAccountsByOwner(ctx, arg)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
 
-	ctx.JSON(http.StatusOK, accounts)
+	ctx.JSON(http.StatusOK, newAccountResponses(accounts))
 }
 
 type getAccountRequest struct {
@@ -46,22 +128,59 @@ func (s *Server) getAccount(ctx *gin.Context) {
 		return
 	}
 
-	account, err := s.store.GetAccount(ctx, req.ID)
+	account, ok := s.ownedAccount(ctx, req.ID)
+	if !ok {
+		return
+	}
+
+	held, err := s.store.GetAccountHeldAmount(ctx, account.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	rsp := newAccountResponse(account)
+	rsp.AvailableBalance = &money.Money{Amount: account.Balance - held, Currency: account.Currency}
+
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+// loadAccount loads the account.
+// It writes the error response itself and reports whether the handler may proceed.
+func (s *Server) loadAccount(ctx *gin.Context, accountID int64) (db.Account, bool) {
+	account, err := s.store.GetAccount(ctx, accountID)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			ctx.JSON(http.StatusNotFound, errorResponse(err))
-			return
+			return account, false
 		}
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
-		return
+		return account, false
 	}
 
-	ctx.JSON(http.StatusOK, account)
+	return account, true
+}
+
+// ownedAccount loads the account and checks that it belongs to the authenticated user.
+// It writes the error response itself and reports whether the handler may proceed.
+func (s *Server) ownedAccount(ctx *gin.Context, accountID int64) (db.Account, bool) {
+	account, ok := s.loadAccount(ctx, accountID)
+	if !ok {
+		return account, false
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if account.Owner != authPayload.Username {
+		err := errors.New("account doesn't belong to the authenticated user")
+		ctx.JSON(http.StatusForbidden, errorResponse(err))
+		return account, false
+	}
+
+	return account, true
 }
 
 type createAccountRequest struct {
-	Owner    string `json:"owner" binding:"required"`
-	Currency string `json:"currency" binding:"required,oneof=USD EUR"`
+	Currency string `json:"currency" binding:"required,currency"`
 }
 
 func (s *Server) createAccount(ctx *gin.Context) {
@@ -71,17 +190,75 @@ func (s *Server) createAccount(ctx *gin.Context) {
 		return
 	}
 
-	arg := db.CreateAccountParams{
-		Owner:    req.Owner,
-		Currency: req.Currency,
-		Balance:  0,
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	setAuditStatus(ctx, http.StatusCreated)
+
+	idempotency, ok := idempotencyParams(ctx, authPayload.Username, req, http.StatusCreated)
+	if !ok || s.replayIdempotentResponse(ctx, idempotency, renderStored(newAccountResponse)) {
+		return
 	}
 
-	account, err := s.store.CreateAccount(ctx, arg)
+	arg := db.CreateAccountTxParams{
+		CreateAccountParams: db.CreateAccountParams{
+			Owner:    authPayload.Username,
+			Currency: req.Currency,
+			Balance:  0,
+		},
+		Idempotency: idempotency,
+	}
+
+	account, err := s.store.CreateAccountTx(ctx, arg)
 	if err != nil {
+		if errors.Is(err, db.ErrDuplicateIdempotencyKey) && s.replayIdempotentResponse(ctx, idempotency, renderStored(newAccountResponse)) {
+			return
+		}
+		ctx.JSON(dbErrorStatus(err), errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, newAccountResponse(account))
+}
+
+type updateAccountStatusRequest struct {
+	Status string `json:"status" binding:"required,oneof=active frozen closed"`
+}
+
+// updateAccountStatus freezes, unfreezes or closes any account. It is reserved to admins.
+func (s *Server) updateAccountStatus(ctx *gin.Context) {
+	var uri getAccountRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	var req updateAccountStatusRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	arg := db.UpdateAccountStatusTxParams{
+		ID:     uri.ID,
+		Status: req.Status,
+	}
+
+	account, err := s.store.UpdateAccountStatusTx(ctx, arg)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		case errors.Is(err, db.ErrAccountClosed):
+			ctx.JSON(http.StatusConflict, errorResponse(err))
+			return
+		case errors.Is(err, db.ErrAccountBalanceNotZero):
+			ctx.JSON(http.StatusUnprocessableEntity, errorResponse(err))
+			return
+		}
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
 
-	ctx.JSON(http.StatusCreated, account)
+	ctx.JSON(http.StatusOK, newAccountResponse(account))
 }
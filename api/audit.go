@@ -0,0 +1,226 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	db "tech-school/db/sqlc"
+)
+
+const (
+	requestIDHeader = "X-Request-ID"
+	requestIDKey    = "request_id"
+)
+
+// requestIDMiddleware tags every request with the ID given by the client in the X-Request-ID header,
+// or with a random one, and echoes it in the response.
+func requestIDMiddleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		requestID := db.AuditRequestID(ctx.GetHeader(requestIDHeader))
+
+		ctx.Set(requestIDKey, requestID)
+		ctx.Header(requestIDHeader, requestID)
+		ctx.Next()
+	}
+}
+
+// auditMiddleware audits every POST, PUT, PATCH and DELETE call, whatever its outcome. It carries a db.AuditCall
+// in the request context: the store transactions append the changes they commit to the audit log themselves,
+// which is why the handlers only change resources with them. The calls that changed nothing,
+// such as the failed ones, are appended once handled, the resource named after the route.
+// It must run after requestIDMiddleware, and the router must fall back to the request context, see NewServer.
+func auditMiddleware(store db.Store) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		switch ctx.Request.Method {
+		case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		default:
+			ctx.Next()
+			return
+		}
+
+		route := ctx.FullPath()
+		if route == "" {
+			route = ctx.Request.URL.Path
+		}
+
+		call := &db.AuditCall{
+			Action:       ctx.Request.Method + " " + route,
+			ResourceType: auditResourceType(route),
+			ResourceID:   ctx.Param("id"),
+			RequestID:    ctx.GetString(requestIDKey),
+			ClientIP:     ctx.ClientIP(),
+			StatusCode:   http.StatusOK,
+		}
+		ctx.Request = ctx.Request.WithContext(db.WithAuditCall(ctx.Request.Context(), call))
+
+		ctx.Next()
+
+		if call.Audited() {
+			return
+		}
+
+		// Nothing was changed: the entry is only written on a best-effort basis, even if the client
+		// has gone away meanwhile.
+		arg := call.Entry(int32(ctx.Writer.Status()), nil)
+		if _, err := store.CreateAuditLogTx(context.Background(), arg); err != nil {
+			log.Printf("failed to write the audit log of %s request [%s]: %v", arg.Action, arg.RequestID, err)
+		}
+	}
+}
+
+// setAuditActor names the authenticated user as the actor of the audited call, if any.
+func setAuditActor(ctx *gin.Context, username string) {
+	if call, ok := db.AuditCallFromContext(ctx.Request.Context()); ok {
+		call.Actor = username
+	}
+}
+
+// setAuditStatus sets the status the audited call responds with when it succeeds, if it isn't 200 OK.
+// It must be called before the change is made.
+func setAuditStatus(ctx *gin.Context, status int) {
+	if call, ok := db.AuditCallFromContext(ctx.Request.Context()); ok {
+		call.StatusCode = int32(status)
+	}
+}
+
+// auditResourceType names the resource of a route after its first segment, such as scheduled_transfers
+// for /scheduled-transfers/:id.
+func auditResourceType(route string) string {
+	segment, _, _ := strings.Cut(strings.TrimPrefix(route, "/"), "/")
+
+	return strings.ReplaceAll(segment, "-", "_")
+}
+
+type auditLogResponse struct {
+	ID           int64           `json:"id"`
+	Actor        string          `json:"actor,omitempty"`
+	Action       string          `json:"action"`
+	ResourceType string          `json:"resource_type"`
+	ResourceID   string          `json:"resource_id,omitempty"`
+	RequestID    string          `json:"request_id"`
+	ClientIP     string          `json:"client_ip"`
+	Before       json.RawMessage `json:"before"`
+	After        json.RawMessage `json:"after"`
+	Outcome      string          `json:"outcome"`
+	StatusCode   int32           `json:"status_code"`
+	CreatedAt    time.Time       `json:"created_at"`
+	PrevHash     string          `json:"prev_hash"`
+	Hash         string          `json:"hash"`
+}
+
+func newAuditLogResponse(entry db.AuditLog) auditLogResponse {
+	return auditLogResponse{
+		ID:           entry.ID,
+		Actor:        entry.Actor.String,
+		Action:       entry.Action,
+		ResourceType: entry.ResourceType,
+		ResourceID:   entry.ResourceID.String,
+		RequestID:    entry.RequestID,
+		ClientIP:     entry.ClientIp,
+		Before:       entry.Before,
+		After:        entry.After,
+		Outcome:      entry.Outcome,
+		StatusCode:   entry.StatusCode,
+		CreatedAt:    entry.CreatedAt,
+		PrevHash:     entry.PrevHash,
+		Hash:         entry.Hash,
+	}
+}
+
+type listAuditLogRequest struct {
+	Actor        string `form:"actor"`
+	ResourceType string `form:"resource_type"`
+	ResourceID   string `form:"resource_id"`
+	PageID       int32  `form:"page_id" binding:"required,min=1"`
+	PageSize     int32  `form:"page_size" binding:"required,min=5,max=100"`
+}
+
+// listAuditLog lists the audit log, the latest entries first, optionally filtered by actor or resource.
+// It is reserved to admins.
+func (s *Server) listAuditLog(ctx *gin.Context) {
+	var req listAuditLogRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	arg := db.ListAuditLogParams{
+		Actor:        sql.NullString{String: req.Actor, Valid: req.Actor != ""},
+		ResourceType: sql.NullString{String: req.ResourceType, Valid: req.ResourceType != ""},
+		ResourceID:   sql.NullString{String: req.ResourceID, Valid: req.ResourceID != ""},
+		PageSize:     req.PageSize,
+		PageOffset:   (req.PageID - 1) * req.PageSize,
+	}
+
+	entries, err := s.store.ListAuditLog(ctx, arg)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	rsp := make([]auditLogResponse, len(entries))
+	for i, entry := range entries {
+		rsp[i] = newAuditLogResponse(entry)
+	}
+
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+const auditLogVerifyBatchSize = 1000
+
+type verifyAuditLogResponse struct {
+	Valid bool `json:"valid"`
+	// Entries is the number of entries verified, up to the first tampered one.
+	Entries int    `json:"entries"`
+	Error   string `json:"error,omitempty"`
+}
+
+// verifyAuditLog walks the whole audit log to check its hash chain. It is reserved to admins.
+func (s *Server) verifyAuditLog(ctx *gin.Context) {
+	var (
+		rsp      = verifyAuditLogResponse{Valid: true}
+		prevHash string
+		afterID  int64
+	)
+
+	for {
+		entries, err := s.store.ListAuditLogAfter(ctx, db.ListAuditLogAfterParams{
+			ID:    afterID,
+			Limit: auditLogVerifyBatchSize,
+		})
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+
+		for _, entry := range entries {
+			prevHash, err = db.VerifyAuditLog(prevHash, []db.AuditLog{entry})
+			if err != nil {
+				if !errors.Is(err, db.ErrAuditLogTampered) {
+					ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+					return
+				}
+				rsp.Valid = false
+				rsp.Error = err.Error()
+				ctx.JSON(http.StatusOK, rsp)
+				return
+			}
+			rsp.Entries++
+			afterID = entry.ID
+		}
+
+		if len(entries) < auditLogVerifyBatchSize {
+			break
+		}
+	}
+
+	ctx.JSON(http.StatusOK, rsp)
+}
@@ -0,0 +1,403 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+
+	mockdb "tech-school/db/mock"
+	db "tech-school/db/sqlc"
+	"tech-school/internal/testutil"
+	"tech-school/token"
+	"tech-school/util"
+)
+
+func TestAuditMiddleware(t *testing.T) {
+	user, _ := testutil.RandomUser(t)
+
+	testCases := []struct {
+		name       string
+		method     string
+		requestID  string
+		setupAuth  func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		handler    func(s *Server, ctx *gin.Context)
+		checkAudit func(t *testing.T, recorder *httptest.ResponseRecorder, arg *db.CreateAuditLogTxParams)
+	}{
+		{
+			name:      "OK",
+			method:    http.MethodPost,
+			requestID: "request-1",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			},
+			handler: func(s *Server, ctx *gin.Context) {
+				setAuditStatus(ctx, http.StatusCreated)
+				ctx.JSON(http.StatusCreated, gin.H{})
+			},
+			checkAudit: func(t *testing.T, recorder *httptest.ResponseRecorder, arg *db.CreateAuditLogTxParams) {
+				require.Equal(t, http.StatusCreated, recorder.Code)
+				require.Equal(t, "request-1", recorder.Header().Get(requestIDHeader))
+
+				// Nothing was changed in a store transaction: the call is audited once handled.
+				require.NotNil(t, arg)
+				require.Equal(t, sql.NullString{String: user.Username, Valid: true}, arg.Actor)
+				require.Equal(t, "POST /audited/:id", arg.Action)
+				require.Equal(t, "audited", arg.ResourceType)
+				require.Equal(t, sql.NullString{String: "7", Valid: true}, arg.ResourceID)
+				require.Equal(t, "request-1", arg.RequestID)
+				require.Equal(t, "192.0.2.1", arg.ClientIp)
+				require.Nil(t, arg.Before)
+				require.Nil(t, arg.After)
+				require.Equal(t, db.AuditOutcomeSuccess, arg.Outcome)
+				require.Equal(t, int32(http.StatusCreated), arg.StatusCode)
+			},
+		},
+		{
+			name:   "Failure",
+			method: http.MethodDelete,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			},
+			handler: func(s *Server, ctx *gin.Context) {
+				ctx.JSON(http.StatusConflict, gin.H{})
+			},
+			checkAudit: func(t *testing.T, recorder *httptest.ResponseRecorder, arg *db.CreateAuditLogTxParams) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+
+				requestID := recorder.Header().Get(requestIDHeader)
+				require.NotEmpty(t, requestID)
+
+				require.NotNil(t, arg)
+				require.Equal(t, "DELETE /audited/:id", arg.Action)
+				require.Equal(t, "audited", arg.ResourceType)
+				require.Equal(t, sql.NullString{String: "7", Valid: true}, arg.ResourceID)
+				require.Equal(t, requestID, arg.RequestID)
+				require.Nil(t, arg.Before)
+				require.Nil(t, arg.After)
+				require.Equal(t, db.AuditOutcomeFailure, arg.Outcome)
+				require.Equal(t, int32(http.StatusConflict), arg.StatusCode)
+			},
+		},
+		{
+			name:   "NoAuthorization",
+			method: http.MethodPatch,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			},
+			handler: func(s *Server, ctx *gin.Context) {
+				ctx.JSON(http.StatusOK, gin.H{})
+			},
+			checkAudit: func(t *testing.T, recorder *httptest.ResponseRecorder, arg *db.CreateAuditLogTxParams) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+
+				require.NotNil(t, arg)
+				require.False(t, arg.Actor.Valid)
+				require.Equal(t, db.AuditOutcomeFailure, arg.Outcome)
+				require.Equal(t, int32(http.StatusUnauthorized), arg.StatusCode)
+			},
+		},
+		{
+			name:   "NotMutating",
+			method: http.MethodGet,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			},
+			handler: func(s *Server, ctx *gin.Context) {
+				ctx.JSON(http.StatusOK, gin.H{})
+			},
+			checkAudit: func(t *testing.T, recorder *httptest.ResponseRecorder, arg *db.CreateAuditLogTxParams) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.NotEmpty(t, recorder.Header().Get(requestIDHeader))
+				require.Nil(t, arg)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			var audited *db.CreateAuditLogTxParams
+
+			store := mockdb.NewMockStore(ctrl)
+			store.EXPECT().
+				CreateAuditLogTx(gomock.Any(), gomock.Any()).
+				AnyTimes().
+				DoAndReturn(func(_ interface{}, arg db.CreateAuditLogTxParams) (db.AuditLog, error) {
+					require.Nil(t, audited, "audited twice")
+					audited = &arg
+					return db.AuditLog{}, nil
+				})
+
+			server := newTestServer(t, store)
+			server.router.Handle(tc.method, "/audited/:id", authMiddleware(server.tokenMaker), func(ctx *gin.Context) {
+				tc.handler(server, ctx)
+			})
+
+			recorder := httptest.NewRecorder()
+			request, err := http.NewRequest(tc.method, "/audited/7", nil)
+			require.NoError(t, err)
+			request.RemoteAddr = "192.0.2.1:1234"
+
+			if tc.requestID != "" {
+				request.Header.Set(requestIDHeader, tc.requestID)
+			}
+
+			tc.setupAuth(t, request, server.tokenMaker)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkAudit(t, recorder, audited)
+		})
+	}
+}
+
+func TestListAuditLogAPI(t *testing.T) {
+	admin, _ := testutil.RandomUser(t)
+	admin.Role = db.UserRoleAdmin
+	user, _ := testutil.RandomUser(t)
+
+	entries := randomAuditLog(t, 5)
+
+	testCases := []struct {
+		name          string
+		query         string
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:  "OK",
+			query: "page_id=2&page_size=5&actor=" + user.Username + "&resource_type=accounts",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, admin.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(admin.Username)).Times(1).Return(admin, nil)
+
+				arg := db.ListAuditLogParams{
+					Actor:        sql.NullString{String: user.Username, Valid: true},
+					ResourceType: sql.NullString{String: "accounts", Valid: true},
+					PageSize:     5,
+					PageOffset:   5,
+				}
+				store.EXPECT().ListAuditLog(gomock.Any(), gomock.Eq(arg)).Times(1).Return(entries, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				data, err := io.ReadAll(recorder.Body)
+				require.NoError(t, err)
+
+				var got []auditLogResponse
+				require.NoError(t, json.Unmarshal(data, &got))
+				require.Len(t, got, len(entries))
+				for i, entry := range entries {
+					require.Equal(t, entry.ID, got[i].ID)
+					require.Equal(t, entry.Action, got[i].Action)
+					require.Equal(t, entry.Hash, got[i].Hash)
+					require.Equal(t, entry.PrevHash, got[i].PrevHash)
+				}
+			},
+		},
+		{
+			name:  "NotAdmin",
+			query: "page_id=1&page_size=5",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().ListAuditLog(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:  "InvalidPageSize",
+			query: "page_id=1&page_size=1000",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, admin.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(admin.Username)).Times(1).Return(admin, nil)
+				store.EXPECT().ListAuditLog(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:  "InternalError",
+			query: "page_id=1&page_size=5",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, admin.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(admin.Username)).Times(1).Return(admin, nil)
+				store.EXPECT().ListAuditLog(gomock.Any(), gomock.Any()).Times(1).Return(nil, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			request, err := http.NewRequest(http.MethodGet, "/admin/audit?"+tc.query, nil)
+			require.NoError(t, err)
+
+			tc.setupAuth(t, request, server.tokenMaker)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestVerifyAuditLogAPI(t *testing.T) {
+	admin, _ := testutil.RandomUser(t)
+	admin.Role = db.UserRoleAdmin
+
+	entries := randomAuditLog(t, 3)
+
+	tampered := make([]db.AuditLog, len(entries))
+	copy(tampered, entries)
+	tampered[1].Outcome = db.AuditOutcomeSuccess
+	tampered[1].StatusCode = http.StatusOK
+	tampered[1].Actor = sql.NullString{String: admin.Username, Valid: true}
+
+	testCases := []struct {
+		name          string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "Valid",
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.ListAuditLogAfterParams{ID: 0, Limit: auditLogVerifyBatchSize}
+				store.EXPECT().ListAuditLogAfter(gomock.Any(), gomock.Eq(arg)).Times(1).Return(entries, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchVerifyAuditLog(t, recorder, verifyAuditLogResponse{Valid: true, Entries: 3})
+			},
+		},
+		{
+			name: "Tampered",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListAuditLogAfter(gomock.Any(), gomock.Any()).Times(1).Return(tampered, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchVerifyAuditLog(t, recorder, verifyAuditLogResponse{
+					Entries: 1,
+					Error:   fmt.Sprintf("%s: entry [%d] doesn't match its hash", db.ErrAuditLogTampered, tampered[1].ID),
+				})
+			},
+		},
+		{
+			name: "InternalError",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListAuditLogAfter(gomock.Any(), gomock.Any()).Times(1).Return(nil, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			store.EXPECT().GetUser(gomock.Any(), gomock.Eq(admin.Username)).Times(1).Return(admin, nil)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			request, err := http.NewRequest(http.MethodGet, "/admin/audit/verify", nil)
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, admin.Username, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+// randomAuditLog returns n chained audit log entries, in ID order.
+func randomAuditLog(t *testing.T, n int) []db.AuditLog {
+	entries := make([]db.AuditLog, n)
+
+	var prevHash string
+	for i := range entries {
+		arg := db.CreateAuditLogParams{
+			Actor:        sql.NullString{String: util.RandomOwner(), Valid: true},
+			Action:       "POST /accounts",
+			ResourceType: "accounts",
+			ResourceID:   sql.NullString{String: fmt.Sprint(util.RandomInt(1, 1000)), Valid: true},
+			RequestID:    util.RandomString(16),
+			ClientIp:     "127.0.0.1",
+			Before:       json.RawMessage("null"),
+			After:        json.RawMessage(`{"balance":0}`),
+			Outcome:      db.AuditOutcomeFailure,
+			StatusCode:   http.StatusForbidden,
+			CreatedAt:    time.Now().UTC().Truncate(time.Microsecond),
+			PrevHash:     prevHash,
+		}
+		arg.Hash = db.AuditLogHash(arg)
+		prevHash = arg.Hash
+
+		entries[i] = db.AuditLog{
+			ID:           int64(i + 1),
+			Actor:        arg.Actor,
+			Action:       arg.Action,
+			ResourceType: arg.ResourceType,
+			ResourceID:   arg.ResourceID,
+			RequestID:    arg.RequestID,
+			ClientIp:     arg.ClientIp,
+			Before:       arg.Before,
+			After:        arg.After,
+			Outcome:      arg.Outcome,
+			StatusCode:   arg.StatusCode,
+			CreatedAt:    arg.CreatedAt,
+			PrevHash:     arg.PrevHash,
+			Hash:         arg.Hash,
+		}
+	}
+
+	_, err := db.VerifyAuditLog("", entries)
+	require.NoError(t, err)
+
+	return entries
+}
+
+func requireBodyMatchVerifyAuditLog(t *testing.T, recorder *httptest.ResponseRecorder, want verifyAuditLogResponse) {
+	data, err := io.ReadAll(recorder.Body)
+	require.NoError(t, err)
+
+	var got verifyAuditLogResponse
+	require.NoError(t, json.Unmarshal(data, &got))
+	require.Equal(t, want, got)
+}
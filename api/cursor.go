@@ -0,0 +1,110 @@
+package api
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const minCursorKeySize = 32
+
+var errInvalidCursor = errors.New("cursor is invalid")
+
+// pageCursor points at the last row of a page in (created_at, id) order.
+type pageCursor struct {
+	CreatedAt time.Time `json:"t"`
+	ID        int64     `json:"i"`
+}
+
+// cursorCodec turns page cursors into opaque tokens signed with HMAC-SHA256,
+// so clients can't forge a position they were never handed.
+type cursorCodec struct {
+	key []byte
+}
+
+func newCursorCodec(key string) (*cursorCodec, error) {
+	if len(key) < minCursorKeySize {
+		return nil, fmt.Errorf("invalid key size: must be at least %d characters", minCursorKeySize)
+	}
+
+	return &cursorCodec{key: []byte(key)}, nil
+}
+
+func (c *cursorCodec) encode(cursor pageCursor) (string, error) {
+	data, err := json.Marshal(cursor)
+	if err != nil {
+		return "", err
+	}
+
+	payload := base64.RawURLEncoding.EncodeToString(data)
+
+	return payload + "." + c.sign(payload), nil
+}
+
+func (c *cursorCodec) decode(token string) (pageCursor, error) {
+	var cursor pageCursor
+
+	payload, signature, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(signature), []byte(c.sign(payload))) {
+		return cursor, errInvalidCursor
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return cursor, errInvalidCursor
+	}
+
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return cursor, errInvalidCursor
+	}
+
+	return cursor, nil
+}
+
+func (c *cursorCodec) sign(payload string) string {
+	mac := hmac.New(sha256.New, c.key)
+	mac.Write([]byte(payload))
+
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// decodeCursor parses the cursor query parameter. An empty token means the first page.
+// It writes the error response itself and reports whether the handler may proceed.
+func (s *Server) decodeCursor(ctx *gin.Context, token string) (pageCursor, bool) {
+	if token == "" {
+		return pageCursor{}, true
+	}
+
+	cursor, err := s.cursors.decode(token)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return cursor, false
+	}
+
+	return cursor, true
+}
+
+// nextPage trims the extra row fetched beyond pageSize and, if there was one,
+// returns the cursor pointing at the last row of the page.
+func nextPage[T any](c *cursorCodec, rows []T, pageSize int32, key func(T) pageCursor) ([]T, string, error) {
+	if len(rows) <= int(pageSize) {
+		return rows, "", nil
+	}
+
+	rows = rows[:pageSize]
+
+	next, err := c.encode(key(rows[len(rows)-1]))
+	if err != nil {
+		return nil, "", err
+	}
+
+	return rows, next, nil
+}
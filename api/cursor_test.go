@@ -0,0 +1,94 @@
+package api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	db "tech-school/db/sqlc"
+	"tech-school/internal/testutil"
+	"tech-school/util"
+)
+
+func TestCursorCodec(t *testing.T) {
+	codec, err := newCursorCodec(util.RandomString(32))
+	require.NoError(t, err)
+
+	cursor := pageCursor{
+		CreatedAt: time.Now().UTC().Truncate(time.Microsecond),
+		ID:        util.RandomInt(1, 1000),
+	}
+
+	token, err := codec.encode(cursor)
+	require.NoError(t, err)
+	require.NotEmpty(t, token)
+
+	got, err := codec.decode(token)
+	require.NoError(t, err)
+	require.Equal(t, cursor.ID, got.ID)
+	require.True(t, cursor.CreatedAt.Equal(got.CreatedAt))
+}
+
+func TestCursorCodecTampered(t *testing.T) {
+	codec, err := newCursorCodec(util.RandomString(32))
+	require.NoError(t, err)
+
+	token, err := codec.encode(pageCursor{CreatedAt: time.Now(), ID: 1})
+	require.NoError(t, err)
+
+	forged, err := codec.encode(pageCursor{CreatedAt: time.Now(), ID: 2})
+	require.NoError(t, err)
+
+	other, err := newCursorCodec(util.RandomString(32))
+	require.NoError(t, err)
+
+	for _, bad := range []string{
+		"",
+		"garbage",
+		token[:len(token)-1],
+		forged[:len(forged)/2] + token[len(token)/2:],
+	} {
+		_, err = codec.decode(bad)
+		require.ErrorIs(t, err, errInvalidCursor)
+	}
+
+	_, err = other.decode(token)
+	require.ErrorIs(t, err, errInvalidCursor)
+}
+
+func TestCursorCodecShortKey(t *testing.T) {
+	codec, err := newCursorCodec(util.RandomString(minCursorKeySize - 1))
+	require.Error(t, err)
+	require.Nil(t, codec)
+}
+
+func TestNextPage(t *testing.T) {
+	codec, err := newCursorCodec(util.RandomString(32))
+	require.NoError(t, err)
+
+	accounts := make([]db.Account, 6)
+	for i := range accounts {
+		accounts[i] = testutil.RandomAccount(util.RandomOwner())
+		accounts[i].ID = int64(i + 1)
+		accounts[i].CreatedAt = time.Now().UTC().Add(time.Duration(i) * time.Second)
+	}
+	key := func(a db.Account) pageCursor {
+		return pageCursor{CreatedAt: a.CreatedAt, ID: a.ID}
+	}
+
+	page, next, err := nextPage(codec, accounts, 5, key)
+	require.NoError(t, err)
+	require.Len(t, page, 5)
+	require.NotEmpty(t, next)
+
+	cursor, err := codec.decode(next)
+	require.NoError(t, err)
+	require.Equal(t, accounts[4].ID, cursor.ID)
+	require.True(t, accounts[4].CreatedAt.Equal(cursor.CreatedAt))
+
+	page, next, err = nextPage(codec, accounts[:5], 5, key)
+	require.NoError(t, err)
+	require.Len(t, page, 5)
+	require.Empty(t, next)
+}
@@ -0,0 +1,105 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	db "tech-school/db/sqlc"
+	"tech-school/money"
+)
+
+type listAccountEntriesRequest struct {
+	periodQuery
+	PageSize int32  `form:"page_size" binding:"required,min=5,max=100"`
+	Cursor   string `form:"cursor"`
+}
+
+type statementEntry struct {
+	ID             int64       `json:"id"`
+	Amount         money.Money `json:"amount"`
+	RunningBalance money.Money `json:"running_balance"`
+	CreatedAt      time.Time   `json:"created_at"`
+}
+
+type accountStatementResponse struct {
+	AccountID      int64            `json:"account_id"`
+	From           string           `json:"from,omitempty"`
+	To             string           `json:"to,omitempty"`
+	OpeningBalance money.Money      `json:"opening_balance"`
+	ClosingBalance money.Money      `json:"closing_balance"`
+	Entries        []statementEntry `json:"entries"`
+	NextCursor     string           `json:"next_cursor,omitempty"`
+}
+
+// listAccountEntries returns the statement of an account: its entries with a running balance,
+// and the opening and closing balance of the period. Both from and to are inclusive dates in UTC.
+func (s *Server) listAccountEntries(ctx *gin.Context) {
+	var uri getAccountRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	var req listAccountEntriesRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	after, ok := s.decodeCursor(ctx, req.Cursor)
+	if !ok {
+		return
+	}
+
+	account, ok := s.ownedAccount(ctx, uri.ID)
+	if !ok {
+		return
+	}
+
+	periodStart, periodEnd := req.bounds()
+
+	arg := db.AccountStatementTxParams{
+		AccountID:      uri.ID,
+		PeriodStart:    periodStart,
+		PeriodEnd:      periodEnd,
+		AfterCreatedAt: after.CreatedAt,
+		AfterID:        after.ID,
+		PageSize:       req.PageSize + 1,
+	}
+
+	result, err := s.store.AccountStatementTx(ctx, arg)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	rows, next, err := nextPage(s.cursors, result.Entries, req.PageSize, func(e db.ListAccountStatementRow) pageCursor {
+		return pageCursor{CreatedAt: e.CreatedAt, ID: e.ID}
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	rsp := accountStatementResponse{
+		AccountID:      uri.ID,
+		From:           formatPeriodDate(req.From),
+		To:             formatPeriodDate(req.To),
+		OpeningBalance: money.Money{Amount: result.OpeningBalance, Currency: account.Currency},
+		ClosingBalance: money.Money{Amount: result.ClosingBalance, Currency: account.Currency},
+		Entries:        make([]statementEntry, len(rows)),
+		NextCursor:     next,
+	}
+	for i, row := range rows {
+		rsp.Entries[i] = statementEntry{
+			ID:             row.ID,
+			Amount:         money.Money{Amount: row.Amount, Currency: account.Currency},
+			RunningBalance: money.Money{Amount: row.RunningBalance, Currency: account.Currency},
+			CreatedAt:      row.CreatedAt,
+		}
+	}
+
+	ctx.JSON(http.StatusOK, rsp)
+}
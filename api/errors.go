@@ -0,0 +1,40 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/lib/pq"
+
+	db "tech-school/db/sqlc"
+)
+
+const (
+	uniqueViolation     = "unique_violation"
+	foreignKeyViolation = "foreign_key_violation"
+)
+
+// dbErrorStatus maps postgres constraint violations, and transactions refused because an account
+// is frozen or closed, to HTTP status codes.
+// Any other error is reported as an internal server error.
+func dbErrorStatus(err error) int {
+	if errors.Is(err, db.ErrDuplicateIdempotencyKey) {
+		return http.StatusConflict
+	}
+
+	if errors.Is(err, db.ErrAccountFrozen) || errors.Is(err, db.ErrAccountClosed) {
+		return http.StatusUnprocessableEntity
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code.Name() {
+		case uniqueViolation:
+			return http.StatusConflict
+		case foreignKeyViolation:
+			return http.StatusForbidden
+		}
+	}
+
+	return http.StatusInternalServerError
+}
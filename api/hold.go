@@ -0,0 +1,258 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	db "tech-school/db/sqlc"
+	"tech-school/money"
+	"tech-school/token"
+)
+
+// maxHoldDuration is the longest time funds may stay reserved by a hold.
+const maxHoldDuration = 30 * 24 * time.Hour
+
+type holdResponse struct {
+	ID          int64       `json:"id"`
+	AccountID   int64       `json:"account_id"`
+	ToAccountID int64       `json:"to_account_id"`
+	Amount      money.Money `json:"amount"`
+	Status      string      `json:"status"`
+	ExpiresAt   time.Time   `json:"expires_at"`
+	TransferID  int64       `json:"transfer_id,omitempty"`
+	CreatedAt   time.Time   `json:"created_at"`
+	ResolvedAt  *time.Time  `json:"resolved_at,omitempty"`
+}
+
+func newHoldResponse(hold db.Hold, currency string) holdResponse {
+	rsp := holdResponse{
+		ID:          hold.ID,
+		AccountID:   hold.AccountID,
+		ToAccountID: hold.ToAccountID,
+		Amount:      money.Money{Amount: hold.Amount, Currency: currency},
+		Status:      hold.Status,
+		ExpiresAt:   hold.ExpiresAt,
+		TransferID:  hold.TransferID.Int64,
+		CreatedAt:   hold.CreatedAt,
+	}
+
+	if hold.ResolvedAt.Valid {
+		rsp.ResolvedAt = &hold.ResolvedAt.Time
+	}
+
+	return rsp
+}
+
+type placeHoldRequest struct {
+	AccountID   int64       `json:"account_id" binding:"required,min=1"`
+	ToAccountID int64       `json:"to_account_id" binding:"required,min=1,nefield=AccountID"`
+	Amount      money.Money `json:"amount" binding:"currency"`
+	ExpiresAt   time.Time   `json:"expires_at" binding:"required"`
+}
+
+// placeHold reserves funds of an account owned by the caller, to be captured later by the destination account.
+func (s *Server) placeHold(ctx *gin.Context) {
+	var req placeHoldRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if !req.Amount.IsPositive() {
+		err := errors.New("amount must be positive")
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if ttl := time.Until(req.ExpiresAt); ttl <= 0 || ttl > maxHoldDuration {
+		err := fmt.Errorf("expires_at must be in the future and at most %s from now", maxHoldDuration)
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	render := func(hold db.Hold) holdResponse {
+		return newHoldResponse(hold, req.Amount.Currency)
+	}
+
+	setAuditStatus(ctx, http.StatusCreated)
+
+	idempotency, ok := idempotencyParams(ctx, authPayload.Username, req, http.StatusCreated)
+	if !ok || s.replayIdempotentResponse(ctx, idempotency, renderStored(render)) {
+		return
+	}
+
+	account, ok := s.validAccount(ctx, req.AccountID, req.Amount.Currency)
+	if !ok {
+		return
+	}
+
+	if account.Owner != authPayload.Username {
+		err := errors.New("account doesn't belong to the authenticated user")
+		ctx.JSON(http.StatusForbidden, errorResponse(err))
+		return
+	}
+
+	if _, ok := s.validAccount(ctx, req.ToAccountID, req.Amount.Currency); !ok {
+		return
+	}
+
+	arg := db.PlaceHoldTxParams{
+		CreateHoldParams: db.CreateHoldParams{
+			AccountID:   req.AccountID,
+			ToAccountID: req.ToAccountID,
+			Amount:      req.Amount.Amount,
+			ExpiresAt:   req.ExpiresAt,
+		},
+		Idempotency: idempotency,
+	}
+
+	hold, err := s.store.PlaceHoldTx(ctx, arg)
+	if err != nil {
+		if errors.Is(err, db.ErrInsufficientFunds) {
+			ctx.JSON(http.StatusUnprocessableEntity, errorResponse(err))
+			return
+		}
+		if errors.Is(err, db.ErrDuplicateIdempotencyKey) && s.replayIdempotentResponse(ctx, idempotency, renderStored(render)) {
+			return
+		}
+		ctx.JSON(dbErrorStatus(err), errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, render(hold))
+}
+
+type holdURIRequest struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+type captureHoldRequest struct {
+	// Amount is optional: without it, the whole hold is captured.
+	Amount *money.Money `json:"amount"`
+}
+
+type captureHoldResponse struct {
+	Hold     holdResponse       `json:"hold"`
+	Transfer transferTxResponse `json:"transfer"`
+}
+
+// captureHold settles a hold with a transfer to its destination account, which must be owned by the caller.
+func (s *Server) captureHold(ctx *gin.Context) {
+	var uri holdURIRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	// The body is optional, so an empty one captures the whole hold.
+	var req captureHoldRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	hold, toAccount, ok := s.receivedHold(ctx, uri.ID)
+	if !ok {
+		return
+	}
+
+	arg := db.CaptureHoldTxParams{HoldID: hold.ID}
+
+	if req.Amount != nil {
+		if !req.Amount.IsPositive() {
+			err := errors.New("amount must be positive")
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+			return
+		}
+		if req.Amount.Currency != toAccount.Currency {
+			err := fmt.Errorf("hold [%d] currency mismatch: %s vs %s", hold.ID, toAccount.Currency, req.Amount.Currency)
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+			return
+		}
+		arg.Amount = req.Amount.Amount
+	}
+
+	result, err := s.store.CaptureHoldTx(ctx, arg)
+	if err != nil {
+		ctx.JSON(holdErrorStatus(err), errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, captureHoldResponse{
+		Hold:     newHoldResponse(result.Hold, toAccount.Currency),
+		Transfer: newTransferTxResponse(result.TransferTxResult),
+	})
+}
+
+// releaseHold cancels a hold. Only the owner of the destination account may give up the reserved funds.
+func (s *Server) releaseHold(ctx *gin.Context) {
+	var uri holdURIRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	hold, toAccount, ok := s.receivedHold(ctx, uri.ID)
+	if !ok {
+		return
+	}
+
+	hold, err := s.store.ReleaseHoldTx(ctx, hold.ID)
+	if err != nil {
+		ctx.JSON(holdErrorStatus(err), errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newHoldResponse(hold, toAccount.Currency))
+}
+
+// receivedHold loads the hold and its destination account, and checks that the account belongs to the authenticated user.
+// It writes the error response itself and reports whether the handler may proceed.
+func (s *Server) receivedHold(ctx *gin.Context, holdID int64) (db.Hold, db.Account, bool) {
+	hold, err := s.store.GetHold(ctx, holdID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return hold, db.Account{}, false
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return hold, db.Account{}, false
+	}
+
+	toAccount, err := s.store.GetAccount(ctx, hold.ToAccountID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return hold, toAccount, false
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if toAccount.Owner != authPayload.Username {
+		err := errors.New("hold isn't placed for an account of the authenticated user")
+		ctx.JSON(http.StatusForbidden, errorResponse(err))
+		return hold, toAccount, false
+	}
+
+	return hold, toAccount, true
+}
+
+// holdErrorStatus maps the errors of the hold transactions to HTTP status codes.
+func holdErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, db.ErrHoldNotActive):
+		return http.StatusConflict
+	case errors.Is(err, db.ErrHoldExpired),
+		errors.Is(err, db.ErrCaptureExceedsHold),
+		errors.Is(err, db.ErrInsufficientFunds):
+		return http.StatusUnprocessableEntity
+	}
+
+	return dbErrorStatus(err)
+}
@@ -0,0 +1,112 @@
+package api
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	db "tech-school/db/sqlc"
+)
+
+const (
+	idempotencyKeyHeader    = "Idempotency-Key"
+	maxIdempotencyKeyLength = 255
+)
+
+// idempotencyParams builds the idempotency parameters of the request from the Idempotency-Key header.
+// It returns nil params when the header is absent. The handler must stop when ok is false,
+// as the error response has already been written.
+func idempotencyParams(ctx *gin.Context, username string, req interface{}, status int) (params *db.IdempotencyParams, ok bool) {
+	key := ctx.GetHeader(idempotencyKeyHeader)
+	if key == "" {
+		return nil, true
+	}
+
+	if len(key) > maxIdempotencyKeyLength {
+		err := errors.New("idempotency key is too long")
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return nil, false
+	}
+
+	hash, err := requestHash(req)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return nil, false
+	}
+
+	return &db.IdempotencyParams{
+		Key:            key,
+		Username:       username,
+		RequestPath:    ctx.FullPath(),
+		RequestHash:    hash,
+		ResponseStatus: int32(status),
+	}, true
+}
+
+// requestHash returns the SHA-256 hex digest of the bound request.
+func requestHash(req interface{}) (string, error) {
+	data, err := json.Marshal(req)
+	if err != nil {
+		return "", err
+	}
+
+	hash := sha256.Sum256(data)
+
+	return hex.EncodeToString(hash[:]), nil
+}
+
+// storedResponse converts the store result saved with an idempotency key into the API response.
+type storedResponse func(body []byte) (interface{}, error)
+
+// renderStored decodes the saved store result as T and renders it with the same function
+// as the original response, so that a replay is indistinguishable from the first answer.
+func renderStored[T any, R any](render func(T) R) storedResponse {
+	return func(body []byte) (interface{}, error) {
+		var result T
+		if err := json.Unmarshal(body, &result); err != nil {
+			return nil, err
+		}
+
+		return render(result), nil
+	}
+}
+
+// replayIdempotentResponse writes the stored response of a previous request with the same idempotency key.
+// A key reused with a different payload is rejected with 422. It reports whether a response has been written.
+func (s *Server) replayIdempotentResponse(ctx *gin.Context, params *db.IdempotencyParams, render storedResponse) bool {
+	if params == nil {
+		return false
+	}
+
+	stored, err := s.store.GetIdempotencyKey(ctx, db.GetIdempotencyKeyParams{
+		Username: params.Username,
+		Key:      params.Key,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return false
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return true
+	}
+
+	if stored.RequestPath != params.RequestPath || stored.RequestHash != params.RequestHash {
+		err := errors.New("idempotency key has already been used with a different request")
+		ctx.JSON(http.StatusUnprocessableEntity, errorResponse(err))
+		return true
+	}
+
+	rsp, err := render(stored.ResponseBody)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return true
+	}
+
+	ctx.JSON(int(stored.ResponseStatus), rsp)
+	return true
+}
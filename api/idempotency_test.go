@@ -0,0 +1,224 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+
+	mockdb "tech-school/db/mock"
+	db "tech-school/db/sqlc"
+	"tech-school/internal/testutil"
+	"tech-school/util"
+)
+
+func TestCreateTransferIdempotencyAPI(t *testing.T) {
+	user, _ := testutil.RandomUser(t)
+
+	account1 := testutil.RandomAccount(user.Username)
+	account2 := testutil.RandomAccount(user.Username)
+	account2.ID = account1.ID + 1
+
+	key := util.RandomString(16)
+	body := gin.H{
+		"from_account_id": account1.ID,
+		"to_account_id":   account2.ID,
+		"amount":          "0.10 USD",
+	}
+
+	storedResult := db.TransferTxResult{
+		FromAccount: account1,
+		ToAccount:   account2,
+	}
+	storedBody, err := json.Marshal(storedResult)
+	require.NoError(t, err)
+
+	replayedBody, err := json.Marshal(newTransferTxResponse(storedResult))
+	require.NoError(t, err)
+
+	storedKey := func(requestHash string) db.IdempotencyKey {
+		return db.IdempotencyKey{
+			Key:            key,
+			Username:       user.Username,
+			RequestPath:    "/transfers",
+			RequestHash:    requestHash,
+			ResponseStatus: http.StatusOK,
+			ResponseBody:   storedBody,
+		}
+	}
+
+	testCases := []struct {
+		name          string
+		buildStubs    func(store *mockdb.MockStore, requestHash string)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "FirstRequest",
+			buildStubs: func(store *mockdb.MockStore, requestHash string) {
+				store.EXPECT().
+					GetIdempotencyKey(gomock.Any(), gomock.Eq(db.GetIdempotencyKeyParams{Username: user.Username, Key: key})).
+					Times(1).
+					Return(db.IdempotencyKey{}, sql.ErrNoRows)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(1).Return(account2, nil)
+
+				arg := db.TransferTxParams{
+					FromAccountID: util.SQLNullInt64(account1.ID),
+					ToAccountID:   util.SQLNullInt64(account2.ID),
+					Amount:        10,
+					Idempotency: &db.IdempotencyParams{
+						Key:            key,
+						Username:       user.Username,
+						RequestPath:    "/transfers",
+						RequestHash:    requestHash,
+						ResponseStatus: http.StatusOK,
+					},
+				}
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Eq(arg)).Times(1).Return(storedResult, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.JSONEq(t, string(replayedBody), recorder.Body.String())
+			},
+		},
+		{
+			name: "Replay",
+			buildStubs: func(store *mockdb.MockStore, requestHash string) {
+				store.EXPECT().
+					GetIdempotencyKey(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(storedKey(requestHash), nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.JSONEq(t, string(replayedBody), recorder.Body.String())
+			},
+		},
+		{
+			name: "DifferentPayload",
+			buildStubs: func(store *mockdb.MockStore, requestHash string) {
+				store.EXPECT().
+					GetIdempotencyKey(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(storedKey("another-hash"), nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+			},
+		},
+		{
+			name: "ConcurrentDuplicate",
+			buildStubs: func(store *mockdb.MockStore, requestHash string) {
+				gomock.InOrder(
+					store.EXPECT().
+						GetIdempotencyKey(gomock.Any(), gomock.Any()).
+						Times(1).
+						Return(db.IdempotencyKey{}, sql.ErrNoRows),
+					store.EXPECT().
+						GetIdempotencyKey(gomock.Any(), gomock.Any()).
+						Times(1).
+						Return(storedKey(requestHash), nil),
+				)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(1).Return(account2, nil)
+				store.EXPECT().
+					TransferTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.TransferTxResult{}, db.ErrDuplicateIdempotencyKey)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.JSONEq(t, string(replayedBody), recorder.Body.String())
+			},
+		},
+		{
+			name: "InternalError",
+			buildStubs: func(store *mockdb.MockStore, requestHash string) {
+				store.EXPECT().
+					GetIdempotencyKey(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.IdempotencyKey{}, sql.ErrConnDone)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store, transferRequestHash(t, body))
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/transfers", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			request.Header.Set(idempotencyKeyHeader, key)
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestIdempotencyKeyTooLong(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	user, _ := testutil.RandomUser(t)
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().GetIdempotencyKey(gomock.Any(), gomock.Any()).Times(0)
+	store.EXPECT().CreateAccountTx(gomock.Any(), gomock.Any()).Times(0)
+
+	server := newTestServer(t, store)
+	recorder := httptest.NewRecorder()
+
+	data, err := json.Marshal(gin.H{"currency": "USD"})
+	require.NoError(t, err)
+
+	request, err := http.NewRequest(http.MethodPost, "/accounts", bytes.NewReader(data))
+	require.NoError(t, err)
+
+	request.Header.Set(idempotencyKeyHeader, util.RandomString(maxIdempotencyKeyLength+1))
+	addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusBadRequest, recorder.Code)
+}
+
+func transferRequestHash(t *testing.T, body gin.H) string {
+	data, err := json.Marshal(body)
+	require.NoError(t, err)
+
+	var req transferRequest
+	err = json.Unmarshal(data, &req)
+	require.NoError(t, err)
+
+	hash, err := requestHash(req)
+	require.NoError(t, err)
+
+	return hash
+}
@@ -0,0 +1,40 @@
+package api
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+
+	mockdb "tech-school/db/mock"
+	db "tech-school/db/sqlc"
+	"tech-school/util"
+)
+
+func newTestServer(t *testing.T, store db.Store) *Server {
+	config := util.Config{
+		TokenSymmetricKey:   util.RandomString(32),
+		AccessTokenDuration: time.Minute,
+		CursorSigningKey:    util.RandomString(32),
+		Currencies:          util.DefaultCurrencies,
+	}
+
+	// Every mutating call is audited: tests that don't check the audit log let it be written.
+	if mockStore, ok := store.(*mockdb.MockStore); ok {
+		mockStore.EXPECT().CreateAuditLogTx(gomock.Any(), gomock.Any()).AnyTimes()
+	}
+
+	server, err := NewServer(config, store)
+	require.NoError(t, err)
+
+	return server
+}
+
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+
+	os.Exit(m.Run())
+}
@@ -0,0 +1,31 @@
+package api
+
+import "time"
+
+const periodDateFormat = "2006-01-02"
+
+// periodQuery binds an inclusive from/to date range given in UTC.
+type periodQuery struct {
+	From time.Time `form:"from" time_format:"2006-01-02" time_utc:"1"`
+	To   time.Time `form:"to" time_format:"2006-01-02" time_utc:"1" binding:"omitempty,gtefield=From"`
+}
+
+// bounds converts the dates into the half-open interval [start, end) used by the queries.
+// A missing from date means the beginning of time, a missing to date means now.
+func (p periodQuery) bounds() (start, end time.Time) {
+	end = time.Now()
+	if !p.To.IsZero() {
+		end = p.To.AddDate(0, 0, 1)
+	}
+
+	return p.From, end
+}
+
+// formatPeriodDate formats a bound for a response, leaving missing ones empty.
+func formatPeriodDate(date time.Time) string {
+	if date.IsZero() {
+		return ""
+	}
+
+	return date.Format(periodDateFormat)
+}
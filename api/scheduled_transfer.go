@@ -0,0 +1,360 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	db "tech-school/db/sqlc"
+	"tech-school/money"
+	"tech-school/recurrence"
+	"tech-school/token"
+)
+
+type scheduledTransferResponse struct {
+	ID            int64       `json:"id"`
+	FromAccountID int64       `json:"from_account_id"`
+	ToAccountID   int64       `json:"to_account_id"`
+	Amount        money.Money `json:"amount"`
+	Schedule      string      `json:"schedule,omitempty"`
+	NextRunAt     time.Time   `json:"next_run_at"`
+	Status        string      `json:"status"`
+	CreatedAt     time.Time   `json:"created_at"`
+}
+
+func newScheduledTransferResponse(scheduled db.ScheduledTransfer) scheduledTransferResponse {
+	return scheduledTransferResponse{
+		ID:            scheduled.ID,
+		FromAccountID: scheduled.FromAccountID,
+		ToAccountID:   scheduled.ToAccountID,
+		Amount:        money.Money{Amount: scheduled.Amount, Currency: scheduled.Currency},
+		Schedule:      scheduled.Schedule.String,
+		NextRunAt:     scheduled.NextRunAt,
+		Status:        scheduled.Status,
+		CreatedAt:     scheduled.CreatedAt,
+	}
+}
+
+type createScheduledTransferRequest struct {
+	FromAccountID int64       `json:"from_account_id" binding:"required,min=1"`
+	ToAccountID   int64       `json:"to_account_id" binding:"required,min=1,nefield=FromAccountID"`
+	Amount        money.Money `json:"amount" binding:"currency"`
+	// Schedule is a cron expression or an @every interval, see package recurrence.
+	// Without it, the transfer runs once at StartAt.
+	Schedule string    `json:"schedule"`
+	StartAt  time.Time `json:"start_at" binding:"required"`
+}
+
+// createScheduledTransfer schedules a one-off or recurring transfer from an account owned by the caller.
+func (s *Server) createScheduledTransfer(ctx *gin.Context) {
+	var req createScheduledTransferRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if !req.Amount.IsPositive() {
+		err := errors.New("amount must be positive")
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if !req.StartAt.After(time.Now()) {
+		err := errors.New("start_at must be in the future")
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if req.Schedule != "" {
+		if _, err := recurrence.Parse(req.Schedule); err != nil {
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+			return
+		}
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	setAuditStatus(ctx, http.StatusCreated)
+
+	idempotency, ok := idempotencyParams(ctx, authPayload.Username, req, http.StatusCreated)
+	if !ok || s.replayIdempotentResponse(ctx, idempotency, renderStored(newScheduledTransferResponse)) {
+		return
+	}
+
+	account, ok := s.validAccount(ctx, req.FromAccountID, req.Amount.Currency)
+	if !ok {
+		return
+	}
+
+	if account.Owner != authPayload.Username {
+		err := errors.New("account doesn't belong to the authenticated user")
+		ctx.JSON(http.StatusForbidden, errorResponse(err))
+		return
+	}
+
+	if _, ok := s.validAccount(ctx, req.ToAccountID, req.Amount.Currency); !ok {
+		return
+	}
+
+	arg := db.CreateScheduledTransferTxParams{
+		CreateScheduledTransferParams: db.CreateScheduledTransferParams{
+			Owner:         authPayload.Username,
+			FromAccountID: req.FromAccountID,
+			ToAccountID:   req.ToAccountID,
+			Amount:        req.Amount.Amount,
+			Currency:      req.Amount.Currency,
+			Schedule:      sql.NullString{String: req.Schedule, Valid: req.Schedule != ""},
+			NextRunAt:     req.StartAt,
+		},
+		Idempotency: idempotency,
+	}
+
+	scheduled, err := s.store.CreateScheduledTransferTx(ctx, arg)
+	if err != nil {
+		if errors.Is(err, db.ErrDuplicateIdempotencyKey) &&
+			s.replayIdempotentResponse(ctx, idempotency, renderStored(newScheduledTransferResponse)) {
+			return
+		}
+		ctx.JSON(dbErrorStatus(err), errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, newScheduledTransferResponse(scheduled))
+}
+
+type listScheduledTransfersRequest struct {
+	PageID   int32 `form:"page_id" binding:"required,min=1"`
+	PageSize int32 `form:"page_size" binding:"required,min=5,max=100"`
+}
+
+// listScheduledTransfers lists the caller's scheduled transfers, including the completed and cancelled ones.
+func (s *Server) listScheduledTransfers(ctx *gin.Context) {
+	var req listScheduledTransfersRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	arg := db.ListScheduledTransfersByOwnerParams{
+		Owner:  authPayload.Username,
+		Limit:  req.PageSize,
+		Offset: (req.PageID - 1) * req.PageSize,
+	}
+
+	scheduled, err := s.store.ListScheduledTransfersByOwner(ctx, arg)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	rsp := make([]scheduledTransferResponse, len(scheduled))
+	for i, st := range scheduled {
+		rsp[i] = newScheduledTransferResponse(st)
+	}
+
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+type scheduledTransferURIRequest struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+func (s *Server) getScheduledTransfer(ctx *gin.Context) {
+	var uri scheduledTransferURIRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	scheduled, ok := s.ownedScheduledTransfer(ctx, uri.ID)
+	if !ok {
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newScheduledTransferResponse(scheduled))
+}
+
+type updateScheduledTransferRequest struct {
+	Amount    *money.Money `json:"amount"`
+	Schedule  *string      `json:"schedule"`
+	NextRunAt *time.Time   `json:"next_run_at"`
+}
+
+// updateScheduledTransfer changes the amount, the schedule or the next run time of an active scheduled transfer.
+// A new schedule applies from the next run on: next_run_at isn't recomputed unless it is given too.
+func (s *Server) updateScheduledTransfer(ctx *gin.Context) {
+	var uri scheduledTransferURIRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	var req updateScheduledTransferRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	scheduled, ok := s.ownedScheduledTransfer(ctx, uri.ID)
+	if !ok {
+		return
+	}
+
+	arg := db.UpdateScheduledTransferParams{ID: scheduled.ID}
+
+	if req.Amount != nil {
+		if !req.Amount.IsPositive() {
+			err := errors.New("amount must be positive")
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+			return
+		}
+		if req.Amount.Currency != scheduled.Currency {
+			err := fmt.Errorf("scheduled transfer [%d] currency mismatch: %s vs %s", scheduled.ID, scheduled.Currency, req.Amount.Currency)
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+			return
+		}
+		arg.Amount = sql.NullInt64{Int64: req.Amount.Amount, Valid: true}
+	}
+
+	if req.Schedule != nil {
+		if _, err := recurrence.Parse(*req.Schedule); err != nil {
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+			return
+		}
+		arg.Schedule = sql.NullString{String: *req.Schedule, Valid: true}
+	}
+
+	if req.NextRunAt != nil {
+		if !req.NextRunAt.After(time.Now()) {
+			err := errors.New("next_run_at must be in the future")
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+			return
+		}
+		arg.NextRunAt = sql.NullTime{Time: *req.NextRunAt, Valid: true}
+	}
+
+	scheduled, err := s.store.UpdateScheduledTransferTx(ctx, arg)
+	if err != nil {
+		ctx.JSON(scheduledTransferErrorStatus(err), errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newScheduledTransferResponse(scheduled))
+}
+
+// cancelScheduledTransfer stops an active scheduled transfer. It is kept, with its runs, but won't run anymore.
+func (s *Server) cancelScheduledTransfer(ctx *gin.Context) {
+	var uri scheduledTransferURIRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if _, ok := s.ownedScheduledTransfer(ctx, uri.ID); !ok {
+		return
+	}
+
+	scheduled, err := s.store.CancelScheduledTransferTx(ctx, uri.ID)
+	if err != nil {
+		ctx.JSON(scheduledTransferErrorStatus(err), errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newScheduledTransferResponse(scheduled))
+}
+
+type scheduledTransferRunResponse struct {
+	ID          int64     `json:"id"`
+	ScheduledAt time.Time `json:"scheduled_at"`
+	TransferID  int64     `json:"transfer_id,omitempty"`
+	Error       string    `json:"error,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type listScheduledTransferRunsRequest struct {
+	PageID   int32 `form:"page_id" binding:"required,min=1"`
+	PageSize int32 `form:"page_size" binding:"required,min=5,max=100"`
+}
+
+// listScheduledTransferRuns lists the outcome of the runs of a scheduled transfer, the latest first.
+func (s *Server) listScheduledTransferRuns(ctx *gin.Context) {
+	var uri scheduledTransferURIRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	var req listScheduledTransferRunsRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if _, ok := s.ownedScheduledTransfer(ctx, uri.ID); !ok {
+		return
+	}
+
+	arg := db.ListScheduledTransferRunsParams{
+		ScheduledTransferID: uri.ID,
+		Limit:               req.PageSize,
+		Offset:              (req.PageID - 1) * req.PageSize,
+	}
+
+	runs, err := s.store.ListScheduledTransferRuns(ctx, arg)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	rsp := make([]scheduledTransferRunResponse, len(runs))
+	for i, run := range runs {
+		rsp[i] = scheduledTransferRunResponse{
+			ID:          run.ID,
+			ScheduledAt: run.ScheduledAt,
+			TransferID:  run.TransferID.Int64,
+			Error:       run.Error.String,
+			CreatedAt:   run.CreatedAt,
+		}
+	}
+
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+// ownedScheduledTransfer loads the scheduled transfer and checks that it belongs to the authenticated user.
+// It writes the error response itself and reports whether the handler may proceed.
+func (s *Server) ownedScheduledTransfer(ctx *gin.Context, id int64) (db.ScheduledTransfer, bool) {
+	scheduled, err := s.store.GetScheduledTransfer(ctx, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return scheduled, false
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return scheduled, false
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if scheduled.Owner != authPayload.Username {
+		err := errors.New("scheduled transfer doesn't belong to the authenticated user")
+		ctx.JSON(http.StatusForbidden, errorResponse(err))
+		return scheduled, false
+	}
+
+	return scheduled, true
+}
+
+// scheduledTransferErrorStatus maps the errors of the scheduled transfer updates to HTTP status codes.
+// The updates only match active scheduled transfers, so no row means it was completed or cancelled meanwhile.
+func scheduledTransferErrorStatus(err error) int {
+	if errors.Is(err, sql.ErrNoRows) {
+		return http.StatusConflict
+	}
+
+	return dbErrorStatus(err)
+}
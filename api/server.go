@@ -1,33 +1,127 @@
 package api
 
 import (
+	"fmt"
+	"net"
+	"net/http"
+
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
 
 	db "tech-school/db/sqlc"
+	"tech-school/fx"
+	"tech-school/token"
+	"tech-school/util"
+	"tech-school/webhook"
 )
 
 type Server struct {
-	store  *db.Store
-	router *gin.Engine
+	config     util.Config
+	store      db.Store
+	tokenMaker token.Maker
+	cursors    *cursorCodec
+	rates      fx.RateProvider
+	resolver   webhook.Resolver
+	router     *gin.Engine
 }
 
-func NewServer(store *db.Store) *Server {
-	return &Server{
-		store:  store,
-		router: gin.Default(),
+func NewServer(config util.Config, store db.Store) (*Server, error) {
+	tokenMaker, err := token.NewMaker(config.TokenType, config.TokenSymmetricKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create token maker: %w", err)
+	}
+
+	cursors, err := newCursorCodec(config.CursorSigningKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cursor codec: %w", err)
+	}
+
+	if err := util.SetSupportedCurrencies(config.Currencies...); err != nil {
+		return nil, fmt.Errorf("failed to load supported currencies: %w", err)
+	}
+
+	s := &Server{
+		config:     config,
+		store:      store,
+		tokenMaker: tokenMaker,
+		cursors:    cursors,
+		resolver:   net.DefaultResolver,
+		router:     gin.Default(),
+	}
+
+	// Cross-currency transfers are only enabled when exchange rates are configured.
+	if config.FXRatesFile != "" {
+		rates, err := fx.NewStaticFileProvider(config.FXRatesFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load exchange rates: %w", err)
+		}
+		s.rates = rates
+	}
+
+	// X-Forwarded-For is only believed from the configured proxies, see gin.Context.ClientIP.
+	if err := s.router.SetTrustedProxies(config.TrustedProxies); err != nil {
+		return nil, fmt.Errorf("failed to set trusted proxies: %w", err)
+	}
+
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		if err := v.RegisterValidation("currency", validCurrency); err != nil {
+			return nil, fmt.Errorf("failed to register currency validator: %w", err)
+		}
 	}
-}
 
-func (s *Server) Run(addr string) error {
 	s.initRoutes()
 
-	return s.router.Run(addr)
+	return s, nil
+}
+
+// Handler returns the HTTP handler serving the routes of the API.
+func (s *Server) Handler() http.Handler {
+	return s.router
 }
 
 func (s *Server) initRoutes() {
-	s.router.GET("/accounts", s.listAccounts)
-	s.router.GET("/accounts/:id", s.getAccount)
-	s.router.POST("/accounts", s.createAccount)
+	// The store reads the audited call from the request context, see auditMiddleware.
+	s.router.ContextWithFallback = true
+
+	s.router.Use(requestIDMiddleware(), auditMiddleware(s.store))
+
+	s.router.POST("/users", s.createUser)
+	s.router.POST("/users/login", s.loginUser)
+
+	authRoutes := s.router.Group("/").Use(authMiddleware(s.tokenMaker))
+
+	authRoutes.GET("/accounts", s.listAccounts)
+	authRoutes.GET("/accounts/:id", s.getAccount)
+	authRoutes.GET("/accounts/:id/entries", s.listAccountEntries)
+	authRoutes.GET("/accounts/:id/transfers", s.listAccountTransfers)
+	authRoutes.POST("/accounts", s.createAccount)
+
+	authRoutes.POST("/transfers", s.createTransfer)
+	authRoutes.POST("/transfers/:id/reverse", s.reverseTransfer)
+
+	authRoutes.POST("/holds", s.placeHold)
+	authRoutes.POST("/holds/:id/capture", s.captureHold)
+	authRoutes.POST("/holds/:id/release", s.releaseHold)
+
+	authRoutes.POST("/scheduled-transfers", s.createScheduledTransfer)
+	authRoutes.GET("/scheduled-transfers", s.listScheduledTransfers)
+	authRoutes.GET("/scheduled-transfers/:id", s.getScheduledTransfer)
+	authRoutes.GET("/scheduled-transfers/:id/runs", s.listScheduledTransferRuns)
+	authRoutes.PATCH("/scheduled-transfers/:id", s.updateScheduledTransfer)
+	authRoutes.DELETE("/scheduled-transfers/:id", s.cancelScheduledTransfer)
+
+	authRoutes.POST("/webhooks", s.createWebhookSubscription)
+	authRoutes.GET("/webhooks", s.listWebhookSubscriptions)
+	authRoutes.DELETE("/webhooks/:id", s.deleteWebhookSubscription)
+	authRoutes.GET("/webhooks/:id/deliveries", s.listWebhookDeliveries)
+	authRoutes.POST("/webhooks/:id/deliveries/:delivery_id/replay", s.replayWebhookDelivery)
+
+	adminRoutes := s.router.Group("/").Use(authMiddleware(s.tokenMaker), adminMiddleware(s.store))
+
+	adminRoutes.PATCH("/accounts/:id/status", s.updateAccountStatus)
+	adminRoutes.GET("/admin/audit", s.listAuditLog)
+	adminRoutes.GET("/admin/audit/verify", s.verifyAuditLog)
 }
 
 func errorResponse(err error) gin.H {
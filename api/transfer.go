@@ -0,0 +1,405 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	db "tech-school/db/sqlc"
+	"tech-school/fx"
+	"tech-school/money"
+	"tech-school/token"
+	"tech-school/util"
+)
+
+type transferRequest struct {
+	FromAccountID int64       `json:"from_account_id" binding:"required,min=1"`
+	ToAccountID   int64       `json:"to_account_id" binding:"required,min=1,nefield=FromAccountID"`
+	Amount        money.Money `json:"amount" binding:"currency"`
+}
+
+type transferResponse struct {
+	ID                 int64        `json:"id"`
+	FromAccountID      int64        `json:"from_account_id"`
+	ToAccountID        int64        `json:"to_account_id"`
+	Amount             money.Money  `json:"amount"`
+	ReversedTransferID int64        `json:"reversed_transfer_id,omitempty"`
+	ToAmount           *money.Money `json:"to_amount,omitempty"`
+	ExchangeRate       string       `json:"exchange_rate,omitempty"`
+	CreatedAt          time.Time    `json:"created_at"`
+}
+
+type entryResponse struct {
+	ID        int64       `json:"id"`
+	AccountID int64       `json:"account_id"`
+	Amount    money.Money `json:"amount"`
+	CreatedAt time.Time   `json:"created_at"`
+}
+
+type feeResponse struct {
+	TransferID int64       `json:"transfer_id"`
+	Flat       money.Money `json:"flat"`
+	Percentage money.Money `json:"percentage"`
+	Total      money.Money `json:"total"`
+}
+
+type transferTxResponse struct {
+	Transfer    transferResponse `json:"transfer"`
+	FromAccount accountResponse  `json:"from_account"`
+	ToAccount   accountResponse  `json:"to_account"`
+	FromEntry   entryResponse    `json:"from_entry"`
+	ToEntry     entryResponse    `json:"to_entry"`
+	Fee         *feeResponse     `json:"fee,omitempty"`
+}
+
+func newTransferTxResponse(result db.TransferTxResult) transferTxResponse {
+	rsp := transferTxResponse{
+		Transfer: transferResponse{
+			ID:                 result.Transfer.ID,
+			FromAccountID:      result.Transfer.FromAccountID.Int64,
+			ToAccountID:        result.Transfer.ToAccountID.Int64,
+			Amount:             money.Money{Amount: result.Transfer.Amount, Currency: result.FromAccount.Currency},
+			ReversedTransferID: result.Transfer.ReversedTransferID.Int64,
+			ExchangeRate:       result.Transfer.ExchangeRate.String,
+			CreatedAt:          result.Transfer.CreatedAt,
+		},
+		FromAccount: newAccountResponse(result.FromAccount),
+		ToAccount:   newAccountResponse(result.ToAccount),
+		FromEntry:   newEntryResponse(result.FromEntry, result.FromAccount.Currency),
+		ToEntry:     newEntryResponse(result.ToEntry, result.ToAccount.Currency),
+	}
+
+	if result.Transfer.ToAmount.Valid {
+		rsp.Transfer.ToAmount = &money.Money{Amount: result.Transfer.ToAmount.Int64, Currency: result.ToAccount.Currency}
+	}
+
+	if fee := result.Fee; fee != nil {
+		currency := result.FromAccount.Currency
+		rsp.Fee = &feeResponse{
+			TransferID: fee.Transfer.ID,
+			Flat:       money.Money{Amount: fee.Flat, Currency: currency},
+			Percentage: money.Money{Amount: fee.Percentage, Currency: currency},
+			Total:      money.Money{Amount: fee.Total, Currency: currency},
+		}
+	}
+
+	return rsp
+}
+
+func newEntryResponse(entry db.Entry, currency string) entryResponse {
+	return entryResponse{
+		ID:        entry.ID,
+		AccountID: entry.AccountID.Int64,
+		Amount:    money.Money{Amount: entry.Amount, Currency: currency},
+		CreatedAt: entry.CreatedAt,
+	}
+}
+
+func (s *Server) createTransfer(ctx *gin.Context) {
+	var req transferRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if !req.Amount.IsPositive() {
+		err := errors.New("amount must be positive")
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	idempotency, ok := idempotencyParams(ctx, authPayload.Username, req, http.StatusOK)
+	if !ok || s.replayIdempotentResponse(ctx, idempotency, renderStored(newTransferTxResponse)) {
+		return
+	}
+
+	fromAccount, ok := s.validAccount(ctx, req.FromAccountID, req.Amount.Currency)
+	if !ok {
+		return
+	}
+
+	if fromAccount.Owner != authPayload.Username {
+		err := errors.New("from account doesn't belong to the authenticated user")
+		ctx.JSON(http.StatusForbidden, errorResponse(err))
+		return
+	}
+
+	// Without exchange rates, both accounts must be in the currency of the amount.
+	var toAccount db.Account
+	if s.rates == nil {
+		toAccount, ok = s.validAccount(ctx, req.ToAccountID, req.Amount.Currency)
+	} else {
+		toAccount, ok = s.loadAccount(ctx, req.ToAccountID)
+	}
+	if !ok {
+		return
+	}
+
+	arg := db.TransferTxParams{
+		FromAccountID: util.SQLNullInt64(req.FromAccountID),
+		ToAccountID:   util.SQLNullInt64(req.ToAccountID),
+		Amount:        req.Amount.Amount,
+		Idempotency:   idempotency,
+	}
+
+	var (
+		result db.TransferTxResult
+		err    error
+	)
+	if toAccount.Currency == fromAccount.Currency {
+		result, err = s.store.TransferTx(ctx, arg)
+	} else {
+		rate, ok := s.exchangeRate(ctx, fromAccount.Currency, toAccount.Currency)
+		if !ok {
+			return
+		}
+		result, err = s.store.CrossCurrencyTransferTx(ctx, db.CrossCurrencyTransferTxParams{
+			TransferTxParams: arg,
+			Rate:             rate,
+		})
+	}
+	if err != nil {
+		if errors.Is(err, db.ErrInsufficientFunds) || errors.Is(err, fx.ErrAmountTooSmall) {
+			ctx.JSON(http.StatusUnprocessableEntity, errorResponse(err))
+			return
+		}
+		if errors.Is(err, db.ErrDuplicateIdempotencyKey) &&
+			s.replayIdempotentResponse(ctx, idempotency, renderStored(newTransferTxResponse)) {
+			return
+		}
+		ctx.JSON(dbErrorStatus(err), errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newTransferTxResponse(result))
+}
+
+type reverseTransferRequest struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+// reverseTransfer records a compensating transfer for the given one.
+// Only the owner of the account that received the original transfer may give the money back.
+func (s *Server) reverseTransfer(ctx *gin.Context) {
+	var req reverseTransferRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	idempotency, ok := idempotencyParams(ctx, authPayload.Username, req, http.StatusOK)
+	if !ok || s.replayIdempotentResponse(ctx, idempotency, renderStored(newTransferTxResponse)) {
+		return
+	}
+
+	transfer, err := s.store.GetTransfer(ctx, req.ID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	toAccount, err := s.store.GetAccount(ctx, transfer.ToAccountID.Int64)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if toAccount.Owner != authPayload.Username {
+		err := errors.New("transfer wasn't received by an account of the authenticated user")
+		ctx.JSON(http.StatusForbidden, errorResponse(err))
+		return
+	}
+
+	arg := db.ReverseTransferTxParams{
+		TransferID:  req.ID,
+		Idempotency: idempotency,
+	}
+
+	result, err := s.store.ReverseTransferTx(ctx, arg)
+	if err != nil {
+		switch {
+		case errors.Is(err, db.ErrTransferAlreadyReversed):
+			ctx.JSON(http.StatusConflict, errorResponse(err))
+			return
+		case errors.Is(err, db.ErrInsufficientFunds),
+			errors.Is(err, db.ErrReversalNotReversible),
+			errors.Is(err, db.ErrFeeNotReversible):
+			ctx.JSON(http.StatusUnprocessableEntity, errorResponse(err))
+			return
+		case errors.Is(err, db.ErrDuplicateIdempotencyKey) &&
+			s.replayIdempotentResponse(ctx, idempotency, renderStored(newTransferTxResponse)):
+			return
+		}
+		ctx.JSON(dbErrorStatus(err), errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newTransferTxResponse(result))
+}
+
+// validAccount loads the account and checks that its currency matches the given one.
+// It writes the error response itself and reports whether the handler may proceed.
+func (s *Server) validAccount(ctx *gin.Context, accountID int64, currency string) (db.Account, bool) {
+	account, ok := s.loadAccount(ctx, accountID)
+	if !ok {
+		return account, false
+	}
+
+	if account.Currency != currency {
+		err := fmt.Errorf("account [%d] currency mismatch: %s vs %s", account.ID, account.Currency, currency)
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return account, false
+	}
+
+	return account, true
+}
+
+// exchangeRate looks up the rate converting from one currency to the other.
+// It writes the error response itself and reports whether the handler may proceed.
+func (s *Server) exchangeRate(ctx *gin.Context, from, to string) (fx.Rate, bool) {
+	rate, err := s.rates.Rate(ctx, from, to)
+	if err != nil {
+		if errors.Is(err, fx.ErrRateNotFound) {
+			ctx.JSON(http.StatusUnprocessableEntity, errorResponse(err))
+			return rate, false
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return rate, false
+	}
+
+	return rate, true
+}
+
+const (
+	directionIncoming = "in"
+	directionOutgoing = "out"
+)
+
+type listAccountTransfersRequest struct {
+	periodQuery
+	Direction      string `form:"direction" binding:"omitempty,oneof=in out"`
+	CounterpartyID int64  `form:"counterparty_id" binding:"omitempty,min=1"`
+	MinAmount      int64  `form:"min_amount" binding:"omitempty,min=1"`
+	MaxAmount      int64  `form:"max_amount" binding:"omitempty,min=1,gtefield=MinAmount"`
+	PageSize       int32  `form:"page_size" binding:"required,min=5,max=100"`
+	Cursor         string `form:"cursor"`
+}
+
+type accountTransfer struct {
+	ID             int64       `json:"id"`
+	Direction      string      `json:"direction"`
+	CounterpartyID int64       `json:"counterparty_id"`
+	Amount         money.Money `json:"amount"`
+	CreatedAt      time.Time   `json:"created_at"`
+}
+
+type listAccountTransfersResponse struct {
+	AccountID  int64             `json:"account_id"`
+	Transfers  []accountTransfer `json:"transfers"`
+	NextCursor string            `json:"next_cursor,omitempty"`
+}
+
+// listAccountTransfers returns the incoming and outgoing transfers of an account owned by the caller.
+func (s *Server) listAccountTransfers(ctx *gin.Context) {
+	var uri getAccountRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	var req listAccountTransfersRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	after, ok := s.decodeCursor(ctx, req.Cursor)
+	if !ok {
+		return
+	}
+
+	account, ok := s.ownedAccount(ctx, uri.ID)
+	if !ok {
+		return
+	}
+
+	periodStart, periodEnd := req.bounds()
+
+	arg := db.ListAccountTransfersParams{
+		IncludeOutgoing: req.Direction != directionIncoming,
+		AccountID:       uri.ID,
+		IncludeIncoming: req.Direction != directionOutgoing,
+		PeriodStart:     periodStart,
+		PeriodEnd:       periodEnd,
+		AfterCreatedAt:  after.CreatedAt,
+		AfterID:         after.ID,
+		PageSize:        req.PageSize + 1,
+	}
+	if req.CounterpartyID > 0 {
+		arg.CounterpartyID = util.SQLNullInt64(req.CounterpartyID)
+	}
+	if req.MinAmount > 0 {
+		arg.MinAmount = util.SQLNullInt64(req.MinAmount)
+	}
+	if req.MaxAmount > 0 {
+		arg.MaxAmount = util.SQLNullInt64(req.MaxAmount)
+	}
+
+	transfers, err := s.store.ListAccountTransfers(ctx, arg)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	transfers, next, err := nextPage(s.cursors, transfers, req.PageSize, func(t db.Transfer) pageCursor {
+		return pageCursor{CreatedAt: t.CreatedAt, ID: t.ID}
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	rsp := listAccountTransfersResponse{
+		AccountID:  uri.ID,
+		Transfers:  make([]accountTransfer, len(transfers)),
+		NextCursor: next,
+	}
+	for i, transfer := range transfers {
+		rsp.Transfers[i] = newAccountTransfer(account, transfer)
+	}
+
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+// newAccountTransfer describes a transfer from the point of view of the given account.
+func newAccountTransfer(account db.Account, transfer db.Transfer) accountTransfer {
+	rsp := accountTransfer{
+		ID:             transfer.ID,
+		Direction:      directionOutgoing,
+		CounterpartyID: transfer.ToAccountID.Int64,
+		Amount:         money.Money{Amount: transfer.Amount, Currency: account.Currency},
+		CreatedAt:      transfer.CreatedAt,
+	}
+
+	if transfer.ToAccountID.Int64 == account.ID {
+		rsp.Direction = directionIncoming
+		rsp.CounterpartyID = transfer.FromAccountID.Int64
+		if transfer.ToAmount.Valid {
+			rsp.Amount.Amount = transfer.ToAmount.Int64
+		}
+	}
+
+	return rsp
+}
@@ -0,0 +1,20 @@
+package api
+
+import (
+	"github.com/go-playground/validator/v10"
+
+	"tech-school/money"
+	"tech-school/util"
+)
+
+// validCurrency accepts currency codes, or amounts, in one of the currencies enabled in the config.
+var validCurrency validator.Func = func(fieldLevel validator.FieldLevel) bool {
+	switch field := fieldLevel.Field().Interface().(type) {
+	case string:
+		return util.IsSupportedCurrency(field)
+	case money.Money:
+		return util.IsSupportedCurrency(field.Currency)
+	default:
+		return false
+	}
+}
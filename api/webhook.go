@@ -0,0 +1,303 @@
+package api
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	db "tech-school/db/sqlc"
+	"tech-school/token"
+	"tech-school/webhook"
+)
+
+type webhookSubscriptionResponse struct {
+	ID         int64     `json:"id"`
+	URL        string    `json:"url"`
+	EventTypes []string  `json:"event_types"`
+	CreatedAt  time.Time `json:"created_at"`
+	// Secret is only returned when the subscription is created.
+	Secret string `json:"secret,omitempty"`
+}
+
+func newWebhookSubscriptionResponse(subscription db.WebhookSubscription) webhookSubscriptionResponse {
+	return webhookSubscriptionResponse{
+		ID:         subscription.ID,
+		URL:        subscription.Url,
+		EventTypes: subscription.EventTypes,
+		CreatedAt:  subscription.CreatedAt,
+	}
+}
+
+type createWebhookSubscriptionRequest struct {
+	URL string `json:"url" binding:"required,url"`
+	// Secret signs the deliveries. A random one is generated when it is omitted.
+	Secret string `json:"secret" binding:"omitempty,min=16,max=256"`
+	// EventTypes filters the delivered events. All of them are delivered when it is empty.
+	EventTypes []string `json:"event_types" binding:"dive,oneof=AccountCreated TransferCompleted TransferReversed"`
+}
+
+// createWebhookSubscription subscribes a URL to the events of the caller's accounts.
+// The secret is returned in the response, and never again.
+func (s *Server) createWebhookSubscription(ctx *gin.Context) {
+	var req createWebhookSubscriptionRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if err := webhook.ValidateURL(ctx, s.resolver, req.URL); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if req.Secret == "" {
+		secret, err := randomWebhookSecret()
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+		req.Secret = secret
+	}
+
+	if req.EventTypes == nil {
+		req.EventTypes = []string{}
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	arg := db.CreateWebhookSubscriptionParams{
+		Owner:      authPayload.Username,
+		Url:        req.URL,
+		Secret:     req.Secret,
+		EventTypes: req.EventTypes,
+	}
+
+	setAuditStatus(ctx, http.StatusCreated)
+
+	subscription, err := s.store.CreateWebhookSubscriptionTx(ctx, arg)
+	if err != nil {
+		ctx.JSON(dbErrorStatus(err), errorResponse(err))
+		return
+	}
+
+	rsp := newWebhookSubscriptionResponse(subscription)
+	rsp.Secret = subscription.Secret
+
+	ctx.JSON(http.StatusCreated, rsp)
+}
+
+// listWebhookSubscriptions lists the caller's webhook subscriptions, except the deleted ones.
+func (s *Server) listWebhookSubscriptions(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	subscriptions, err := s.store.ListWebhookSubscriptions(ctx, authPayload.Username)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	rsp := make([]webhookSubscriptionResponse, len(subscriptions))
+	for i, subscription := range subscriptions {
+		rsp[i] = newWebhookSubscriptionResponse(subscription)
+	}
+
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+type webhookSubscriptionURIRequest struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+// deleteWebhookSubscription stops the deliveries to a webhook. Its delivery log is kept.
+func (s *Server) deleteWebhookSubscription(ctx *gin.Context) {
+	var uri webhookSubscriptionURIRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if _, ok := s.ownedWebhookSubscription(ctx, uri.ID); !ok {
+		return
+	}
+
+	subscription, err := s.store.DisableWebhookSubscriptionTx(ctx, uri.ID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newWebhookSubscriptionResponse(subscription))
+}
+
+type webhookDeliveryResponse struct {
+	ID             int64      `json:"id"`
+	EventID        int64      `json:"event_id"`
+	Status         string     `json:"status"`
+	Attempts       int32      `json:"attempts"`
+	NextAttemptAt  *time.Time `json:"next_attempt_at,omitempty"`
+	ResponseStatus int32      `json:"response_status,omitempty"`
+	LastError      string     `json:"last_error,omitempty"`
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+func newWebhookDeliveryResponse(delivery db.WebhookDelivery) webhookDeliveryResponse {
+	rsp := webhookDeliveryResponse{
+		ID:             delivery.ID,
+		EventID:        delivery.EventID,
+		Status:         delivery.Status,
+		Attempts:       delivery.Attempts,
+		ResponseStatus: delivery.ResponseStatus.Int32,
+		LastError:      delivery.LastError.String,
+		CreatedAt:      delivery.CreatedAt,
+	}
+	if delivery.Status == db.WebhookDeliveryStatusPending {
+		rsp.NextAttemptAt = &delivery.NextAttemptAt
+	}
+	if delivery.DeliveredAt.Valid {
+		rsp.DeliveredAt = &delivery.DeliveredAt.Time
+	}
+
+	return rsp
+}
+
+type listWebhookDeliveriesRequest struct {
+	PageID   int32 `form:"page_id" binding:"required,min=1"`
+	PageSize int32 `form:"page_size" binding:"required,min=5,max=100"`
+}
+
+// listWebhookDeliveries lists the deliveries of a webhook subscription, the latest first.
+func (s *Server) listWebhookDeliveries(ctx *gin.Context) {
+	var uri webhookSubscriptionURIRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	var req listWebhookDeliveriesRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if _, ok := s.ownedWebhookSubscription(ctx, uri.ID); !ok {
+		return
+	}
+
+	arg := db.ListWebhookDeliveriesParams{
+		SubscriptionID: uri.ID,
+		Limit:          req.PageSize,
+		Offset:         (req.PageID - 1) * req.PageSize,
+	}
+
+	deliveries, err := s.store.ListWebhookDeliveries(ctx, arg)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	rsp := make([]webhookDeliveryResponse, len(deliveries))
+	for i, delivery := range deliveries {
+		rsp[i] = newWebhookDeliveryResponse(delivery)
+	}
+
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+type webhookDeliveryURIRequest struct {
+	ID         int64 `uri:"id" binding:"required,min=1"`
+	DeliveryID int64 `uri:"delivery_id" binding:"required,min=1"`
+}
+
+// replayWebhookDelivery queues a delivered or dead delivery again, with a fresh set of attempts.
+func (s *Server) replayWebhookDelivery(ctx *gin.Context) {
+	var uri webhookDeliveryURIRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	subscription, ok := s.ownedWebhookSubscription(ctx, uri.ID)
+	if !ok {
+		return
+	}
+
+	if subscription.DisabledAt.Valid {
+		err := fmt.Errorf("webhook subscription [%d] is deleted", subscription.ID)
+		ctx.JSON(http.StatusConflict, errorResponse(err))
+		return
+	}
+
+	delivery, err := s.store.GetWebhookDelivery(ctx, uri.DeliveryID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if delivery.SubscriptionID != subscription.ID {
+		err := fmt.Errorf("webhook delivery [%d] doesn't belong to subscription [%d]", delivery.ID, subscription.ID)
+		ctx.JSON(http.StatusNotFound, errorResponse(err))
+		return
+	}
+
+	delivery, err = s.store.ReplayWebhookDeliveryTx(ctx, delivery.ID)
+	if err != nil {
+		// Only the deliveries that aren't pending are replayed.
+		if errors.Is(err, sql.ErrNoRows) {
+			err := fmt.Errorf("webhook delivery [%d] is already pending", uri.DeliveryID)
+			ctx.JSON(http.StatusConflict, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newWebhookDeliveryResponse(delivery))
+}
+
+// ownedWebhookSubscription loads the webhook subscription and checks that it belongs to the authenticated user.
+// It writes the error response itself and reports whether the handler may proceed.
+func (s *Server) ownedWebhookSubscription(ctx *gin.Context, id int64) (db.WebhookSubscription, bool) {
+	subscription, err := s.store.GetWebhookSubscription(ctx, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return subscription, false
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return subscription, false
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if subscription.Owner != authPayload.Username {
+		err := errors.New("webhook subscription doesn't belong to the authenticated user")
+		ctx.JSON(http.StatusForbidden, errorResponse(err))
+		return subscription, false
+	}
+
+	return subscription, true
+}
+
+// randomWebhookSecret returns 32 random bytes, hex-encoded.
+func randomWebhookSecret() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(secret), nil
+}
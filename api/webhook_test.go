@@ -0,0 +1,472 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+
+	mockdb "tech-school/db/mock"
+	db "tech-school/db/sqlc"
+	"tech-school/internal/testutil"
+	"tech-school/token"
+	"tech-school/util"
+)
+
+func randomWebhookSubscription(owner string) db.WebhookSubscription {
+	return db.WebhookSubscription{
+		ID:         util.RandomInt(1, 1000),
+		Owner:      owner,
+		Url:        "https://example.com/hooks/" + util.RandomString(6),
+		Secret:     util.RandomString(32),
+		EventTypes: []string{db.EventTransferCompleted},
+		CreatedAt:  time.Now().Truncate(time.Second).UTC(),
+	}
+}
+
+// fakeResolver resolves the hosts it knows, and fails on the others.
+type fakeResolver map[string][]string
+
+func (r fakeResolver) LookupIPAddr(_ context.Context, host string) ([]net.IPAddr, error) {
+	ips, ok := r[host]
+	if !ok {
+		return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+
+	addrs := make([]net.IPAddr, len(ips))
+	for i, ip := range ips {
+		addrs[i] = net.IPAddr{IP: net.ParseIP(ip)}
+	}
+
+	return addrs, nil
+}
+
+func TestCreateWebhookSubscriptionAPI(t *testing.T) {
+	user, _ := testutil.RandomUser(t)
+	subscription := randomWebhookSubscription(user.Username)
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: gin.H{
+				"url":         subscription.Url,
+				"secret":      subscription.Secret,
+				"event_types": subscription.EventTypes,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.CreateWebhookSubscriptionParams{
+					Owner:      user.Username,
+					Url:        subscription.Url,
+					Secret:     subscription.Secret,
+					EventTypes: subscription.EventTypes,
+				}
+				store.EXPECT().CreateWebhookSubscriptionTx(gomock.Any(), gomock.Eq(arg)).Times(1).Return(subscription, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusCreated, recorder.Code)
+
+				var got webhookSubscriptionResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &got))
+
+				expected := newWebhookSubscriptionResponse(subscription)
+				expected.Secret = subscription.Secret
+				require.Equal(t, expected, got)
+			},
+		},
+		{
+			name: "GeneratedSecret",
+			body: gin.H{"url": subscription.Url},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateWebhookSubscriptionTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.CreateWebhookSubscriptionParams) (db.WebhookSubscription, error) {
+						require.Len(t, arg.Secret, 64)
+						require.Empty(t, arg.EventTypes)
+						require.NotNil(t, arg.EventTypes)
+
+						created := subscription
+						created.Secret = arg.Secret
+						created.EventTypes = arg.EventTypes
+						return created, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusCreated, recorder.Code)
+
+				var got webhookSubscriptionResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &got))
+				require.Len(t, got.Secret, 64)
+			},
+		},
+		{
+			name: "InvalidURL",
+			body: gin.H{"url": "ftp://example.com/hooks"},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateWebhookSubscriptionTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "PrivateURL",
+			body: gin.H{"url": "http://10.0.0.1/hooks"},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateWebhookSubscriptionTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "LinkLocalHost",
+			body: gin.H{"url": "http://metadata.example.com/latest/meta-data"},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateWebhookSubscriptionTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "ShortSecret",
+			body: gin.H{"url": subscription.Url, "secret": "secret"},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateWebhookSubscriptionTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "UnknownEventType",
+			body: gin.H{"url": subscription.Url, "event_types": []string{"MoneyArrived"}},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateWebhookSubscriptionTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "NoAuthorization",
+			body: gin.H{"url": subscription.Url},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateWebhookSubscriptionTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			server.resolver = fakeResolver{
+				"example.com":          {"93.184.216.34"},
+				"metadata.example.com": {"169.254.169.254"},
+			}
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/webhooks", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			tc.setupAuth(t, request, server.tokenMaker)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestListWebhookDeliveriesAPI(t *testing.T) {
+	user1, _ := testutil.RandomUser(t)
+	user2, _ := testutil.RandomUser(t)
+
+	subscription := randomWebhookSubscription(user1.Username)
+
+	deliveries := []db.WebhookDelivery{
+		{
+			ID:             2,
+			SubscriptionID: subscription.ID,
+			EventID:        20,
+			Status:         db.WebhookDeliveryStatusPending,
+			Attempts:       1,
+			NextAttemptAt:  time.Now().Add(time.Minute).Truncate(time.Second).UTC(),
+			ResponseStatus: sql.NullInt32{Int32: http.StatusBadGateway, Valid: true},
+			LastError:      sql.NullString{String: "responded 502 Bad Gateway", Valid: true},
+			CreatedAt:      time.Now().Truncate(time.Second).UTC(),
+		},
+		{
+			ID:             1,
+			SubscriptionID: subscription.ID,
+			EventID:        10,
+			Status:         db.WebhookDeliveryStatusDelivered,
+			Attempts:       1,
+			ResponseStatus: sql.NullInt32{Int32: http.StatusOK, Valid: true},
+			DeliveredAt:    sql.NullTime{Time: time.Now().Truncate(time.Second).UTC(), Valid: true},
+			CreatedAt:      time.Now().Truncate(time.Second).UTC(),
+		},
+	}
+
+	testCases := []struct {
+		name          string
+		username      string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:     "OK",
+			username: user1.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetWebhookSubscription(gomock.Any(), gomock.Eq(subscription.ID)).Times(1).Return(subscription, nil)
+
+				arg := db.ListWebhookDeliveriesParams{
+					SubscriptionID: subscription.ID,
+					Limit:          5,
+					Offset:         5,
+				}
+				store.EXPECT().ListWebhookDeliveries(gomock.Any(), gomock.Eq(arg)).Times(1).Return(deliveries, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var got []webhookDeliveryResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &got))
+				require.Equal(t, []webhookDeliveryResponse{
+					newWebhookDeliveryResponse(deliveries[0]),
+					newWebhookDeliveryResponse(deliveries[1]),
+				}, got)
+				require.NotNil(t, got[0].NextAttemptAt)
+				require.Nil(t, got[1].NextAttemptAt)
+				require.NotNil(t, got[1].DeliveredAt)
+			},
+		},
+		{
+			name:     "NotOwner",
+			username: user2.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetWebhookSubscription(gomock.Any(), gomock.Eq(subscription.ID)).Times(1).Return(subscription, nil)
+				store.EXPECT().ListWebhookDeliveries(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:     "NotFound",
+			username: user1.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetWebhookSubscription(gomock.Any(), gomock.Eq(subscription.ID)).Times(1).Return(db.WebhookSubscription{}, sql.ErrNoRows)
+				store.EXPECT().ListWebhookDeliveries(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			url := fmt.Sprintf("/webhooks/%d/deliveries?page_id=2&page_size=5", subscription.ID)
+			request, err := http.NewRequest(http.MethodGet, url, nil)
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, tc.username, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestReplayWebhookDeliveryAPI(t *testing.T) {
+	user1, _ := testutil.RandomUser(t)
+	user2, _ := testutil.RandomUser(t)
+
+	subscription := randomWebhookSubscription(user1.Username)
+
+	deleted := subscription
+	deleted.DisabledAt = sql.NullTime{Time: time.Now(), Valid: true}
+
+	dead := db.WebhookDelivery{
+		ID:             util.RandomInt(1, 1000),
+		SubscriptionID: subscription.ID,
+		EventID:        util.RandomInt(1, 1000),
+		Status:         db.WebhookDeliveryStatusDead,
+		Attempts:       10,
+		ResponseStatus: sql.NullInt32{Int32: http.StatusInternalServerError, Valid: true},
+		LastError:      sql.NullString{String: "responded 500 Internal Server Error", Valid: true},
+		CreatedAt:      time.Now().Truncate(time.Second).UTC(),
+	}
+
+	replayed := dead
+	replayed.Status = db.WebhookDeliveryStatusPending
+	replayed.Attempts = 0
+	replayed.NextAttemptAt = time.Now().Truncate(time.Second).UTC()
+
+	other := dead
+	other.SubscriptionID = subscription.ID + 1
+
+	testCases := []struct {
+		name          string
+		username      string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:     "OK",
+			username: user1.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetWebhookSubscription(gomock.Any(), gomock.Eq(subscription.ID)).Times(1).Return(subscription, nil)
+				store.EXPECT().GetWebhookDelivery(gomock.Any(), gomock.Eq(dead.ID)).Times(1).Return(dead, nil)
+				store.EXPECT().ReplayWebhookDeliveryTx(gomock.Any(), gomock.Eq(dead.ID)).Times(1).Return(replayed, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var got webhookDeliveryResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &got))
+				require.Equal(t, newWebhookDeliveryResponse(replayed), got)
+			},
+		},
+		{
+			name:     "NotOwner",
+			username: user2.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetWebhookSubscription(gomock.Any(), gomock.Eq(subscription.ID)).Times(1).Return(subscription, nil)
+				store.EXPECT().GetWebhookDelivery(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().ReplayWebhookDeliveryTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:     "OtherSubscription",
+			username: user1.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetWebhookSubscription(gomock.Any(), gomock.Eq(subscription.ID)).Times(1).Return(subscription, nil)
+				store.EXPECT().GetWebhookDelivery(gomock.Any(), gomock.Eq(dead.ID)).Times(1).Return(other, nil)
+				store.EXPECT().ReplayWebhookDeliveryTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:     "DeletedSubscription",
+			username: user1.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetWebhookSubscription(gomock.Any(), gomock.Eq(subscription.ID)).Times(1).Return(deleted, nil)
+				store.EXPECT().GetWebhookDelivery(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().ReplayWebhookDeliveryTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+			},
+		},
+		{
+			name:     "AlreadyPending",
+			username: user1.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetWebhookSubscription(gomock.Any(), gomock.Eq(subscription.ID)).Times(1).Return(subscription, nil)
+				store.EXPECT().GetWebhookDelivery(gomock.Any(), gomock.Eq(dead.ID)).Times(1).Return(replayed, nil)
+				store.EXPECT().ReplayWebhookDeliveryTx(gomock.Any(), gomock.Eq(dead.ID)).Times(1).Return(db.WebhookDelivery{}, sql.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+			},
+		},
+		{
+			name:     "DeliveryNotFound",
+			username: user1.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetWebhookSubscription(gomock.Any(), gomock.Eq(subscription.ID)).Times(1).Return(subscription, nil)
+				store.EXPECT().GetWebhookDelivery(gomock.Any(), gomock.Eq(dead.ID)).Times(1).Return(db.WebhookDelivery{}, sql.ErrNoRows)
+				store.EXPECT().ReplayWebhookDeliveryTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			url := fmt.Sprintf("/webhooks/%d/deliveries/%d/replay", subscription.ID, dead.ID)
+			request, err := http.NewRequest(http.MethodPost, url, nil)
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, tc.username, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
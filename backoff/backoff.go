@@ -0,0 +1,38 @@
+// Package backoff computes the delays between the attempts of retried operations, such as transactions,
+// outbox events and webhook deliveries.
+package backoff
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+// Exponential returns the delay before the given retry, counting from zero: base doubled on every retry,
+// capped at max unless max is zero.
+func Exponential(base, max time.Duration, retry int) time.Duration {
+	delay := base
+	for i := 0; i < retry && (max <= 0 || delay < max) && delay <= math.MaxInt64/2; i++ {
+		delay *= 2
+	}
+	if max > 0 && delay > max {
+		delay = max
+	}
+	if delay < 0 {
+		return 0
+	}
+
+	return delay
+}
+
+// Jittered returns the exponential delay before the given retry, picked uniformly from [d/2, d]
+// so that concurrent retries spread out.
+func Jittered(base, max time.Duration, retry int) time.Duration {
+	delay := Exponential(base, max, retry)
+	if delay <= 0 {
+		return 0
+	}
+
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(delay-half)+1))
+}
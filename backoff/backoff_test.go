@@ -0,0 +1,40 @@
+package backoff
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExponential(t *testing.T) {
+	require.Equal(t, time.Second, Exponential(time.Second, time.Minute, 0))
+	require.Equal(t, 2*time.Second, Exponential(time.Second, time.Minute, 1))
+	require.Equal(t, 32*time.Second, Exponential(time.Second, time.Minute, 5))
+	require.Equal(t, time.Minute, Exponential(time.Second, time.Minute, 6))
+	require.Equal(t, time.Minute, Exponential(time.Second, time.Minute, 1000))
+
+	// Without a cap, the delay keeps doubling.
+	require.Equal(t, 1024*time.Second, Exponential(time.Second, 0, 10))
+	require.Equal(t, time.Duration(0), Exponential(0, time.Minute, 3))
+}
+
+func TestJittered(t *testing.T) {
+	expected := []time.Duration{
+		10 * time.Millisecond,
+		20 * time.Millisecond,
+		40 * time.Millisecond,
+		50 * time.Millisecond,
+		50 * time.Millisecond,
+	}
+
+	for retry, delay := range expected {
+		for i := 0; i < 100; i++ {
+			got := Jittered(10*time.Millisecond, 50*time.Millisecond, retry)
+			require.GreaterOrEqual(t, got, delay/2)
+			require.LessOrEqual(t, got, delay)
+		}
+	}
+
+	require.Equal(t, time.Duration(0), Jittered(0, 0, 3))
+}
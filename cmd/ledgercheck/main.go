@@ -0,0 +1,44 @@
+// Command ledgercheck verifies the ledger invariants and prints the report as JSON.
+// It exits with a non-zero status when any inconsistency is found.
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"log"
+	"os"
+
+	_ "github.com/lib/pq"
+
+	db "tech-school/db/sqlc"
+	"tech-school/util"
+)
+
+func main() {
+	cfg, err := util.LoadConfig(".")
+	if err != nil {
+		log.Fatalf("failed to load the config: %v", err)
+	}
+
+	conn, err := sql.Open(cfg.DBDriver, cfg.DBSource)
+	if err != nil {
+		log.Fatalf("failed to connect to db: %v", err)
+	}
+	defer conn.Close()
+
+	report, err := db.CheckLedger(context.Background(), conn)
+	if err != nil {
+		log.Fatalf("failed to check the ledger: %v", err)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		log.Fatalf("failed to write the report: %v", err)
+	}
+
+	if !report.Consistent() {
+		os.Exit(1)
+	}
+}
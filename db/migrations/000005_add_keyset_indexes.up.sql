@@ -0,0 +1,5 @@
+CREATE INDEX ON "accounts" ("owner", "created_at", "id");
+
+CREATE INDEX ON "entries" ("created_at", "id");
+
+CREATE INDEX ON "transfers" ("created_at", "id");
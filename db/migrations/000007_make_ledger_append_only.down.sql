@@ -0,0 +1,7 @@
+DROP TRIGGER IF EXISTS transfers_append_only ON transfers;
+
+DROP TRIGGER IF EXISTS entries_append_only ON entries;
+
+DROP FUNCTION IF EXISTS reject_ledger_mutation();
+
+ALTER TABLE IF EXISTS transfers DROP COLUMN IF EXISTS reversed_transfer_id;
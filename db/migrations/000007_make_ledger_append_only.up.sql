@@ -0,0 +1,20 @@
+ALTER TABLE "transfers" ADD COLUMN "reversed_transfer_id" bigint UNIQUE;
+
+ALTER TABLE "transfers" ADD FOREIGN KEY ("reversed_transfer_id") REFERENCES "transfers" ("id");
+
+COMMENT ON COLUMN "transfers"."reversed_transfer_id" IS 'set on the compensating transfer of a reversal';
+
+CREATE FUNCTION reject_ledger_mutation() RETURNS trigger AS $$
+BEGIN
+  RAISE EXCEPTION '% on % is not allowed: the ledger is append-only', TG_OP, TG_TABLE_NAME
+    USING ERRCODE = 'integrity_constraint_violation';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER "entries_append_only"
+  BEFORE UPDATE OR DELETE ON "entries"
+  FOR EACH ROW EXECUTE FUNCTION reject_ledger_mutation();
+
+CREATE TRIGGER "transfers_append_only"
+  BEFORE UPDATE OR DELETE ON "transfers"
+  FOR EACH ROW EXECUTE FUNCTION reject_ledger_mutation();
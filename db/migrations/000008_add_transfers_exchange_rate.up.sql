@@ -0,0 +1,9 @@
+ALTER TABLE "transfers" ADD COLUMN "to_amount" bigint;
+
+ALTER TABLE "transfers" ADD COLUMN "exchange_rate" numeric(20,8);
+
+ALTER TABLE "transfers" ADD CONSTRAINT "transfers_exchange_rate_check" CHECK ("exchange_rate" IS NULL OR "to_amount" IS NOT NULL);
+
+COMMENT ON COLUMN "transfers"."to_amount" IS 'set on cross-currency transfers: amount credited in the destination account currency';
+
+COMMENT ON COLUMN "transfers"."exchange_rate" IS 'units of the destination currency per unit of the source currency';
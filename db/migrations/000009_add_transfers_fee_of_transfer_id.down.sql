@@ -0,0 +1 @@
+ALTER TABLE IF EXISTS transfers DROP COLUMN IF EXISTS fee_of_transfer_id;
@@ -0,0 +1,5 @@
+ALTER TABLE "transfers" ADD COLUMN "fee_of_transfer_id" bigint UNIQUE;
+
+ALTER TABLE "transfers" ADD FOREIGN KEY ("fee_of_transfer_id") REFERENCES "transfers" ("id");
+
+COMMENT ON COLUMN "transfers"."fee_of_transfer_id" IS 'set on the transfer of the fee charged on another transfer';
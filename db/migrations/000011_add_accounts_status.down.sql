@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS "owner_currency_key";
+
+ALTER TABLE "accounts" ADD CONSTRAINT "owner_currency_key" UNIQUE ("owner", "currency");
+
+ALTER TABLE "accounts" DROP CONSTRAINT IF EXISTS "accounts_status_check";
+
+ALTER TABLE "accounts" DROP COLUMN IF EXISTS "closed_at";
+
+ALTER TABLE "accounts" DROP COLUMN IF EXISTS "status";
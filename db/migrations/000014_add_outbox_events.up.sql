@@ -0,0 +1,24 @@
+CREATE TABLE "outbox_events" (
+  "id" bigserial PRIMARY KEY,
+  "event_type" varchar NOT NULL,
+  "version" int NOT NULL,
+  "aggregate_id" bigint NOT NULL,
+  "payload" jsonb NOT NULL,
+  "attempts" int NOT NULL DEFAULT 0,
+  "next_attempt_at" timestamptz NOT NULL DEFAULT (now()),
+  "last_error" varchar,
+  "delivered_at" timestamptz,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "outbox_events" ("next_attempt_at") WHERE "delivered_at" IS NULL;
+
+COMMENT ON COLUMN "outbox_events"."event_type" IS 'AccountCreated, TransferCompleted or TransferReversed';
+
+COMMENT ON COLUMN "outbox_events"."version" IS 'version of the payload schema of the event type';
+
+COMMENT ON COLUMN "outbox_events"."aggregate_id" IS 'id of the account or transfer the event is about';
+
+COMMENT ON COLUMN "outbox_events"."next_attempt_at" IS 'pending events are published from then on';
+
+COMMENT ON COLUMN "outbox_events"."delivered_at" IS 'set when the event is published';
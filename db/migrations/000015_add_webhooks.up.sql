@@ -0,0 +1,46 @@
+CREATE TABLE "webhook_subscriptions" (
+  "id" bigserial PRIMARY KEY,
+  "owner" varchar NOT NULL,
+  "url" varchar NOT NULL,
+  "secret" varchar NOT NULL,
+  "event_types" varchar[] NOT NULL DEFAULT '{}',
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  "disabled_at" timestamptz
+);
+
+CREATE TABLE "webhook_deliveries" (
+  "id" bigserial PRIMARY KEY,
+  "subscription_id" bigint NOT NULL,
+  "event_id" bigint NOT NULL,
+  "status" varchar NOT NULL DEFAULT 'pending',
+  "attempts" int NOT NULL DEFAULT 0,
+  "next_attempt_at" timestamptz NOT NULL DEFAULT (now()),
+  "response_status" int,
+  "last_error" varchar,
+  "delivered_at" timestamptz,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "webhook_subscriptions" ("owner") WHERE "disabled_at" IS NULL;
+
+CREATE UNIQUE INDEX ON "webhook_deliveries" ("subscription_id", "event_id");
+
+CREATE INDEX ON "webhook_deliveries" ("next_attempt_at") WHERE "status" = 'pending';
+
+COMMENT ON COLUMN "webhook_subscriptions"."secret" IS 'key of the HMAC-SHA256 signatures of the deliveries';
+
+COMMENT ON COLUMN "webhook_subscriptions"."event_types" IS 'event types delivered to the subscription, all of them when empty';
+
+COMMENT ON COLUMN "webhook_subscriptions"."disabled_at" IS 'set when the owner deletes the subscription';
+
+COMMENT ON COLUMN "webhook_deliveries"."status" IS 'pending, delivered or dead';
+
+COMMENT ON COLUMN "webhook_deliveries"."next_attempt_at" IS 'pending deliveries are attempted from then on';
+
+COMMENT ON COLUMN "webhook_deliveries"."response_status" IS 'HTTP status of the last response, if any';
+
+ALTER TABLE "webhook_subscriptions" ADD FOREIGN KEY ("owner") REFERENCES "users" ("username");
+
+ALTER TABLE "webhook_deliveries" ADD FOREIGN KEY ("subscription_id") REFERENCES "webhook_subscriptions" ("id");
+
+ALTER TABLE "webhook_deliveries" ADD FOREIGN KEY ("event_id") REFERENCES "outbox_events" ("id");
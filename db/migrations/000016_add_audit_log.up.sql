@@ -0,0 +1,45 @@
+CREATE TABLE "audit_log" (
+  "id" bigserial PRIMARY KEY,
+  "actor" varchar,
+  "action" varchar NOT NULL,
+  "resource_type" varchar NOT NULL,
+  "resource_id" varchar,
+  "request_id" varchar NOT NULL,
+  "client_ip" varchar NOT NULL,
+  "before" json NOT NULL,
+  "after" json NOT NULL,
+  "outcome" varchar NOT NULL,
+  "status_code" int NOT NULL,
+  "created_at" timestamptz NOT NULL,
+  "prev_hash" varchar NOT NULL,
+  "hash" varchar UNIQUE NOT NULL
+);
+
+CREATE INDEX ON "audit_log" ("actor");
+
+CREATE INDEX ON "audit_log" ("resource_type", "resource_id");
+
+COMMENT ON COLUMN "audit_log"."actor" IS 'username of the caller, null for anonymous calls';
+
+COMMENT ON COLUMN "audit_log"."action" IS 'HTTP method and route, such as POST /transfers';
+
+COMMENT ON COLUMN "audit_log"."before" IS 'snapshot of the resource before the call, null when created or unknown';
+
+COMMENT ON COLUMN "audit_log"."after" IS 'snapshot of the resource after the call, null when unchanged or unknown';
+
+COMMENT ON COLUMN "audit_log"."outcome" IS 'success or failure';
+
+COMMENT ON COLUMN "audit_log"."prev_hash" IS 'hash of the previous entry, empty for the first one';
+
+COMMENT ON COLUMN "audit_log"."hash" IS 'SHA-256 of prev_hash and the entry, see db.AuditLogHash';
+
+CREATE FUNCTION reject_audit_log_mutation() RETURNS trigger AS $$
+BEGIN
+  RAISE EXCEPTION '% on % is not allowed: the audit log is append-only', TG_OP, TG_TABLE_NAME
+    USING ERRCODE = 'integrity_constraint_violation';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER "audit_log_append_only"
+  BEFORE UPDATE OR DELETE ON "audit_log"
+  FOR EACH ROW EXECUTE FUNCTION reject_audit_log_mutation();
@@ -0,0 +1,40 @@
+ALTER TABLE "entries" ADD COLUMN "transfer_id" bigint;
+
+ALTER TABLE "entries" ADD FOREIGN KEY ("transfer_id") REFERENCES "transfers" ("id");
+
+CREATE INDEX ON "entries" ("transfer_id");
+
+COMMENT ON COLUMN "entries"."transfer_id" IS 'the transfer the entry was recorded for';
+
+-- Link the existing entries to their transfer. Entries used to be matched by account, amount and creation time:
+-- an entry is only linked when it is the only one, and the transfer side the only one, sharing these. The others
+-- are left unlinked rather than possibly linked to the wrong transfer, see CheckLedger.
+-- The append-only trigger is disabled within the transaction, so that it is enabled again if the backfill fails.
+BEGIN;
+
+ALTER TABLE "entries" DISABLE TRIGGER "entries_append_only";
+
+WITH transfer_sides AS (
+  SELECT id AS transfer_id, from_account_id AS account_id, -amount AS amount, created_at FROM transfers
+  UNION ALL
+  SELECT id, to_account_id, COALESCE(to_amount, amount), created_at FROM transfers
+), unique_transfer_sides AS (
+  SELECT account_id, amount, created_at, min(transfer_id) AS transfer_id
+  FROM transfer_sides
+  GROUP BY account_id, amount, created_at
+  HAVING count(*) = 1
+), unique_entries AS (
+  SELECT account_id, amount, created_at, min(id) AS id
+  FROM entries
+  GROUP BY account_id, amount, created_at
+  HAVING count(*) = 1
+)
+UPDATE entries
+SET transfer_id = unique_transfer_sides.transfer_id
+FROM unique_entries
+JOIN unique_transfer_sides USING (account_id, amount, created_at)
+WHERE entries.id = unique_entries.id;
+
+ALTER TABLE "entries" ENABLE TRIGGER "entries_append_only";
+
+COMMIT;
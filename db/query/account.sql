@@ -22,6 +22,22 @@ ORDER BY id
 LIMIT $1
 OFFSET $2;
 
+-- name: ListAccountsByOwner :many
+SELECT * FROM accounts
+WHERE owner = $1
+  AND status <> 'closed'
+ORDER BY id
+LIMIT $2
+OFFSET $3;
+
+-- name: ListAccountsByOwnerAfter :many
+SELECT * FROM accounts
+WHERE owner = sqlc.arg(owner)
+  AND status <> 'closed'
+  AND (created_at, id) > (sqlc.arg(after_created_at)::timestamptz, sqlc.arg(after_id)::bigint)
+ORDER BY created_at, id
+LIMIT sqlc.arg(page_size);
+
 -- name: UpdateAccount :one
 UPDATE accounts
 SET balance = $2
@@ -34,5 +50,9 @@ SET balance = balance + sqlc.arg(amount)
 WHERE id = sqlc.arg(id)
 RETURNING *;
 
--- name: DeleteAccount :exec
-DELETE FROM accounts WHERE id = $1;
+-- name: UpdateAccountStatus :one
+UPDATE accounts
+SET status = sqlc.arg(status),
+    closed_at = CASE WHEN sqlc.arg(status)::varchar = 'closed' THEN now() END
+WHERE id = sqlc.arg(id)
+RETURNING *;
@@ -0,0 +1,41 @@
+-- name: LockAuditLog :exec
+SELECT pg_advisory_xact_lock(hashtext('audit_log'));
+
+-- name: GetLastAuditLog :one
+SELECT * FROM audit_log
+ORDER BY id DESC
+LIMIT 1;
+
+-- name: CreateAuditLog :one
+INSERT INTO audit_log (
+    actor,
+    action,
+    resource_type,
+    resource_id,
+    request_id,
+    client_ip,
+    before,
+    after,
+    outcome,
+    status_code,
+    created_at,
+    prev_hash,
+    hash
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13
+) RETURNING *;
+
+-- name: ListAuditLog :many
+SELECT * FROM audit_log
+WHERE (sqlc.narg(actor)::varchar IS NULL OR actor = sqlc.narg(actor)::varchar)
+  AND (sqlc.narg(resource_type)::varchar IS NULL OR resource_type = sqlc.narg(resource_type)::varchar)
+  AND (sqlc.narg(resource_id)::varchar IS NULL OR resource_id = sqlc.narg(resource_id)::varchar)
+ORDER BY id DESC
+LIMIT sqlc.arg(page_size)
+OFFSET sqlc.arg(page_offset);
+
+-- name: ListAuditLogAfter :many
+SELECT * FROM audit_log
+WHERE id > $1
+ORDER BY id
+LIMIT $2;
@@ -1,9 +1,10 @@
 -- name: CreateEntry :one
 INSERT INTO entries (
     account_id,
-    amount
+    amount,
+    transfer_id
 ) VALUES (
-    $1, $2
+    $1, $2, $3
 ) RETURNING *;
 
 -- name: GetEntry :one
@@ -16,11 +17,29 @@ ORDER BY id
 LIMIT $1
 OFFSET $2;
 
--- name: UpdateEntry :one
-UPDATE entries
-SET amount = $2
-WHERE id = $1
-RETURNING *;
+-- name: ListAccountStatement :many
+SELECT id, account_id, amount, created_at, running_balance FROM (
+    SELECT
+        e.id,
+        e.account_id,
+        e.amount,
+        e.created_at,
+        (a.balance - SUM(e.amount) OVER () + SUM(e.amount) OVER (ORDER BY e.created_at, e.id))::bigint AS running_balance
+    FROM entries e
+    JOIN accounts a ON a.id = e.account_id
+    WHERE e.account_id = sqlc.arg(account_id)
+) AS statement
+WHERE created_at >= sqlc.arg(period_start)
+  AND created_at < sqlc.arg(period_end)
+  AND (created_at, id) > (sqlc.arg(after_created_at)::timestamptz, sqlc.arg(after_id)::bigint)
+ORDER BY created_at, id
+LIMIT sqlc.arg(page_size);
 
--- name: DeleteEntry :exec
-DELETE FROM entries WHERE id = $1;
+-- name: GetAccountStatementBalances :one
+SELECT
+    (a.balance - COALESCE(SUM(e.amount) FILTER (WHERE e.created_at >= sqlc.arg(period_start)), 0))::bigint AS opening_balance,
+    (a.balance - COALESCE(SUM(e.amount) FILTER (WHERE e.created_at >= sqlc.arg(period_end)), 0))::bigint AS closing_balance
+FROM accounts a
+LEFT JOIN entries e ON e.account_id = a.id
+WHERE a.id = sqlc.arg(account_id)
+GROUP BY a.id;
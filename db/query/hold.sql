@@ -0,0 +1,39 @@
+-- name: CreateHold :one
+INSERT INTO holds (
+    account_id,
+    to_account_id,
+    amount,
+    expires_at
+) VALUES (
+    $1, $2, $3, $4
+) RETURNING *;
+
+-- name: GetHold :one
+SELECT * FROM holds
+WHERE id = $1 LIMIT 1;
+
+-- name: GetHoldForUpdate :one
+SELECT * FROM holds
+WHERE id = $1 LIMIT 1
+FOR UPDATE;
+
+-- name: GetAccountHeldAmount :one
+SELECT COALESCE(SUM(amount), 0)::bigint AS held_amount FROM holds
+WHERE account_id = $1
+  AND status = 'active'
+  AND expires_at > now();
+
+-- name: ResolveHold :one
+UPDATE holds
+SET status = sqlc.arg(status),
+    transfer_id = sqlc.narg(transfer_id),
+    resolved_at = now()
+WHERE id = sqlc.arg(id)
+RETURNING *;
+
+-- name: ExpireHolds :execrows
+UPDATE holds
+SET status = 'expired',
+    resolved_at = now()
+WHERE status = 'active'
+  AND expires_at <= now();
@@ -0,0 +1,15 @@
+-- name: CreateIdempotencyKey :one
+INSERT INTO idempotency_keys (
+    key,
+    username,
+    request_path,
+    request_hash,
+    response_status,
+    response_body
+) VALUES (
+    $1, $2, $3, $4, $5, $6
+) RETURNING *;
+
+-- name: GetIdempotencyKey :one
+SELECT * FROM idempotency_keys
+WHERE username = $1 AND key = $2 LIMIT 1;
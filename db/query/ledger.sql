@@ -0,0 +1,48 @@
+-- name: ListAccountBalanceDrifts :many
+SELECT
+    a.id,
+    a.balance,
+    COALESCE(SUM(e.amount), 0)::bigint AS entries_total
+FROM accounts a
+LEFT JOIN entries e ON e.account_id = a.id
+GROUP BY a.id
+HAVING a.balance <> COALESCE(SUM(e.amount), 0)
+ORDER BY a.id;
+
+-- name: ListOrphanedEntries :many
+SELECT e.* FROM entries e
+WHERE NOT EXISTS (
+    SELECT 1 FROM transfers t
+    WHERE (t.id = e.transfer_id OR (e.transfer_id IS NULL AND t.created_at = e.created_at))
+      AND (
+        (t.from_account_id = e.account_id AND t.amount = -e.amount)
+        OR (t.to_account_id = e.account_id AND COALESCE(t.to_amount, t.amount) = e.amount)
+      )
+)
+ORDER BY e.id;
+
+-- name: ListUnbalancedTransfers :many
+SELECT id, from_account_id, to_account_id, amount, from_entries, to_entries FROM (
+    SELECT
+        t.id,
+        t.from_account_id,
+        t.to_account_id,
+        t.amount,
+        (
+            SELECT CASE WHEN COUNT(e.transfer_id) > 0 THEN COUNT(e.transfer_id) ELSE LEAST(COUNT(*), 1) END
+            FROM entries e
+            WHERE (e.transfer_id = t.id OR (e.transfer_id IS NULL AND e.created_at = t.created_at))
+              AND e.account_id = t.from_account_id
+              AND e.amount = -t.amount
+        ) AS from_entries,
+        (
+            SELECT CASE WHEN COUNT(e.transfer_id) > 0 THEN COUNT(e.transfer_id) ELSE LEAST(COUNT(*), 1) END
+            FROM entries e
+            WHERE (e.transfer_id = t.id OR (e.transfer_id IS NULL AND e.created_at = t.created_at))
+              AND e.account_id = t.to_account_id
+              AND e.amount = COALESCE(t.to_amount, t.amount)
+        ) AS to_entries
+    FROM transfers t
+) AS checked
+WHERE from_entries <> 1 OR to_entries <> 1
+ORDER BY id;
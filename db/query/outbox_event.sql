@@ -0,0 +1,36 @@
+-- name: CreateOutboxEvent :one
+INSERT INTO outbox_events (
+    event_type,
+    version,
+    aggregate_id,
+    payload
+) VALUES (
+    $1, $2, $3, $4
+) RETURNING *;
+
+-- name: ClaimOutboxEvents :many
+UPDATE outbox_events
+SET next_attempt_at = sqlc.arg(locked_until)
+WHERE id IN (
+    SELECT id FROM outbox_events
+    WHERE delivered_at IS NULL
+      AND next_attempt_at <= now()
+    ORDER BY id
+    LIMIT sqlc.arg(max_events)
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: MarkOutboxEventDelivered :exec
+UPDATE outbox_events
+SET attempts = attempts + 1,
+    last_error = NULL,
+    delivered_at = now()
+WHERE id = $1;
+
+-- name: MarkOutboxEventFailed :exec
+UPDATE outbox_events
+SET attempts = attempts + 1,
+    last_error = sqlc.arg(last_error),
+    next_attempt_at = sqlc.arg(next_attempt_at)
+WHERE id = sqlc.arg(id);
@@ -2,26 +2,49 @@
 INSERT INTO transfers (
     from_account_id,
     to_account_id,
-    amount
+    amount,
+    reversed_transfer_id,
+    to_amount,
+    exchange_rate,
+    fee_of_transfer_id
 ) VALUES (
-    $1, $2, $3
+    $1, $2, $3, $4, $5, $6, $7
 ) RETURNING *;
 
 -- name: GetTransfer :one
 SELECT * FROM transfers
 WHERE id = $1 LIMIT 1;
 
+-- name: GetTransferForUpdate :one
+SELECT * FROM transfers
+WHERE id = $1 LIMIT 1
+FOR UPDATE;
+
+-- name: GetTransferReversal :one
+SELECT * FROM transfers
+WHERE reversed_transfer_id = $1 LIMIT 1;
+
 -- name: ListTransfers :many
 SELECT * FROM transfers
 ORDER BY id
 LIMIT $1
 OFFSET $2;
 
--- name: UpdateTransfer :one
-UPDATE transfers
-SET amount = $2
-WHERE id = $1
-RETURNING *;
-
--- name: DeleteTransfer :exec
-DELETE FROM transfers WHERE id = $1;
+-- name: ListAccountTransfers :many
+SELECT * FROM transfers
+WHERE (
+    (sqlc.arg(include_outgoing)::boolean AND from_account_id = sqlc.arg(account_id)::bigint)
+    OR (sqlc.arg(include_incoming)::boolean AND to_account_id = sqlc.arg(account_id)::bigint)
+  )
+  AND (sqlc.narg(counterparty_id)::bigint IS NULL
+    OR (from_account_id = sqlc.arg(account_id)::bigint AND to_account_id = sqlc.narg(counterparty_id)::bigint)
+    OR (to_account_id = sqlc.arg(account_id)::bigint AND from_account_id = sqlc.narg(counterparty_id)::bigint))
+  AND (sqlc.narg(min_amount)::bigint IS NULL
+    OR CASE WHEN to_account_id = sqlc.arg(account_id)::bigint THEN COALESCE(to_amount, amount) ELSE amount END >= sqlc.narg(min_amount)::bigint)
+  AND (sqlc.narg(max_amount)::bigint IS NULL
+    OR CASE WHEN to_account_id = sqlc.arg(account_id)::bigint THEN COALESCE(to_amount, amount) ELSE amount END <= sqlc.narg(max_amount)::bigint)
+  AND created_at >= sqlc.arg(period_start)
+  AND created_at < sqlc.arg(period_end)
+  AND (created_at, id) > (sqlc.arg(after_created_at)::timestamptz, sqlc.arg(after_id)::bigint)
+ORDER BY created_at, id
+LIMIT sqlc.arg(page_size);
@@ -0,0 +1,99 @@
+-- name: CreateWebhookSubscription :one
+INSERT INTO webhook_subscriptions (
+    owner,
+    url,
+    secret,
+    event_types
+) VALUES (
+    $1, $2, $3, $4
+) RETURNING *;
+
+-- name: GetWebhookSubscription :one
+SELECT * FROM webhook_subscriptions
+WHERE id = $1 LIMIT 1;
+
+-- name: GetWebhookSubscriptionForUpdate :one
+SELECT * FROM webhook_subscriptions
+WHERE id = $1 LIMIT 1
+FOR NO KEY UPDATE;
+
+-- name: ListWebhookSubscriptions :many
+SELECT * FROM webhook_subscriptions
+WHERE owner = $1
+  AND disabled_at IS NULL
+ORDER BY id;
+
+-- name: DisableWebhookSubscription :one
+UPDATE webhook_subscriptions
+SET disabled_at = now()
+WHERE id = $1
+  AND disabled_at IS NULL
+RETURNING *;
+
+-- name: CreateWebhookDeliveries :exec
+INSERT INTO webhook_deliveries (subscription_id, event_id)
+SELECT id, sqlc.arg(event_id) FROM webhook_subscriptions
+WHERE owner = ANY(sqlc.arg(owners)::varchar[])
+  AND disabled_at IS NULL
+  AND (cardinality(event_types) = 0 OR sqlc.arg(event_type)::varchar = ANY(event_types));
+
+-- name: GetWebhookDelivery :one
+SELECT * FROM webhook_deliveries
+WHERE id = $1 LIMIT 1;
+
+-- name: GetWebhookDeliveryForUpdate :one
+SELECT * FROM webhook_deliveries
+WHERE id = $1 LIMIT 1
+FOR NO KEY UPDATE;
+
+-- name: ListWebhookDeliveries :many
+SELECT * FROM webhook_deliveries
+WHERE subscription_id = $1
+ORDER BY id DESC
+LIMIT $2
+OFFSET $3;
+
+-- name: ClaimWebhookDeliveries :many
+UPDATE webhook_deliveries d
+SET next_attempt_at = sqlc.arg(locked_until)
+FROM webhook_subscriptions s, outbox_events e
+WHERE d.subscription_id = s.id
+  AND d.event_id = e.id
+  AND d.id IN (
+    SELECT wd.id FROM webhook_deliveries wd
+    JOIN webhook_subscriptions ws ON ws.id = wd.subscription_id
+    WHERE wd.status = 'pending'
+      AND wd.next_attempt_at <= now()
+      AND ws.disabled_at IS NULL
+    ORDER BY wd.id
+    LIMIT sqlc.arg(max_deliveries)
+    FOR UPDATE OF wd SKIP LOCKED
+  )
+RETURNING d.id, d.attempts, s.url, s.secret, e.id AS event_id, e.event_type, e.version, e.aggregate_id, e.payload, e.created_at AS event_created_at;
+
+-- name: MarkWebhookDeliveryDelivered :exec
+UPDATE webhook_deliveries
+SET status = 'delivered',
+    attempts = attempts + 1,
+    response_status = sqlc.arg(response_status),
+    last_error = NULL,
+    delivered_at = now()
+WHERE id = sqlc.arg(id);
+
+-- name: MarkWebhookDeliveryFailed :exec
+UPDATE webhook_deliveries
+SET status = sqlc.arg(status),
+    attempts = attempts + 1,
+    response_status = sqlc.narg(response_status),
+    last_error = sqlc.arg(last_error),
+    next_attempt_at = sqlc.arg(next_attempt_at)
+WHERE id = sqlc.arg(id);
+
+-- name: ReplayWebhookDelivery :one
+UPDATE webhook_deliveries
+SET status = 'pending',
+    attempts = 0,
+    next_attempt_at = now()
+WHERE id = $1
+  AND status <> 'pending'
+RETURNING *;
@@ -2,10 +2,10 @@ package db
 
 import (
 	"context"
-	"database/sql"
 	"testing"
 	"time"
 
+	"github.com/lib/pq"
 	"github.com/stretchr/testify/require"
 
 	"tech-school/util"
@@ -14,8 +14,10 @@ import (
 func createRandomAccount(t *testing.T) Account {
 	ctx := context.Background()
 
+	user := createRandomUser(t)
+
 	arg := CreateAccountParams{
-		Owner:    util.RandomOwner(),
+		Owner:    user.Username,
 		Balance:  util.RandomMoney(),
 		Currency: util.RandomCurrency(),
 	}
@@ -26,6 +28,8 @@ func createRandomAccount(t *testing.T) Account {
 
 	require.NotZero(t, account.ID)
 	require.NotZero(t, account.CreatedAt)
+	require.Equal(t, AccountStatusActive, account.Status)
+	require.False(t, account.ClosedAt.Valid)
 
 	require.Equal(t, arg.Owner, account.Owner)
 	require.Equal(t, arg.Balance, account.Balance)
@@ -76,18 +80,45 @@ func TestUpdateAccount(t *testing.T) {
 
 }
 
-func TestDeleteAccount(t *testing.T) {
+func TestUpdateAccountStatus(t *testing.T) {
 	ctx := context.Background()
 
 	account1 := createRandomAccount(t)
 
-	err := testQueries.DeleteAccount(ctx, account1.ID)
+	account2, err := testQueries.UpdateAccountStatus(ctx, UpdateAccountStatusParams{
+		ID:     account1.ID,
+		Status: AccountStatusFrozen,
+	})
 	require.NoError(t, err)
+	require.Equal(t, AccountStatusFrozen, account2.Status)
+	require.False(t, account2.ClosedAt.Valid)
 
-	account2, err := testQueries.GetAccount(ctx, account1.ID)
-	require.Error(t, err)
-	require.EqualError(t, err, sql.ErrNoRows.Error())
-	require.Empty(t, account2)
+	account2, err = testQueries.UpdateAccountStatus(ctx, UpdateAccountStatusParams{
+		ID:     account1.ID,
+		Status: AccountStatusClosed,
+	})
+	require.NoError(t, err)
+	require.Equal(t, AccountStatusClosed, account2.Status)
+	require.True(t, account2.ClosedAt.Valid)
+
+	// Closing is a soft delete: the account is kept but no longer listed.
+	account3, err := testQueries.GetAccount(ctx, account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, account2, account3)
+
+	accounts, err := testQueries.ListAccountsByOwner(ctx, ListAccountsByOwnerParams{
+		Owner: account1.Owner,
+		Limit: 5,
+	})
+	require.NoError(t, err)
+	require.Empty(t, accounts)
+
+	// A closed account doesn't prevent opening a new one in the same currency.
+	_, err = testQueries.CreateAccount(ctx, CreateAccountParams{
+		Owner:    account1.Owner,
+		Currency: account1.Currency,
+	})
+	require.NoError(t, err)
 }
 
 func TestListAccounts(t *testing.T) {
@@ -110,3 +141,101 @@ func TestListAccounts(t *testing.T) {
 		require.NotEmpty(t, acc)
 	}
 }
+
+func TestListAccountsByOwner(t *testing.T) {
+	ctx := context.Background()
+
+	var lastAccount Account
+	for i := 0; i < 10; i++ {
+		lastAccount = createRandomAccount(t)
+	}
+
+	arg := ListAccountsByOwnerParams{
+		Owner:  lastAccount.Owner,
+		Limit:  5,
+		Offset: 0,
+	}
+
+	accounts, err := testQueries.ListAccountsByOwner(ctx, arg)
+	require.NoError(t, err)
+	require.NotEmpty(t, accounts)
+
+	for _, acc := range accounts {
+		require.NotEmpty(t, acc)
+		require.Equal(t, lastAccount.Owner, acc.Owner)
+	}
+}
+
+func TestCreateAccountDuplicateCurrency(t *testing.T) {
+	ctx := context.Background()
+
+	account1 := createRandomAccount(t)
+
+	arg := CreateAccountParams{
+		Owner:    account1.Owner,
+		Balance:  util.RandomMoney(),
+		Currency: account1.Currency,
+	}
+
+	account2, err := testQueries.CreateAccount(ctx, arg)
+	require.Error(t, err)
+	require.Empty(t, account2)
+
+	pqErr, ok := err.(*pq.Error)
+	require.True(t, ok)
+	require.Equal(t, "unique_violation", pqErr.Code.Name())
+}
+
+func TestCreateAccountUnknownOwner(t *testing.T) {
+	ctx := context.Background()
+
+	arg := CreateAccountParams{
+		Owner:    util.RandomOwner(),
+		Balance:  util.RandomMoney(),
+		Currency: util.RandomCurrency(),
+	}
+
+	account, err := testQueries.CreateAccount(ctx, arg)
+	require.Error(t, err)
+	require.Empty(t, account)
+
+	pqErr, ok := err.(*pq.Error)
+	require.True(t, ok)
+	require.Equal(t, "foreign_key_violation", pqErr.Code.Name())
+}
+
+func TestListAccountsByOwnerAfter(t *testing.T) {
+	ctx := context.Background()
+
+	user := createRandomUser(t)
+
+	var created []Account
+	for _, currency := range []string{"USD", "EUR", "CAD"} {
+		account, err := testQueries.CreateAccount(ctx, CreateAccountParams{
+			Owner:    user.Username,
+			Balance:  util.RandomMoney(),
+			Currency: currency,
+		})
+		require.NoError(t, err)
+		created = append(created, account)
+	}
+
+	arg := ListAccountsByOwnerAfterParams{
+		Owner:    user.Username,
+		PageSize: 2,
+	}
+
+	page1, err := testQueries.ListAccountsByOwnerAfter(ctx, arg)
+	require.NoError(t, err)
+	require.Len(t, page1, 2)
+
+	last := page1[len(page1)-1]
+	arg.AfterCreatedAt = last.CreatedAt
+	arg.AfterID = last.ID
+
+	page2, err := testQueries.ListAccountsByOwnerAfter(ctx, arg)
+	require.NoError(t, err)
+	require.Len(t, page2, 1)
+
+	require.Equal(t, created, append(page1, page2...))
+}
@@ -0,0 +1,159 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+
+	"github.com/google/uuid"
+)
+
+// Outcomes of an audited call.
+const (
+	AuditOutcomeSuccess = "success"
+	AuditOutcomeFailure = "failure"
+)
+
+// maxAuditRequestIDLength bounds the request IDs given by the clients.
+const maxAuditRequestIDLength = 128
+
+// AuditChange describes a resource changed by an audited call, with snapshots of it before and after the change.
+type AuditChange struct {
+	ResourceType string
+	ResourceID   string
+	// Before is nil when the resource is created.
+	Before interface{}
+	After  interface{}
+}
+
+// AuditCall describes an API call for the audit log. The APIs carry it in the context of the call, see WithAuditCall:
+// the store transactions changing resources on its behalf append their change to the audit log within the same
+// transaction, so that no change is committed unaudited. The APIs must only change resources with them.
+type AuditCall struct {
+	// Actor is the username of the caller, empty for anonymous calls.
+	Actor string
+	// Action names the call, such as its HTTP method and route.
+	Action string
+	// ResourceType and ResourceID name the resource of the call when it doesn't record any change.
+	ResourceType string
+	ResourceID   string
+	RequestID    string
+	ClientIP     string
+	// StatusCode is the HTTP status the call responds with when it succeeds.
+	StatusCode int32
+
+	// pending counts the entries appended by the transaction in progress, see runTx.
+	pending int
+	audited bool
+}
+
+type auditCallKey struct{}
+
+// WithAuditCall returns a copy of ctx carrying the audit call.
+func WithAuditCall(ctx context.Context, call *AuditCall) context.Context {
+	return context.WithValue(ctx, auditCallKey{}, call)
+}
+
+// AuditCallFromContext returns the audit call carried by ctx, if any.
+func AuditCallFromContext(ctx context.Context) (*AuditCall, bool) {
+	call, ok := ctx.Value(auditCallKey{}).(*AuditCall)
+	return call, ok
+}
+
+// Audited reports whether a change of the call was committed to the audit log. Otherwise, the call is left
+// for the API to audit once it is over, see Entry.
+func (call *AuditCall) Audited() bool {
+	return call.audited
+}
+
+// Entry returns the audit log entry of the call, with the status it responded with and the change it made, if any.
+// Statuses from 400 on are failures.
+func (call *AuditCall) Entry(statusCode int32, change *AuditChange) CreateAuditLogTxParams {
+	arg := CreateAuditLogTxParams{
+		Actor:        sql.NullString{String: call.Actor, Valid: call.Actor != ""},
+		Action:       call.Action,
+		ResourceType: call.ResourceType,
+		ResourceID:   sql.NullString{String: call.ResourceID, Valid: call.ResourceID != ""},
+		RequestID:    call.RequestID,
+		ClientIp:     call.ClientIP,
+		Outcome:      AuditOutcomeSuccess,
+		StatusCode:   statusCode,
+	}
+
+	if statusCode >= 400 {
+		arg.Outcome = AuditOutcomeFailure
+	}
+
+	if change != nil {
+		arg.ResourceType = change.ResourceType
+		arg.ResourceID = sql.NullString{String: change.ResourceID, Valid: change.ResourceID != ""}
+		arg.Before = auditSnapshot(change.Before)
+		arg.After = auditSnapshot(change.After)
+	}
+
+	return arg
+}
+
+// beginTx forgets the entries appended by a previous attempt of the transaction, which was rolled back.
+func (call *AuditCall) beginTx() {
+	if call != nil {
+		call.pending = 0
+	}
+}
+
+// commitTx records that the entries appended by the transaction are committed.
+func (call *AuditCall) commitTx() {
+	if call != nil && call.pending > 0 {
+		call.audited = true
+	}
+}
+
+// audit appends the change to the audit log with the given transaction queries, on behalf of the call carried by ctx.
+// It is a no-op outside of an audited call, such as for the scheduler. Since it locks the whole audit log until the
+// transaction ends, it must be the last statement of the transaction, see appendAuditLog.
+func audit(ctx context.Context, q *Queries, change AuditChange) error {
+	call, ok := AuditCallFromContext(ctx)
+	if !ok {
+		return nil
+	}
+
+	if _, err := appendAuditLog(ctx, q, call.Entry(call.StatusCode, &change)); err != nil {
+		return fmt.Errorf("failed to write the audit log: %w", err)
+	}
+
+	call.pending++
+
+	return nil
+}
+
+// AuditRequestID returns the request ID given by the client, or a random one if it is empty or too long.
+func AuditRequestID(requestID string) string {
+	if requestID == "" || len(requestID) > maxAuditRequestIDLength {
+		return uuid.NewString()
+	}
+
+	return requestID
+}
+
+// AuditID formats the ID of a changed resource.
+func AuditID(id int64) string {
+	return strconv.FormatInt(id, 10)
+}
+
+// auditSnapshot encodes a snapshot of a resource. It returns nil, stored as null, when there is none.
+func auditSnapshot(v interface{}) json.RawMessage {
+	if v == nil {
+		return nil
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		log.Printf("failed to encode the audit snapshot of %T: %v", v, err)
+		return nil
+	}
+
+	return data
+}
@@ -0,0 +1,278 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"tech-school/util"
+)
+
+func appendRandomAuditLog(t *testing.T, store Store) AuditLog {
+	arg := CreateAuditLogTxParams{
+		Actor:        sql.NullString{String: util.RandomOwner(), Valid: true},
+		Action:       "POST /accounts",
+		ResourceType: "accounts",
+		ResourceID:   sql.NullString{String: util.RandomString(6), Valid: true},
+		RequestID:    util.RandomString(16),
+		ClientIp:     "192.0.2.1",
+		After:        json.RawMessage(`{"balance": 0, "currency": "USD"}`),
+		Outcome:      AuditOutcomeSuccess,
+		StatusCode:   201,
+	}
+
+	entry, err := store.CreateAuditLogTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.NotZero(t, entry.ID)
+
+	require.Equal(t, arg.Actor, entry.Actor)
+	require.Equal(t, arg.Action, entry.Action)
+	require.Equal(t, arg.ResourceType, entry.ResourceType)
+	require.Equal(t, arg.ResourceID, entry.ResourceID)
+	require.Equal(t, arg.RequestID, entry.RequestID)
+	require.Equal(t, arg.ClientIp, entry.ClientIp)
+	require.JSONEq(t, "null", string(entry.Before))
+	// The snapshot is stored as is, so that it hashes the same when read back.
+	require.Equal(t, string(arg.After), string(entry.After))
+	require.Equal(t, arg.Outcome, entry.Outcome)
+	require.Equal(t, arg.StatusCode, entry.StatusCode)
+	require.NotZero(t, entry.CreatedAt)
+	require.NotEmpty(t, entry.Hash)
+
+	return entry
+}
+
+func TestCreateAuditLogTx(t *testing.T) {
+	store := NewStore(testDB)
+
+	first := appendRandomAuditLog(t, store)
+	second := appendRandomAuditLog(t, store)
+
+	require.Equal(t, first.Hash, second.PrevHash)
+
+	entries, err := store.ListAuditLogAfter(context.Background(), ListAuditLogAfterParams{ID: first.ID - 1, Limit: 2})
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+
+	lastHash, err := VerifyAuditLog(first.PrevHash, entries)
+	require.NoError(t, err)
+	require.Equal(t, second.Hash, lastHash)
+}
+
+func TestCreateAuditLogTxConcurrent(t *testing.T) {
+	store := NewStore(testDB)
+
+	n := 5
+	errs := make(chan error)
+	for i := 0; i < n; i++ {
+		go func() {
+			_, err := store.CreateAuditLogTx(context.Background(), CreateAuditLogTxParams{
+				Action:       "DELETE /webhooks/:id",
+				ResourceType: "webhooks",
+				RequestID:    util.RandomString(16),
+				ClientIp:     "192.0.2.1",
+				Outcome:      AuditOutcomeFailure,
+				StatusCode:   404,
+			})
+			errs <- err
+		}()
+	}
+
+	for i := 0; i < n; i++ {
+		require.NoError(t, <-errs)
+	}
+
+	// The appends are serialized, so the chain doesn't fork.
+	last := appendRandomAuditLog(t, store)
+
+	entries, err := store.ListAuditLogAfter(context.Background(), ListAuditLogAfterParams{ID: last.ID - int64(n) - 1, Limit: int32(n) + 1})
+	require.NoError(t, err)
+
+	_, err = VerifyAuditLog(entries[0].PrevHash, entries)
+	require.NoError(t, err)
+}
+
+func TestTransferTxAuditedConcurrent(t *testing.T) {
+	store := NewStore(testDB)
+
+	// Run n concurrent audited transfers both ways between two accounts: each of them appends its entry
+	// as its last statement, so the audit log lock doesn't deadlock with the account locks.
+	n := 10
+	amount := int64(10)
+
+	account1 := createFundedAccount(t, int64(n)*amount)
+	account2 := createFundedAccount(t, int64(n)*amount)
+
+	actor := util.RandomOwner()
+
+	errs := make(chan error)
+	for i := 0; i < n; i++ {
+		fromAccountID := account1.ID
+		toAccountID := account2.ID
+
+		if i%2 == 1 {
+			fromAccountID = account2.ID
+			toAccountID = account1.ID
+		}
+
+		go func() {
+			call := &AuditCall{Actor: actor, Action: "POST /transfers", RequestID: util.RandomString(16), StatusCode: 200}
+
+			_, err := store.TransferTx(WithAuditCall(context.Background(), call), TransferTxParams{
+				FromAccountID: util.SQLNullInt64[int64](fromAccountID),
+				ToAccountID:   util.SQLNullInt64[int64](toAccountID),
+				Amount:        amount,
+			})
+			if err == nil && !call.Audited() {
+				err = errors.New("transfer not audited")
+			}
+
+			errs <- err
+		}()
+	}
+
+	for i := 0; i < n; i++ {
+		require.NoError(t, <-errs)
+	}
+
+	audited, err := store.ListAuditLog(context.Background(), ListAuditLogParams{
+		Actor:    sql.NullString{String: actor, Valid: true},
+		PageSize: int32(n) + 1,
+	})
+	require.NoError(t, err)
+	require.Len(t, audited, n)
+
+	// The entries are listed newest first: the chain is verified from the first one to the last one,
+	// with the entries appended meanwhile by other calls.
+	first, last := audited[n-1], audited[0]
+
+	entries, err := store.ListAuditLogAfter(context.Background(), ListAuditLogAfterParams{
+		ID:    first.ID - 1,
+		Limit: int32(last.ID - first.ID + 1),
+	})
+	require.NoError(t, err)
+	require.Equal(t, last.ID, entries[len(entries)-1].ID)
+
+	lastHash, err := VerifyAuditLog(first.PrevHash, entries)
+	require.NoError(t, err)
+	require.Equal(t, last.Hash, lastHash)
+}
+
+func TestVerifyAuditLogTampered(t *testing.T) {
+	store := NewStore(testDB)
+
+	first := appendRandomAuditLog(t, store)
+	second := appendRandomAuditLog(t, store)
+	entries := []AuditLog{first, second}
+
+	changed := first
+	changed.Outcome = AuditOutcomeFailure
+	_, err := VerifyAuditLog(first.PrevHash, []AuditLog{changed, second})
+	require.True(t, errors.Is(err, ErrAuditLogTampered))
+
+	// Rehashing a changed entry doesn't help, the next one is chained to the original hash.
+	changed.Hash = AuditLogHash(auditLogParams(changed))
+	_, err = VerifyAuditLog(first.PrevHash, []AuditLog{changed, second})
+	require.True(t, errors.Is(err, ErrAuditLogTampered))
+
+	// Nor does removing an entry.
+	_, err = VerifyAuditLog(first.PrevHash, entries[1:])
+	require.True(t, errors.Is(err, ErrAuditLogTampered))
+
+	_, err = VerifyAuditLog(first.PrevHash, entries)
+	require.NoError(t, err)
+}
+
+func TestAuditLogIsAppendOnly(t *testing.T) {
+	store := NewStore(testDB)
+
+	entry := appendRandomAuditLog(t, store)
+
+	_, err := testDB.ExecContext(context.Background(), `UPDATE audit_log SET outcome = 'failure' WHERE id = $1`, entry.ID)
+	require.Error(t, err)
+
+	_, err = testDB.ExecContext(context.Background(), `DELETE FROM audit_log WHERE id = $1`, entry.ID)
+	require.Error(t, err)
+}
+
+func TestListAuditLog(t *testing.T) {
+	store := NewStore(testDB)
+
+	var last AuditLog
+	for i := 0; i < 3; i++ {
+		last = appendRandomAuditLog(t, store)
+	}
+
+	entries, err := store.ListAuditLog(context.Background(), ListAuditLogParams{
+		Actor:        last.Actor,
+		ResourceType: sql.NullString{String: last.ResourceType, Valid: true},
+		PageSize:     5,
+	})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.Equal(t, last, entries[0])
+}
+
+func TestAuditCall(t *testing.T) {
+	store := NewStore(testDB)
+
+	call := &AuditCall{
+		Actor:      util.RandomOwner(),
+		Action:     "POST /accounts",
+		RequestID:  util.RandomString(16),
+		ClientIP:   "192.0.2.1",
+		StatusCode: 201,
+	}
+	ctx := WithAuditCall(context.Background(), call)
+
+	user := createRandomUser(t)
+
+	account, err := store.CreateAccountTx(ctx, CreateAccountTxParams{
+		CreateAccountParams: CreateAccountParams{
+			Owner:    user.Username,
+			Currency: util.RandomCurrency(),
+		},
+	})
+	require.NoError(t, err)
+	require.True(t, call.Audited())
+
+	// The change is appended to the audit log in the transaction that commits it.
+	entries, err := store.ListAuditLog(context.Background(), ListAuditLogParams{
+		Actor:    sql.NullString{String: call.Actor, Valid: true},
+		PageSize: 5,
+	})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+
+	entry := entries[0]
+	require.Equal(t, call.Action, entry.Action)
+	require.Equal(t, "accounts", entry.ResourceType)
+	require.Equal(t, sql.NullString{String: AuditID(account.ID), Valid: true}, entry.ResourceID)
+	require.Equal(t, call.RequestID, entry.RequestID)
+	require.Equal(t, AuditOutcomeSuccess, entry.Outcome)
+	require.Equal(t, int32(201), entry.StatusCode)
+	require.JSONEq(t, "null", string(entry.Before))
+	require.JSONEq(t, string(auditSnapshot(account)), string(entry.After))
+
+	// A failed transaction leaves no entry.
+	call = &AuditCall{Actor: util.RandomOwner(), Action: "PATCH /accounts/:id", StatusCode: 200}
+	ctx = WithAuditCall(context.Background(), call)
+
+	_, err = store.UpdateAccountStatusTx(ctx, UpdateAccountStatusTxParams{ID: account.ID, Status: AccountStatusClosed})
+	require.NoError(t, err)
+
+	_, err = store.UpdateAccountStatusTx(ctx, UpdateAccountStatusTxParams{ID: account.ID, Status: AccountStatusFrozen})
+	require.ErrorIs(t, err, ErrAccountClosed)
+
+	entries, err = store.ListAuditLog(context.Background(), ListAuditLogParams{
+		Actor:    sql.NullString{String: call.Actor, Valid: true},
+		PageSize: 5,
+	})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.JSONEq(t, string(auditSnapshot(account)), string(entries[0].Before))
+}
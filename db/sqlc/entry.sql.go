@@ -8,45 +8,71 @@ package db
 import (
 	"context"
 	"database/sql"
+	"time"
 )
 
 const createEntry = `-- name: CreateEntry :one
 INSERT INTO entries (
     account_id,
-    amount
+    amount,
+    transfer_id
 ) VALUES (
-    $1, $2
-) RETURNING id, account_id, amount, created_at
+    $1, $2, $3
+) RETURNING id, account_id, amount, created_at, transfer_id
 `
 
 type CreateEntryParams struct {
-	AccountID sql.NullInt64
-	Amount    int64
+	AccountID  sql.NullInt64
+	Amount     int64
+	TransferID sql.NullInt64
 }
 
 func (q *Queries) CreateEntry(ctx context.Context, arg CreateEntryParams) (Entry, error) {
-	row := q.db.QueryRowContext(ctx, createEntry, arg.AccountID, arg.Amount)
+	row := q.db.QueryRowContext(ctx, createEntry, arg.AccountID, arg.Amount, arg.TransferID)
 	var i Entry
 	err := row.Scan(
 		&i.ID,
 		&i.AccountID,
 		&i.Amount,
 		&i.CreatedAt,
+		&i.TransferID,
 	)
 	return i, err
 }
 
-const deleteEntry = `-- name: DeleteEntry :exec
-DELETE FROM entries WHERE id = $1
+const getAccountStatementBalances = `-- name: GetAccountStatementBalances :one
+SELECT
+    (a.balance - COALESCE(SUM(e.amount) FILTER (WHERE e.created_at >= $1), 0))::bigint AS opening_balance,
+    (a.balance - COALESCE(SUM(e.amount) FILTER (WHERE e.created_at >= $2), 0))::bigint AS closing_balance
+FROM accounts a
+LEFT JOIN entries e ON e.account_id = a.id
+WHERE a.id = $3
+GROUP BY a.id
 `
 
-func (q *Queries) DeleteEntry(ctx context.Context, id int64) error {
-	_, err := q.db.ExecContext(ctx, deleteEntry, id)
-	return err
+type GetAccountStatementBalancesParams struct {
+	PeriodStart time.Time
+	PeriodEnd   time.Time
+	AccountID   int64
+}
+
+type GetAccountStatementBalancesRow struct {
+	OpeningBalance int64
+	ClosingBalance int64
+}
+
+func (q *Queries) GetAccountStatementBalances(ctx context.Context, arg GetAccountStatementBalancesParams) (GetAccountStatementBalancesRow, error) {
+	row := q.db.QueryRowContext(ctx, getAccountStatementBalances, arg.PeriodStart, arg.PeriodEnd, arg.AccountID)
+	var i GetAccountStatementBalancesRow
+	err := row.Scan(
+		&i.OpeningBalance,
+		&i.ClosingBalance,
+	)
+	return i, err
 }
 
 const getEntry = `-- name: GetEntry :one
-SELECT id, account_id, amount, created_at FROM entries
+SELECT id, account_id, amount, created_at, transfer_id FROM entries
 WHERE id = $1 LIMIT 1
 `
 
@@ -58,12 +84,85 @@ func (q *Queries) GetEntry(ctx context.Context, id int64) (Entry, error) {
 		&i.AccountID,
 		&i.Amount,
 		&i.CreatedAt,
+		&i.TransferID,
 	)
 	return i, err
 }
 
+const listAccountStatement = `-- name: ListAccountStatement :many
+SELECT id, account_id, amount, created_at, running_balance FROM (
+    SELECT
+        e.id,
+        e.account_id,
+        e.amount,
+        e.created_at,
+        (a.balance - SUM(e.amount) OVER () + SUM(e.amount) OVER (ORDER BY e.created_at, e.id))::bigint AS running_balance
+    FROM entries e
+    JOIN accounts a ON a.id = e.account_id
+    WHERE e.account_id = $1
+) AS statement
+WHERE created_at >= $2
+  AND created_at < $3
+  AND (created_at, id) > ($4::timestamptz, $5::bigint)
+ORDER BY created_at, id
+LIMIT $6
+`
+
+type ListAccountStatementParams struct {
+	AccountID      sql.NullInt64
+	PeriodStart    time.Time
+	PeriodEnd      time.Time
+	AfterCreatedAt time.Time
+	AfterID        int64
+	PageSize       int32
+}
+
+type ListAccountStatementRow struct {
+	ID             int64
+	AccountID      sql.NullInt64
+	Amount         int64
+	CreatedAt      time.Time
+	RunningBalance int64
+}
+
+func (q *Queries) ListAccountStatement(ctx context.Context, arg ListAccountStatementParams) ([]ListAccountStatementRow, error) {
+	rows, err := q.db.QueryContext(ctx, listAccountStatement,
+		arg.AccountID,
+		arg.PeriodStart,
+		arg.PeriodEnd,
+		arg.AfterCreatedAt,
+		arg.AfterID,
+		arg.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListAccountStatementRow{}
+	for rows.Next() {
+		var i ListAccountStatementRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.AccountID,
+			&i.Amount,
+			&i.CreatedAt,
+			&i.RunningBalance,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listEntries = `-- name: ListEntries :many
-SELECT id, account_id, amount, created_at FROM entries
+SELECT id, account_id, amount, created_at, transfer_id FROM entries
 ORDER BY id
 LIMIT $1
 OFFSET $2
@@ -88,6 +187,7 @@ func (q *Queries) ListEntries(ctx context.Context, arg ListEntriesParams) ([]Ent
 			&i.AccountID,
 			&i.Amount,
 			&i.CreatedAt,
+			&i.TransferID,
 		); err != nil {
 			return nil, err
 		}
@@ -101,27 +201,3 @@ func (q *Queries) ListEntries(ctx context.Context, arg ListEntriesParams) ([]Ent
 	}
 	return items, nil
 }
-
-const updateEntry = `-- name: UpdateEntry :one
-UPDATE entries
-SET amount = $2
-WHERE id = $1
-RETURNING id, account_id, amount, created_at
-`
-
-type UpdateEntryParams struct {
-	ID     int64
-	Amount int64
-}
-
-func (q *Queries) UpdateEntry(ctx context.Context, arg UpdateEntryParams) (Entry, error) {
-	row := q.db.QueryRowContext(ctx, updateEntry, arg.ID, arg.Amount)
-	var i Entry
-	err := row.Scan(
-		&i.ID,
-		&i.AccountID,
-		&i.Amount,
-		&i.CreatedAt,
-	)
-	return i, err
-}
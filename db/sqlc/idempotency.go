@@ -0,0 +1,51 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/lib/pq"
+)
+
+// ErrDuplicateIdempotencyKey is returned when a concurrent request has already stored the same idempotency key.
+var ErrDuplicateIdempotencyKey = errors.New("duplicate idempotency key")
+
+// IdempotencyParams identifies a client request that must take effect at most once.
+// The response is stored with the key inside the transaction that performs the request.
+type IdempotencyParams struct {
+	Key            string
+	Username       string
+	RequestPath    string
+	RequestHash    string
+	ResponseStatus int32
+}
+
+// saveIdempotencyKey stores the response of an idempotent request. It is a no-op when params is nil.
+func saveIdempotencyKey(ctx context.Context, q *Queries, params *IdempotencyParams, response interface{}) error {
+	if params == nil {
+		return nil
+	}
+
+	body, err := json.Marshal(response)
+	if err != nil {
+		return err
+	}
+
+	_, err = q.CreateIdempotencyKey(ctx, CreateIdempotencyKeyParams{
+		Key:            params.Key,
+		Username:       params.Username,
+		RequestPath:    params.RequestPath,
+		RequestHash:    params.RequestHash,
+		ResponseStatus: params.ResponseStatus,
+		ResponseBody:   body,
+	})
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code.Name() == "unique_violation" {
+			return ErrDuplicateIdempotencyKey
+		}
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,72 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"tech-school/util"
+)
+
+func createRandomIdempotencyKey(t *testing.T) IdempotencyKey {
+	ctx := context.Background()
+
+	user := createRandomUser(t)
+
+	arg := CreateIdempotencyKeyParams{
+		Key:            util.RandomString(16),
+		Username:       user.Username,
+		RequestPath:    "/transfers",
+		RequestHash:    util.RandomString(64),
+		ResponseStatus: http.StatusOK,
+		ResponseBody:   json.RawMessage(`{"amount":10}`),
+	}
+
+	key, err := testQueries.CreateIdempotencyKey(ctx, arg)
+	require.NoError(t, err)
+	require.NotEmpty(t, key)
+
+	require.Equal(t, arg.Key, key.Key)
+	require.Equal(t, arg.Username, key.Username)
+	require.Equal(t, arg.RequestPath, key.RequestPath)
+	require.Equal(t, arg.RequestHash, key.RequestHash)
+	require.Equal(t, arg.ResponseStatus, key.ResponseStatus)
+	require.JSONEq(t, string(arg.ResponseBody), string(key.ResponseBody))
+	require.NotZero(t, key.CreatedAt)
+
+	return key
+}
+
+func TestCreateIdempotencyKey(t *testing.T) {
+	createRandomIdempotencyKey(t)
+}
+
+func TestGetIdempotencyKey(t *testing.T) {
+	ctx := context.Background()
+
+	key1 := createRandomIdempotencyKey(t)
+
+	key2, err := testQueries.GetIdempotencyKey(ctx, GetIdempotencyKeyParams{
+		Username: key1.Username,
+		Key:      key1.Key,
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, key2)
+
+	require.Equal(t, key1.Key, key2.Key)
+	require.Equal(t, key1.Username, key2.Username)
+	require.Equal(t, key1.RequestHash, key2.RequestHash)
+	require.WithinDuration(t, key1.CreatedAt, key2.CreatedAt, time.Second)
+
+	key3, err := testQueries.GetIdempotencyKey(ctx, GetIdempotencyKeyParams{
+		Username: key1.Username,
+		Key:      util.RandomString(16),
+	})
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+	require.Empty(t, key3)
+}
@@ -0,0 +1,168 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"tech-school/util"
+)
+
+func TestCheckLedger(t *testing.T) {
+	ctx := context.Background()
+
+	store := NewStore(testDB)
+
+	driftingAccount := createFundedAccount(t, 100)
+	orphanedEntry := createRandomEntry(t)
+	unbalancedTransfer := createRandomTransfer(t)
+
+	result, err := store.TransferTx(ctx, TransferTxParams{
+		FromAccountID: util.SQLNullInt64(driftingAccount.ID),
+		ToAccountID:   util.SQLNullInt64(createRandomAccount(t).ID),
+		Amount:        10,
+	})
+	require.NoError(t, err)
+	require.Equal(t, util.SQLNullInt64(result.Transfer.ID), result.FromEntry.TransferID)
+	require.Equal(t, util.SQLNullInt64(result.Transfer.ID), result.ToEntry.TransferID)
+
+	report, err := CheckLedger(ctx, testDB)
+	require.NoError(t, err)
+	require.False(t, report.Consistent())
+	require.NotZero(t, report.CheckedAt)
+
+	require.Contains(t, report.AccountDrifts, AccountDrift{
+		AccountID:    driftingAccount.ID,
+		Balance:      90,
+		EntriesTotal: -10,
+		Drift:        100,
+	})
+
+	require.Contains(t, report.OrphanedEntries, OrphanedEntry{
+		EntryID:   orphanedEntry.ID,
+		AccountID: orphanedEntry.AccountID.Int64,
+		Amount:    orphanedEntry.Amount,
+		CreatedAt: orphanedEntry.CreatedAt,
+	})
+
+	require.Contains(t, report.UnbalancedTransfers, UnbalancedTransfer{
+		TransferID:    unbalancedTransfer.ID,
+		FromAccountID: unbalancedTransfer.FromAccountID.Int64,
+		ToAccountID:   unbalancedTransfer.ToAccountID.Int64,
+		Amount:        unbalancedTransfer.Amount,
+	})
+
+	for _, e := range report.OrphanedEntries {
+		require.NotEqual(t, result.FromEntry.ID, e.EntryID)
+		require.NotEqual(t, result.ToEntry.ID, e.EntryID)
+	}
+	for _, trn := range report.UnbalancedTransfers {
+		require.NotEqual(t, result.Transfer.ID, trn.TransferID)
+	}
+}
+
+func TestCheckLedgerTransfersInOneTx(t *testing.T) {
+	ctx := context.Background()
+
+	store := NewStore(testDB).(*SQLStore)
+
+	account := createFundedAccount(t, 100)
+
+	// Like a transfer and its fee: same source account, amount and creation time.
+	var results []TransferTxResult
+	_, err := store.execTx(ctx, nil, func(q *Queries) error {
+		for i := 0; i < 2; i++ {
+			from, to, err := lockTransferAccounts(ctx, q, account.ID, createRandomAccount(t).ID)
+			if err != nil {
+				return err
+			}
+
+			result, err := transfer(ctx, q, transferParams{FromAccount: from, ToAccount: to, Amount: 10})
+			if err != nil {
+				return err
+			}
+			results = append(results, result)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	require.Equal(t, results[0].Transfer.CreatedAt, results[1].Transfer.CreatedAt)
+
+	report, err := CheckLedger(ctx, testDB)
+	require.NoError(t, err)
+
+	for _, result := range results {
+		for _, e := range report.OrphanedEntries {
+			require.NotEqual(t, result.FromEntry.ID, e.EntryID)
+			require.NotEqual(t, result.ToEntry.ID, e.EntryID)
+		}
+		for _, trn := range report.UnbalancedTransfers {
+			require.NotEqual(t, result.Transfer.ID, trn.TransferID)
+		}
+	}
+}
+
+func TestCheckLedgerLegacyEntries(t *testing.T) {
+	ctx := context.Background()
+
+	store := NewStore(testDB).(*SQLStore)
+
+	fromAccount := createRandomAccount(t)
+	toAccount := createRandomAccount(t)
+
+	// Like two transfers recorded before entries were linked, that the migration couldn't tell apart.
+	var (
+		transfers []Transfer
+		entries   []Entry
+	)
+	_, err := store.execTx(ctx, nil, func(q *Queries) error {
+		for i := 0; i < 2; i++ {
+			transfer, err := q.CreateTransfer(ctx, CreateTransferParams{
+				FromAccountID: util.SQLNullInt64(fromAccount.ID),
+				ToAccountID:   util.SQLNullInt64(toAccount.ID),
+				Amount:        10,
+			})
+			if err != nil {
+				return err
+			}
+			transfers = append(transfers, transfer)
+
+			for _, arg := range []CreateEntryParams{
+				{AccountID: util.SQLNullInt64(fromAccount.ID), Amount: -10},
+				{AccountID: util.SQLNullInt64(toAccount.ID), Amount: 10},
+			} {
+				entry, err := q.CreateEntry(ctx, arg)
+				if err != nil {
+					return err
+				}
+				entries = append(entries, entry)
+			}
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	report, err := CheckLedger(ctx, testDB)
+	require.NoError(t, err)
+
+	for _, entry := range entries {
+		require.False(t, entry.TransferID.Valid)
+		for _, e := range report.OrphanedEntries {
+			require.NotEqual(t, entry.ID, e.EntryID)
+		}
+	}
+	for _, transfer := range transfers {
+		for _, trn := range report.UnbalancedTransfers {
+			require.NotEqual(t, transfer.ID, trn.TransferID)
+		}
+	}
+}
+
+func TestLedgerReportConsistent(t *testing.T) {
+	require.True(t, LedgerReport{}.Consistent())
+	require.False(t, LedgerReport{AccountDrifts: []AccountDrift{{AccountID: 1, Drift: 1}}}.Consistent())
+	require.False(t, LedgerReport{OrphanedEntries: []OrphanedEntry{{EntryID: 1}}}.Consistent())
+	require.False(t, LedgerReport{UnbalancedTransfers: []UnbalancedTransfer{{TransferID: 1}}}.Consistent())
+}
@@ -6,6 +6,7 @@ package db
 
 import (
 	"database/sql"
+	"encoding/json"
 	"time"
 )
 
@@ -15,6 +16,34 @@ type Account struct {
 	Balance   int64
 	Currency  string
 	CreatedAt time.Time
+	// active, frozen or closed
+	Status string
+	// set when the account is closed
+	ClosedAt sql.NullTime
+}
+
+type AuditLog struct {
+	ID int64
+	// username of the caller, null for anonymous calls
+	Actor sql.NullString
+	// HTTP method and route, such as POST /transfers
+	Action       string
+	ResourceType string
+	ResourceID   sql.NullString
+	RequestID    string
+	ClientIp     string
+	// snapshot of the resource before the call, null when created or unknown
+	Before json.RawMessage
+	// snapshot of the resource after the call, null when unchanged or unknown
+	After json.RawMessage
+	// success or failure
+	Outcome    string
+	StatusCode int32
+	CreatedAt  time.Time
+	// hash of the previous entry, empty for the first one
+	PrevHash string
+	// SHA-256 of prev_hash and the entry, see db.AuditLogHash
+	Hash string
 }
 
 type Entry struct {
@@ -23,6 +52,79 @@ type Entry struct {
 	// can be negative or positive
 	Amount    int64
 	CreatedAt time.Time
+	// the transfer the entry was recorded for
+	TransferID sql.NullInt64
+}
+
+type Hold struct {
+	ID          int64
+	AccountID   int64
+	ToAccountID int64
+	// must be positive
+	Amount int64
+	// active, captured, released or expired
+	Status    string
+	ExpiresAt time.Time
+	// set when the hold is captured
+	TransferID sql.NullInt64
+	CreatedAt  time.Time
+	ResolvedAt sql.NullTime
+}
+
+type IdempotencyKey struct {
+	Key            string
+	Username       string
+	RequestPath    string
+	RequestHash    string
+	ResponseStatus int32
+	ResponseBody   json.RawMessage
+	CreatedAt      time.Time
+}
+
+type OutboxEvent struct {
+	ID int64
+	// AccountCreated, TransferCompleted or TransferReversed
+	EventType string
+	// version of the payload schema of the event type
+	Version int32
+	// id of the account or transfer the event is about
+	AggregateID int64
+	Payload     json.RawMessage
+	Attempts    int32
+	// pending events are published from then on
+	NextAttemptAt time.Time
+	LastError     sql.NullString
+	// set when the event is published
+	DeliveredAt sql.NullTime
+	CreatedAt   time.Time
+}
+
+type ScheduledTransfer struct {
+	ID            int64
+	Owner         string
+	FromAccountID int64
+	ToAccountID   int64
+	// must be positive
+	Amount int64
+	// currency of both accounts
+	Currency string
+	// cron expression or @every interval, null for a one-off transfer
+	Schedule  sql.NullString
+	NextRunAt time.Time
+	// active, completed or cancelled
+	Status    string
+	CreatedAt time.Time
+}
+
+type ScheduledTransferRun struct {
+	ID                  int64
+	ScheduledTransferID int64
+	ScheduledAt         time.Time
+	// set when the run succeeded
+	TransferID sql.NullInt64
+	// set when the run failed
+	Error     sql.NullString
+	CreatedAt time.Time
 }
 
 type Transfer struct {
@@ -32,4 +134,52 @@ type Transfer struct {
 	// must be positive
 	Amount    int64
 	CreatedAt time.Time
+	// set on the compensating transfer of a reversal
+	ReversedTransferID sql.NullInt64
+	// set on cross-currency transfers: amount credited in the destination account currency
+	ToAmount sql.NullInt64
+	// units of the destination currency per unit of the source currency
+	ExchangeRate sql.NullString
+	// set on the transfer of the fee charged on another transfer
+	FeeOfTransferID sql.NullInt64
+}
+
+type User struct {
+	Username          string
+	HashedPassword    string
+	FullName          string
+	Email             string
+	PasswordChangedAt time.Time
+	CreatedAt         time.Time
+	// customer or admin
+	Role string
+}
+
+type WebhookDelivery struct {
+	ID             int64
+	SubscriptionID int64
+	EventID        int64
+	// pending, delivered or dead
+	Status   string
+	Attempts int32
+	// pending deliveries are attempted from then on
+	NextAttemptAt time.Time
+	// HTTP status of the last response, if any
+	ResponseStatus sql.NullInt32
+	LastError      sql.NullString
+	DeliveredAt    sql.NullTime
+	CreatedAt      time.Time
+}
+
+type WebhookSubscription struct {
+	ID    int64
+	Owner string
+	Url   string
+	// key of the HMAC-SHA256 signatures of the deliveries
+	Secret string
+	// event types delivered to the subscription, all of them when empty
+	EventTypes []string
+	CreatedAt  time.Time
+	// set when the owner deletes the subscription
+	DisabledAt sql.NullTime
 }
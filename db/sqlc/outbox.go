@@ -0,0 +1,122 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// Types of the events written to the outbox.
+const (
+	EventAccountCreated    = "AccountCreated"
+	EventTransferCompleted = "TransferCompleted"
+	EventTransferReversed  = "TransferReversed"
+)
+
+// Versions of the event payloads. A version is bumped whenever a change to its payload
+// could break consumers, such as renaming or removing a field; adding a field doesn't.
+const (
+	AccountCreatedVersion = 1
+	// TransferEventVersion 2 dropped the owners of the accounts: the event is delivered to the webhooks
+	// of both owners, who must not learn each other's username.
+	TransferEventVersion = 2
+)
+
+// AccountCreatedEvent is the payload of the AccountCreated events.
+type AccountCreatedEvent struct {
+	AccountID int64     `json:"account_id"`
+	Owner     string    `json:"owner"`
+	Currency  string    `json:"currency"`
+	Balance   int64     `json:"balance"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TransferEvent is the payload of the TransferCompleted and TransferReversed events.
+// Fees are transfers of their own, linked to the transfer they are charged on by FeeOfTransferID.
+type TransferEvent struct {
+	TransferID    int64 `json:"transfer_id"`
+	FromAccountID int64 `json:"from_account_id"`
+	ToAccountID   int64 `json:"to_account_id"`
+	// Amount is debited from the source account, in its currency.
+	Amount       int64  `json:"amount"`
+	FromCurrency string `json:"from_currency"`
+	// ToAmount is credited to the destination account, in its currency.
+	ToAmount           int64     `json:"to_amount"`
+	ToCurrency         string    `json:"to_currency"`
+	ExchangeRate       string    `json:"exchange_rate,omitempty"`
+	ReversedTransferID *int64    `json:"reversed_transfer_id,omitempty"`
+	FeeOfTransferID    *int64    `json:"fee_of_transfer_id,omitempty"`
+	CreatedAt          time.Time `json:"created_at"`
+}
+
+// recordEvent writes an event to the outbox, and queues its delivery to the webhooks subscribed to it by the owners
+// it concerns, using the given transaction queries, so that it is published if and only if the transaction commits.
+func recordEvent(ctx context.Context, q *Queries, eventType string, version int32, aggregateID int64, owners []string, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	event, err := q.CreateOutboxEvent(ctx, CreateOutboxEventParams{
+		EventType:   eventType,
+		Version:     version,
+		AggregateID: aggregateID,
+		Payload:     data,
+	})
+	if err != nil {
+		return err
+	}
+
+	return q.CreateWebhookDeliveries(ctx, CreateWebhookDeliveriesParams{
+		EventID:   event.ID,
+		Owners:    owners,
+		EventType: eventType,
+	})
+}
+
+// recordAccountCreated writes the AccountCreated event of the account to the outbox.
+func recordAccountCreated(ctx context.Context, q *Queries, account Account) error {
+	return recordEvent(ctx, q, EventAccountCreated, AccountCreatedVersion, account.ID, []string{account.Owner}, AccountCreatedEvent{
+		AccountID: account.ID,
+		Owner:     account.Owner,
+		Currency:  account.Currency,
+		Balance:   account.Balance,
+		CreatedAt: account.CreatedAt,
+	})
+}
+
+// recordTransfer writes the TransferCompleted event of the transfer to the outbox,
+// or its TransferReversed event when it compensates another transfer.
+func recordTransfer(ctx context.Context, q *Queries, result TransferTxResult) error {
+	transfer := result.Transfer
+
+	event := TransferEvent{
+		TransferID:    transfer.ID,
+		FromAccountID: result.FromAccount.ID,
+		ToAccountID:   result.ToAccount.ID,
+		Amount:        transfer.Amount,
+		FromCurrency:  result.FromAccount.Currency,
+		ToAmount:      transfer.Amount,
+		ToCurrency:    result.ToAccount.Currency,
+		CreatedAt:     transfer.CreatedAt,
+	}
+	if transfer.ToAmount.Valid {
+		event.ToAmount = transfer.ToAmount.Int64
+	}
+	if transfer.ExchangeRate.Valid {
+		event.ExchangeRate = transfer.ExchangeRate.String
+	}
+	if transfer.FeeOfTransferID.Valid {
+		event.FeeOfTransferID = &transfer.FeeOfTransferID.Int64
+	}
+
+	eventType := EventTransferCompleted
+	if transfer.ReversedTransferID.Valid {
+		eventType = EventTransferReversed
+		event.ReversedTransferID = &transfer.ReversedTransferID.Int64
+	}
+
+	owners := []string{result.FromAccount.Owner, result.ToAccount.Owner}
+
+	return recordEvent(ctx, q, eventType, TransferEventVersion, transfer.ID, owners, event)
+}
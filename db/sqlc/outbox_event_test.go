@@ -0,0 +1,194 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"tech-school/util"
+)
+
+// getOutboxEvent returns the event of the given type about the given account or transfer.
+func getOutboxEvent(t *testing.T, eventType string, aggregateID int64) OutboxEvent {
+	var event OutboxEvent
+	err := testDB.QueryRowContext(context.Background(), `
+		SELECT id, event_type, version, aggregate_id, payload, attempts, next_attempt_at, last_error, delivered_at, created_at
+		FROM outbox_events WHERE event_type = $1 AND aggregate_id = $2`, eventType, aggregateID).Scan(
+		&event.ID,
+		&event.EventType,
+		&event.Version,
+		&event.AggregateID,
+		&event.Payload,
+		&event.Attempts,
+		&event.NextAttemptAt,
+		&event.LastError,
+		&event.DeliveredAt,
+		&event.CreatedAt,
+	)
+	require.NoError(t, err)
+
+	require.Zero(t, event.Attempts)
+	require.False(t, event.DeliveredAt.Valid)
+
+	return event
+}
+
+func TestOutboxEvents(t *testing.T) {
+	ctx := context.Background()
+
+	store := NewStore(testDB)
+
+	user := createRandomUser(t)
+
+	account, err := store.CreateAccountTx(ctx, CreateAccountTxParams{
+		CreateAccountParams: CreateAccountParams{
+			Owner:    user.Username,
+			Currency: util.RandomCurrency(),
+		},
+	})
+	require.NoError(t, err)
+
+	event := getOutboxEvent(t, EventAccountCreated, account.ID)
+	require.Equal(t, int32(AccountCreatedVersion), event.Version)
+
+	var accountCreated AccountCreatedEvent
+	require.NoError(t, json.Unmarshal(event.Payload, &accountCreated))
+	require.Equal(t, account.ID, accountCreated.AccountID)
+	require.Equal(t, account.Owner, accountCreated.Owner)
+	require.Equal(t, account.Currency, accountCreated.Currency)
+	require.WithinDuration(t, account.CreatedAt, accountCreated.CreatedAt, time.Second)
+
+	account1 := createFundedAccount(t, 100)
+	account2 := createRandomAccount(t)
+
+	result, err := store.TransferTx(ctx, TransferTxParams{
+		FromAccountID: util.SQLNullInt64[int64](account1.ID),
+		ToAccountID:   util.SQLNullInt64[int64](account2.ID),
+		Amount:        10,
+	})
+	require.NoError(t, err)
+
+	event = getOutboxEvent(t, EventTransferCompleted, result.Transfer.ID)
+	require.Equal(t, int32(TransferEventVersion), event.Version)
+
+	var completed TransferEvent
+	require.NoError(t, json.Unmarshal(event.Payload, &completed))
+	require.Equal(t, result.Transfer.ID, completed.TransferID)
+	require.Equal(t, account1.ID, completed.FromAccountID)
+	require.Equal(t, account2.ID, completed.ToAccountID)
+	// Both owners get the event: neither is told the other's username.
+	require.NotContains(t, string(event.Payload), account1.Owner)
+	require.NotContains(t, string(event.Payload), account2.Owner)
+	require.Equal(t, int64(10), completed.Amount)
+	require.Equal(t, int64(10), completed.ToAmount)
+	require.Nil(t, completed.ReversedTransferID)
+
+	reversal, err := store.ReverseTransferTx(ctx, ReverseTransferTxParams{TransferID: result.Transfer.ID})
+	require.NoError(t, err)
+
+	event = getOutboxEvent(t, EventTransferReversed, reversal.Transfer.ID)
+
+	var reversed TransferEvent
+	require.NoError(t, json.Unmarshal(event.Payload, &reversed))
+	require.Equal(t, reversal.Transfer.ID, reversed.TransferID)
+	require.Equal(t, account2.ID, reversed.FromAccountID)
+	require.Equal(t, account1.ID, reversed.ToAccountID)
+	require.NotNil(t, reversed.ReversedTransferID)
+	require.Equal(t, result.Transfer.ID, *reversed.ReversedTransferID)
+}
+
+func TestOutboxEventsRolledBack(t *testing.T) {
+	ctx := context.Background()
+
+	store := NewStore(testDB)
+
+	account1 := createRandomAccount(t)
+	account2 := createRandomAccount(t)
+
+	var before int64
+	err := testDB.QueryRowContext(ctx, "SELECT COALESCE(MAX(id), 0) FROM outbox_events").Scan(&before)
+	require.NoError(t, err)
+
+	_, err = store.TransferTx(ctx, TransferTxParams{
+		FromAccountID: util.SQLNullInt64[int64](account1.ID),
+		ToAccountID:   util.SQLNullInt64[int64](account2.ID),
+		Amount:        account1.Balance + 1,
+	})
+	require.ErrorIs(t, err, ErrInsufficientFunds)
+
+	// A failed transfer leaves no event behind.
+	var count int
+	err = testDB.QueryRowContext(ctx, `
+		SELECT count(*) FROM outbox_events
+		WHERE id > $1 AND payload->>'from_account_id' = $2::text`, before, account1.ID).Scan(&count)
+	require.NoError(t, err)
+	require.Zero(t, count)
+}
+
+func TestClaimOutboxEvents(t *testing.T) {
+	ctx := context.Background()
+
+	// Claim all the pending events, so that the ones created below are the only ones due.
+	for {
+		events, err := testQueries.ClaimOutboxEvents(ctx, ClaimOutboxEventsParams{
+			LockedUntil: time.Now().Add(time.Hour),
+			MaxEvents:   100,
+		})
+		require.NoError(t, err)
+		if len(events) == 0 {
+			break
+		}
+	}
+
+	account := createRandomAccount(t)
+	event, err := testQueries.CreateOutboxEvent(ctx, CreateOutboxEventParams{
+		EventType:   EventAccountCreated,
+		Version:     AccountCreatedVersion,
+		AggregateID: account.ID,
+		Payload:     json.RawMessage(`{}`),
+	})
+	require.NoError(t, err)
+
+	lockedUntil := time.Now().Add(time.Minute)
+	events, err := testQueries.ClaimOutboxEvents(ctx, ClaimOutboxEventsParams{LockedUntil: lockedUntil, MaxEvents: 100})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	require.Equal(t, event.ID, events[0].ID)
+	require.WithinDuration(t, lockedUntil, events[0].NextAttemptAt, time.Second)
+
+	// A claimed event isn't claimed again until its lease runs out.
+	events, err = testQueries.ClaimOutboxEvents(ctx, ClaimOutboxEventsParams{LockedUntil: lockedUntil, MaxEvents: 100})
+	require.NoError(t, err)
+	require.Empty(t, events)
+
+	err = testQueries.MarkOutboxEventFailed(ctx, MarkOutboxEventFailedParams{
+		ID:            event.ID,
+		LastError:     sql.NullString{String: "unavailable", Valid: true},
+		NextAttemptAt: time.Now().Add(-time.Second),
+	})
+	require.NoError(t, err)
+
+	events, err = testQueries.ClaimOutboxEvents(ctx, ClaimOutboxEventsParams{LockedUntil: lockedUntil, MaxEvents: 100})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	require.Equal(t, int32(1), events[0].Attempts)
+	require.Equal(t, "unavailable", events[0].LastError.String)
+
+	err = testQueries.MarkOutboxEventDelivered(ctx, event.ID)
+	require.NoError(t, err)
+
+	// Even when due, delivered events are never claimed again.
+	err = testQueries.MarkOutboxEventFailed(ctx, MarkOutboxEventFailedParams{
+		ID:            event.ID,
+		NextAttemptAt: time.Now().Add(-time.Second),
+	})
+	require.NoError(t, err)
+
+	events, err = testQueries.ClaimOutboxEvents(ctx, ClaimOutboxEventsParams{LockedUntil: lockedUntil, MaxEvents: 100})
+	require.NoError(t, err)
+	require.Empty(t, events)
+}
@@ -0,0 +1,68 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+
+	"tech-school/backoff"
+)
+
+const (
+	serializationFailure = "serialization_failure"
+	deadlockDetected     = "deadlock_detected"
+)
+
+// RetryPolicy controls how execTx retries transactions aborted by
+// serialization failures or deadlocks.
+type RetryPolicy struct {
+	// MaxRetries is the number of retries after the first attempt. Zero disables retrying.
+	MaxRetries int
+	// BaseDelay is the backoff before the first retry. It doubles on every following retry.
+	BaseDelay time.Duration
+	// MaxDelay caps the backoff between two attempts.
+	MaxDelay time.Duration
+}
+
+// DefaultRetryPolicy is used by NewStore unless WithRetryPolicy is given.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxRetries: 3,
+	BaseDelay:  10 * time.Millisecond,
+	MaxDelay:   500 * time.Millisecond,
+}
+
+// backoff returns a jittered delay before the given retry, counting from zero, see backoff.Jittered.
+func (p RetryPolicy) backoff(retry int) time.Duration {
+	return backoff.Jittered(p.BaseDelay, p.MaxDelay, retry)
+}
+
+// isRetryable reports whether the transaction failed because of a serialization failure or a deadlock
+// and can be safely run again.
+func isRetryable(err error) bool {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return false
+	}
+
+	switch pqErr.Code.Name() {
+	case serializationFailure, deadlockDetected:
+		return true
+	default:
+		return false
+	}
+}
+
+// sleep waits for the given duration or until the context is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,140 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := RetryPolicy{
+		MaxRetries: 5,
+		BaseDelay:  10 * time.Millisecond,
+		MaxDelay:   50 * time.Millisecond,
+	}
+
+	expected := []time.Duration{
+		10 * time.Millisecond,
+		20 * time.Millisecond,
+		40 * time.Millisecond,
+		50 * time.Millisecond,
+		50 * time.Millisecond,
+	}
+
+	for retry, delay := range expected {
+		for i := 0; i < 100; i++ {
+			got := policy.backoff(retry)
+			require.GreaterOrEqual(t, got, delay/2)
+			require.LessOrEqual(t, got, delay)
+		}
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	testCases := []struct {
+		name      string
+		err       error
+		retryable bool
+	}{
+		{
+			name:      "SerializationFailure",
+			err:       &pq.Error{Code: "40001"},
+			retryable: true,
+		},
+		{
+			name:      "DeadlockDetected",
+			err:       &pq.Error{Code: "40P01"},
+			retryable: true,
+		},
+		{
+			name:      "Wrapped",
+			err:       fmt.Errorf("tx error: %w, rb error: %v", &pq.Error{Code: "40001"}, sql.ErrTxDone),
+			retryable: true,
+		},
+		{
+			name:      "UniqueViolation",
+			err:       &pq.Error{Code: "23505"},
+			retryable: false,
+		},
+		{
+			name:      "InsufficientFunds",
+			err:       ErrInsufficientFunds,
+			retryable: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.retryable, isRetryable(tc.err))
+		})
+	}
+}
+
+func TestExecTxRetriesSerializationFailure(t *testing.T) {
+	ctx := context.Background()
+
+	store := NewStore(testDB, WithRetryPolicy(RetryPolicy{
+		MaxRetries: 50,
+		BaseDelay:  time.Millisecond,
+		MaxDelay:   20 * time.Millisecond,
+	})).(*SQLStore)
+
+	account := createRandomAccount(t)
+
+	// Run n concurrent read-modify-write transactions on the same row,
+	// which abort with serialization failures under serializable isolation.
+	n := 5
+	opts := &sql.TxOptions{Isolation: sql.LevelSerializable}
+
+	errs := make(chan error)
+
+	for i := 0; i < n; i++ {
+		go func() {
+			_, err := store.execTx(ctx, opts, func(q *Queries) error {
+				acc, err := q.GetAccount(ctx, account.ID)
+				if err != nil {
+					return err
+				}
+
+				_, err = q.UpdateAccount(ctx, UpdateAccountParams{
+					ID:      acc.ID,
+					Balance: acc.Balance + 1,
+				})
+				return err
+			})
+
+			errs <- err
+		}()
+	}
+
+	for i := 0; i < n; i++ {
+		err := <-errs
+		require.NoError(t, err)
+	}
+
+	updatedAccount, err := testQueries.GetAccount(ctx, account.ID)
+	require.NoError(t, err)
+	require.Equal(t, account.Balance+int64(n), updatedAccount.Balance)
+}
+
+func TestExecTxReadOnly(t *testing.T) {
+	ctx := context.Background()
+
+	store := NewStore(testDB).(*SQLStore)
+
+	account := createRandomAccount(t)
+
+	_, err := store.execTx(ctx, &sql.TxOptions{ReadOnly: true}, func(q *Queries) error {
+		_, err := q.UpdateAccount(ctx, UpdateAccountParams{
+			ID:      account.ID,
+			Balance: account.Balance + 1,
+		})
+		return err
+	})
+	require.Error(t, err)
+}
@@ -0,0 +1,8 @@
+package db
+
+// Roles of a user.
+const (
+	UserRoleCustomer = "customer"
+	// UserRoleAdmin may manage the accounts of every user.
+	UserRoleAdmin = "admin"
+)
@@ -3,26 +3,102 @@ package db
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"sort"
+
+	"tech-school/fees"
 )
 
-// Store provides all functions to execute db quries & transactions.
-type Store struct {
+// ErrInsufficientFunds is returned when the source account balance cannot cover a transfer.
+var ErrInsufficientFunds = errors.New("insufficient funds")
+
+// Store provides all functions to execute db queries & transactions.
+type Store interface {
+	Querier
+	AccountStatementTx(ctx context.Context, arg AccountStatementTxParams) (AccountStatementTxResult, error)
+	CancelScheduledTransferTx(ctx context.Context, id int64) (ScheduledTransfer, error)
+	CaptureHoldTx(ctx context.Context, arg CaptureHoldTxParams) (CaptureHoldTxResult, error)
+	CreateAccountTx(ctx context.Context, arg CreateAccountTxParams) (Account, error)
+	CreateAuditLogTx(ctx context.Context, arg CreateAuditLogTxParams) (AuditLog, error)
+	CreateScheduledTransferTx(ctx context.Context, arg CreateScheduledTransferTxParams) (ScheduledTransfer, error)
+	CreateUserTx(ctx context.Context, arg CreateUserParams) (User, error)
+	CreateWebhookSubscriptionTx(ctx context.Context, arg CreateWebhookSubscriptionParams) (WebhookSubscription, error)
+	CrossCurrencyTransferTx(ctx context.Context, arg CrossCurrencyTransferTxParams) (TransferTxResult, error)
+	DisableWebhookSubscriptionTx(ctx context.Context, id int64) (WebhookSubscription, error)
+	PlaceHoldTx(ctx context.Context, arg PlaceHoldTxParams) (Hold, error)
+	ReleaseHoldTx(ctx context.Context, holdID int64) (Hold, error)
+	ReplayWebhookDeliveryTx(ctx context.Context, id int64) (WebhookDelivery, error)
+	ReverseTransferTx(ctx context.Context, arg ReverseTransferTxParams) (TransferTxResult, error)
+	RunScheduledTransferTx(ctx context.Context) (RunScheduledTransferTxResult, error)
+	TransferTx(ctx context.Context, arg TransferTxParams) (TransferTxResult, error)
+	UpdateAccountStatusTx(ctx context.Context, arg UpdateAccountStatusTxParams) (Account, error)
+	UpdateScheduledTransferTx(ctx context.Context, arg UpdateScheduledTransferParams) (ScheduledTransfer, error)
+}
+
+// SQLStore provides all functions to execute SQL queries & transactions.
+type SQLStore struct {
 	*Queries
-	db *sql.DB
+	db    *sql.DB
+	retry RetryPolicy
+	fees  *fees.Schedule
+}
+
+// StoreOption configures a SQLStore.
+type StoreOption func(*SQLStore)
+
+// WithRetryPolicy sets the policy used to retry transactions aborted by serialization failures or deadlocks.
+func WithRetryPolicy(policy RetryPolicy) StoreOption {
+	return func(store *SQLStore) {
+		store.retry = policy
+	}
+}
+
+// WithFees sets the schedule of the fees charged on transfers. Without it, transfers are free.
+func WithFees(schedule *fees.Schedule) StoreOption {
+	return func(store *SQLStore) {
+		store.fees = schedule
+	}
 }
 
 // NewStore create a new Store.
-func NewStore(db *sql.DB) *Store {
-	return &Store{
+func NewStore(db *sql.DB, opts ...StoreOption) Store {
+	store := &SQLStore{
 		db:      db,
 		Queries: New(db),
+		retry:   DefaultRetryPolicy,
+	}
+
+	for _, opt := range opts {
+		opt(store)
 	}
+
+	return store
 }
 
 // execTx executes a function within a database transaction.
-func (store *Store) execTx(ctx context.Context, fn func(*Queries) error) error {
-	tx, err := store.db.BeginTx(ctx, nil)
+// The transaction is run again, with a jittered exponential backoff, when it is aborted by a serialization failure
+// or a deadlock, so fn must not have side effects outside of the transaction.
+// It returns the number of retries performed.
+func (store *SQLStore) execTx(ctx context.Context, opts *sql.TxOptions, fn func(*Queries) error) (int, error) {
+	for retries := 0; ; retries++ {
+		err := store.runTx(ctx, opts, fn)
+		if err == nil || !isRetryable(err) || retries >= store.retry.MaxRetries {
+			return retries, err
+		}
+
+		if err := sleep(ctx, store.retry.backoff(retries)); err != nil {
+			return retries, err
+		}
+	}
+}
+
+// runTx executes a function within a single database transaction attempt.
+func (store *SQLStore) runTx(ctx context.Context, opts *sql.TxOptions, fn func(*Queries) error) error {
+	call, _ := AuditCallFromContext(ctx)
+	call.beginTx()
+
+	tx, err := store.db.BeginTx(ctx, opts)
 	if err != nil {
 		return err
 	}
@@ -31,7 +107,7 @@ func (store *Store) execTx(ctx context.Context, fn func(*Queries) error) error {
 
 	if err := fn(q); err != nil {
 		if rbErr := tx.Rollback(); rbErr != nil {
-			return fmt.Errorf("tx error: %v, rb error:%v", err, rbErr)
+			return fmt.Errorf("tx error: %w, rb error: %v", err, rbErr)
 		}
 		return err
 	}
@@ -40,6 +116,8 @@ func (store *Store) execTx(ctx context.Context, fn func(*Queries) error) error {
 		return err
 	}
 
+	call.commitTx()
+
 	return nil
 }
 
@@ -48,6 +126,9 @@ type TransferTxParams struct {
 	FromAccountID sql.NullInt64 `json:"from_account_id"`
 	ToAccountID   sql.NullInt64 `json:"to_account_id"`
 	Amount        int64         `json:"amount"`
+
+	// Idempotency, when set, stores the transfer result under the client's idempotency key.
+	Idempotency *IdempotencyParams `json:"-"`
 }
 
 // TransferTxResult is the result of the transfer transaction.
@@ -57,59 +138,187 @@ type TransferTxResult struct {
 	ToAccount   Account  `json:"to_account"`
 	FromEntry   Entry    `json:"from_entry"`
 	ToEntry     Entry    `json:"to_entry"`
+	// Fee is set when a fee was charged on the transfer.
+	Fee *TransferFee `json:"fee,omitempty"`
+
+	// Retries is the number of times the transaction was retried after a serialization failure or a deadlock.
+	Retries int `json:"-"`
 }
 
 // TransferTx performs a money transfer from one account to the other.
 // It creates a transfer record, adds account entries, and updates accounts' balance within a single database transaction.
-func (store *Store) TransferTx(ctx context.Context, arg TransferTxParams) (TransferTxResult, error) {
+// The fee charged on the transfer, if any, is posted in the same transaction, see chargeFee.
+// It fails with ErrInsufficientFunds if the source account available balance is lower than the amount plus the fee,
+// and with ErrAccountFrozen or ErrAccountClosed if either account is frozen or closed.
+func (store *SQLStore) TransferTx(ctx context.Context, arg TransferTxParams) (TransferTxResult, error) {
 	var result TransferTxResult
 
-	if err := store.execTx(ctx, func(q *Queries) error {
+	retries, err := store.execTx(ctx, nil, func(q *Queries) error {
 		var err error
 
-		result.Transfer, err = q.CreateTransfer(ctx, CreateTransferParams{
-			FromAccountID: arg.FromAccountID,
-			ToAccountID:   arg.ToAccountID,
-			Amount:        arg.Amount,
-		})
+		result, err = store.transferTx(ctx, q, arg)
 		if err != nil {
 			return err
 		}
 
-		result.FromEntry, err = q.CreateEntry(ctx, CreateEntryParams{
-			AccountID: arg.FromAccountID,
-			Amount:    -arg.Amount,
-		})
-		if err != nil {
+		if err := saveIdempotencyKey(ctx, q, arg.Idempotency, result); err != nil {
 			return err
 		}
 
-		result.ToEntry, err = q.CreateEntry(ctx, CreateEntryParams{
-			AccountID: arg.ToAccountID,
-			Amount:    arg.Amount,
-		})
-		if err != nil {
-			return err
-		}
+		return audit(ctx, q, AuditChange{ResourceType: "transfers", ResourceID: AuditID(result.Transfer.ID), After: result})
+	})
+	if err != nil {
+		return TransferTxResult{}, err
+	}
 
-		if arg.FromAccountID.Int64 < arg.ToAccountID.Int64 {
-			result.FromAccount, result.ToAccount, err = addMoney(ctx, q, arg.FromAccountID.Int64, -arg.Amount, arg.ToAccountID.Int64, arg.Amount)
-		} else {
-			result.ToAccount, result.FromAccount, err = addMoney(ctx, q, arg.ToAccountID.Int64, arg.Amount, arg.FromAccountID.Int64, -arg.Amount)
-		}
-		if err != nil {
-			return err
-		}
+	result.Retries = retries
 
-		return nil
+	return result, nil
+}
+
+// transferTx performs the transfer and charges its fee using the given transaction queries.
+func (store *SQLStore) transferTx(ctx context.Context, q *Queries, arg TransferTxParams) (TransferTxResult, error) {
+	accounts, err := store.lockChargedTransferAccounts(ctx, q, arg.FromAccountID.Int64, arg.ToAccountID.Int64, arg.Amount)
+	if err != nil {
+		return TransferTxResult{}, err
+	}
+
+	result, err := transfer(ctx, q, transferParams{
+		FromAccount: accounts.From,
+		ToAccount:   accounts.To,
+		Amount:      arg.Amount,
+	})
+	if err != nil {
+		return TransferTxResult{}, err
+	}
 
-	}); err != nil {
+	if err := chargeFee(ctx, q, &result, accounts); err != nil {
 		return TransferTxResult{}, err
 	}
 
 	return result, nil
 }
 
+// transferParams contains the locked accounts and the amounts moved by a single transfer.
+type transferParams struct {
+	FromAccount Account
+	ToAccount   Account
+	// Amount is debited from FromAccount, in its currency.
+	Amount int64
+	// ToAmount, when set, is credited to ToAccount instead of Amount, in its currency.
+	ToAmount     sql.NullInt64
+	ExchangeRate sql.NullString
+	// ReversedTransferID links a compensating transfer to the transfer it reverses.
+	ReversedTransferID sql.NullInt64
+	// FeeOfTransferID links a fee transfer to the transfer it is charged on.
+	FeeOfTransferID sql.NullInt64
+}
+
+// transfer moves the money and records the transfer, its entries and its outbox event using the given transaction queries.
+// Both accounts must have been locked with lockTransferAccounts.
+func transfer(ctx context.Context, q *Queries, arg transferParams) (TransferTxResult, error) {
+	var (
+		result TransferTxResult
+		err    error
+	)
+
+	fromAccountID, toAccountID := arg.FromAccount.ID, arg.ToAccount.ID
+
+	for _, account := range []Account{arg.FromAccount, arg.ToAccount} {
+		if err := checkAccountActive(account); err != nil {
+			return result, err
+		}
+	}
+
+	available, err := availableBalance(ctx, q, arg.FromAccount)
+	if err != nil {
+		return result, err
+	}
+
+	if available < arg.Amount {
+		return result, fmt.Errorf("%w: account [%d] available balance %d is less than %d", ErrInsufficientFunds, fromAccountID, available, arg.Amount)
+	}
+
+	credit := arg.Amount
+	if arg.ToAmount.Valid {
+		credit = arg.ToAmount.Int64
+	}
+
+	result.Transfer, err = q.CreateTransfer(ctx, CreateTransferParams{
+		FromAccountID:      sql.NullInt64{Int64: fromAccountID, Valid: true},
+		ToAccountID:        sql.NullInt64{Int64: toAccountID, Valid: true},
+		Amount:             arg.Amount,
+		ReversedTransferID: arg.ReversedTransferID,
+		ToAmount:           arg.ToAmount,
+		ExchangeRate:       arg.ExchangeRate,
+		FeeOfTransferID:    arg.FeeOfTransferID,
+	})
+	if err != nil {
+		return result, err
+	}
+
+	result.FromEntry, err = q.CreateEntry(ctx, CreateEntryParams{
+		AccountID:  sql.NullInt64{Int64: fromAccountID, Valid: true},
+		Amount:     -arg.Amount,
+		TransferID: sql.NullInt64{Int64: result.Transfer.ID, Valid: true},
+	})
+	if err != nil {
+		return result, err
+	}
+
+	result.ToEntry, err = q.CreateEntry(ctx, CreateEntryParams{
+		AccountID:  sql.NullInt64{Int64: toAccountID, Valid: true},
+		Amount:     credit,
+		TransferID: sql.NullInt64{Int64: result.Transfer.ID, Valid: true},
+	})
+	if err != nil {
+		return result, err
+	}
+
+	if fromAccountID < toAccountID {
+		result.FromAccount, result.ToAccount, err = addMoney(ctx, q, fromAccountID, -arg.Amount, toAccountID, credit)
+	} else {
+		result.ToAccount, result.FromAccount, err = addMoney(ctx, q, toAccountID, credit, fromAccountID, -arg.Amount)
+	}
+	if err != nil {
+		return result, err
+	}
+
+	return result, recordTransfer(ctx, q, result)
+}
+
+// lockTransferAccounts selects both accounts of a transfer for update.
+func lockTransferAccounts(ctx context.Context, q *Queries, fromAccountID, toAccountID int64) (fromAccount, toAccount Account, err error) {
+	accounts, err := lockAccounts(ctx, q, fromAccountID, toAccountID)
+	if err != nil {
+		return Account{}, Account{}, err
+	}
+
+	return accounts[fromAccountID], accounts[toAccountID], nil
+}
+
+// lockAccounts selects the accounts for update in ascending ID order,
+// so concurrent transactions always acquire the row locks in the same order.
+func lockAccounts(ctx context.Context, q *Queries, accountIDs ...int64) (map[int64]Account, error) {
+	ids := append([]int64(nil), accountIDs...)
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	accounts := make(map[int64]Account, len(ids))
+	for _, id := range ids {
+		if _, ok := accounts[id]; ok {
+			continue
+		}
+
+		account, err := q.GetAccountForUpdate(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		accounts[id] = account
+	}
+
+	return accounts, nil
+}
+
 func addMoney(ctx context.Context, q *Queries, accountID1, amount1, accountID2, amount2 int64) (account1, account2 Account, err error) {
 	account1, err = q.AddAccountBalance(ctx, AddAccountBalanceParams{
 		ID:     accountID1,
@@ -2,10 +2,16 @@ package db
 
 import (
 	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 
+	"tech-school/fees"
+	"tech-school/fx"
 	"tech-school/util"
 )
 
@@ -14,13 +20,13 @@ func TestTransferTx(t *testing.T) {
 
 	store := NewStore(testDB)
 
-	account1 := createRandomAccount(t)
-	account2 := createRandomAccount(t)
-
 	// Run n current transfer transactions.
 	n := 5
 	amount := int64(10)
 
+	account1 := createFundedAccount(t, int64(n)*amount)
+	account2 := createRandomAccount(t)
+
 	errs := make(chan error)
 	results := make(chan TransferTxResult)
 
@@ -117,13 +123,13 @@ func TestTransferTxDeadlock(t *testing.T) {
 
 	store := NewStore(testDB)
 
-	account1 := createRandomAccount(t)
-	account2 := createRandomAccount(t)
-
 	// Run n current transfer transactions.
 	n := 10
 	amount := int64(10)
 
+	account1 := createFundedAccount(t, int64(n)*amount)
+	account2 := createFundedAccount(t, int64(n)*amount)
+
 	errs := make(chan error)
 
 	for i := 0; i < n; i++ {
@@ -162,3 +168,813 @@ func TestTransferTxDeadlock(t *testing.T) {
 	require.Equal(t, account1.Balance, updatedAccount1.Balance)
 	require.Equal(t, account2.Balance, updatedAccount2.Balance)
 }
+
+func TestTransferTxInsufficientFunds(t *testing.T) {
+	ctx := context.Background()
+
+	store := NewStore(testDB)
+
+	// Run more concurrent transfers than the source balance can cover.
+	n := 20
+	amount := int64(10)
+	covered := 10
+
+	account1 := createFundedAccount(t, int64(covered)*amount)
+	account2 := createRandomAccount(t)
+
+	errs := make(chan error)
+
+	for i := 0; i < n; i++ {
+		go func() {
+			_, err := store.TransferTx(ctx, TransferTxParams{
+				FromAccountID: util.SQLNullInt64[int64](account1.ID),
+				ToAccountID:   util.SQLNullInt64[int64](account2.ID),
+				Amount:        amount,
+			})
+
+			errs <- err
+		}()
+	}
+
+	// Checks that exactly the covered transfers succeeded
+	succeeded := 0
+	for i := 0; i < n; i++ {
+		err := <-errs
+		if err != nil {
+			require.True(t, errors.Is(err, ErrInsufficientFunds))
+			continue
+		}
+		succeeded++
+	}
+	require.Equal(t, covered, succeeded)
+
+	// Checks the source account was never overdrawn
+	updatedAccount1, err := testQueries.GetAccount(ctx, account1.ID)
+	require.NoError(t, err)
+
+	updatedAccount2, err := testQueries.GetAccount(ctx, account2.ID)
+	require.NoError(t, err)
+
+	require.Zero(t, updatedAccount1.Balance)
+	require.Equal(t, account2.Balance+int64(covered)*amount, updatedAccount2.Balance)
+}
+
+func createFundedAccount(t *testing.T, balance int64) Account {
+	account := createRandomAccount(t)
+
+	account, err := testQueries.UpdateAccount(context.Background(), UpdateAccountParams{
+		ID:      account.ID,
+		Balance: balance,
+	})
+	require.NoError(t, err)
+	require.Equal(t, balance, account.Balance)
+
+	return account
+}
+
+func TestTransferTxIdempotencyKey(t *testing.T) {
+	ctx := context.Background()
+
+	store := NewStore(testDB)
+
+	amount := int64(10)
+
+	account1 := createFundedAccount(t, 10*amount)
+	account2 := createRandomAccount(t)
+
+	arg := TransferTxParams{
+		FromAccountID: util.SQLNullInt64[int64](account1.ID),
+		ToAccountID:   util.SQLNullInt64[int64](account2.ID),
+		Amount:        amount,
+		Idempotency: &IdempotencyParams{
+			Key:            util.RandomString(16),
+			Username:       account1.Owner,
+			RequestPath:    "/transfers",
+			RequestHash:    util.RandomString(64),
+			ResponseStatus: 200,
+		},
+	}
+
+	result, err := store.TransferTx(ctx, arg)
+	require.NoError(t, err)
+
+	// Repeating the transfer with the same key must not move money again
+	_, err = store.TransferTx(ctx, arg)
+	require.ErrorIs(t, err, ErrDuplicateIdempotencyKey)
+
+	updatedAccount1, err := testQueries.GetAccount(ctx, account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, account1.Balance-amount, updatedAccount1.Balance)
+
+	stored, err := testQueries.GetIdempotencyKey(ctx, GetIdempotencyKeyParams{
+		Username: arg.Idempotency.Username,
+		Key:      arg.Idempotency.Key,
+	})
+	require.NoError(t, err)
+
+	var storedResult TransferTxResult
+	err = json.Unmarshal(stored.ResponseBody, &storedResult)
+	require.NoError(t, err)
+	require.Equal(t, result.Transfer.ID, storedResult.Transfer.ID)
+}
+
+func TestAccountStatementTx(t *testing.T) {
+	ctx := context.Background()
+
+	store := NewStore(testDB)
+
+	account1 := createFundedAccount(t, 1000)
+	account2 := createRandomAccount(t)
+
+	amount := int64(10)
+	n := 3
+
+	var entries []Entry
+	for i := 0; i < n; i++ {
+		result, err := store.TransferTx(ctx, TransferTxParams{
+			FromAccountID: util.SQLNullInt64(account1.ID),
+			ToAccountID:   util.SQLNullInt64(account2.ID),
+			Amount:        amount,
+		})
+		require.NoError(t, err)
+		entries = append(entries, result.FromEntry)
+	}
+
+	result, err := store.AccountStatementTx(ctx, AccountStatementTxParams{
+		AccountID: account1.ID,
+		PeriodEnd: time.Now().Add(time.Minute),
+		PageSize:  10,
+	})
+	require.NoError(t, err)
+	require.Equal(t, account1.Balance, result.OpeningBalance)
+	require.Equal(t, account1.Balance-int64(n)*amount, result.ClosingBalance)
+	require.Len(t, result.Entries, n)
+
+	for i, row := range result.Entries {
+		require.Equal(t, entries[i].ID, row.ID)
+		require.Equal(t, -amount, row.Amount)
+		require.Equal(t, account1.Balance-int64(i+1)*amount, row.RunningBalance)
+	}
+
+	// A period starting at the second entry opens with the balance left after the first one.
+	result, err = store.AccountStatementTx(ctx, AccountStatementTxParams{
+		AccountID:   account1.ID,
+		PeriodStart: entries[1].CreatedAt,
+		PeriodEnd:   entries[2].CreatedAt,
+		PageSize:    10,
+	})
+	require.NoError(t, err)
+	require.Equal(t, account1.Balance-amount, result.OpeningBalance)
+	require.Equal(t, account1.Balance-2*amount, result.ClosingBalance)
+	require.Len(t, result.Entries, 1)
+	require.Equal(t, entries[1].ID, result.Entries[0].ID)
+	require.Equal(t, result.ClosingBalance, result.Entries[0].RunningBalance)
+}
+
+func TestReverseTransferTx(t *testing.T) {
+	ctx := context.Background()
+
+	store := NewStore(testDB)
+
+	account1 := createFundedAccount(t, 100)
+	account2 := createFundedAccount(t, 0)
+
+	original, err := store.TransferTx(ctx, TransferTxParams{
+		FromAccountID: util.SQLNullInt64(account1.ID),
+		ToAccountID:   util.SQLNullInt64(account2.ID),
+		Amount:        30,
+	})
+	require.NoError(t, err)
+
+	result, err := store.ReverseTransferTx(ctx, ReverseTransferTxParams{TransferID: original.Transfer.ID})
+	require.NoError(t, err)
+
+	reversal := result.Transfer
+	require.Equal(t, util.SQLNullInt64(original.Transfer.ID), reversal.ReversedTransferID)
+	require.Equal(t, original.Transfer.ToAccountID, reversal.FromAccountID)
+	require.Equal(t, original.Transfer.FromAccountID, reversal.ToAccountID)
+	require.Equal(t, original.Transfer.Amount, reversal.Amount)
+
+	require.Equal(t, account2.ID, result.FromAccount.ID)
+	require.Equal(t, account2.Balance, result.FromAccount.Balance)
+	require.Equal(t, account1.ID, result.ToAccount.ID)
+	require.Equal(t, account1.Balance, result.ToAccount.Balance)
+
+	require.Equal(t, -reversal.Amount, result.FromEntry.Amount)
+	require.Equal(t, reversal.Amount, result.ToEntry.Amount)
+
+	// The original transfer and its entries are left untouched.
+	transfer, err := store.GetTransfer(ctx, original.Transfer.ID)
+	require.NoError(t, err)
+	require.Equal(t, original.Transfer, transfer)
+
+	_, err = store.ReverseTransferTx(ctx, ReverseTransferTxParams{TransferID: original.Transfer.ID})
+	require.ErrorIs(t, err, ErrTransferAlreadyReversed)
+
+	_, err = store.ReverseTransferTx(ctx, ReverseTransferTxParams{TransferID: reversal.ID})
+	require.ErrorIs(t, err, ErrReversalNotReversible)
+
+	_, err = store.ReverseTransferTx(ctx, ReverseTransferTxParams{TransferID: -1})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestReverseTransferTxInsufficientFunds(t *testing.T) {
+	ctx := context.Background()
+
+	store := NewStore(testDB)
+
+	account1 := createFundedAccount(t, 100)
+	account2 := createFundedAccount(t, 0)
+	account3 := createFundedAccount(t, 0)
+
+	original, err := store.TransferTx(ctx, TransferTxParams{
+		FromAccountID: util.SQLNullInt64(account1.ID),
+		ToAccountID:   util.SQLNullInt64(account2.ID),
+		Amount:        50,
+	})
+	require.NoError(t, err)
+
+	_, err = store.TransferTx(ctx, TransferTxParams{
+		FromAccountID: util.SQLNullInt64(account2.ID),
+		ToAccountID:   util.SQLNullInt64(account3.ID),
+		Amount:        40,
+	})
+	require.NoError(t, err)
+
+	_, err = store.ReverseTransferTx(ctx, ReverseTransferTxParams{TransferID: original.Transfer.ID})
+	require.ErrorIs(t, err, ErrInsufficientFunds)
+
+	_, err = store.GetTransferReversal(ctx, util.SQLNullInt64(original.Transfer.ID))
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestReverseTransferTxConcurrent(t *testing.T) {
+	ctx := context.Background()
+
+	store := NewStore(testDB)
+
+	account1 := createFundedAccount(t, 100)
+	account2 := createFundedAccount(t, 0)
+
+	original, err := store.TransferTx(ctx, TransferTxParams{
+		FromAccountID: util.SQLNullInt64(account1.ID),
+		ToAccountID:   util.SQLNullInt64(account2.ID),
+		Amount:        10,
+	})
+	require.NoError(t, err)
+
+	n := 5
+	errs := make(chan error)
+
+	for i := 0; i < n; i++ {
+		go func() {
+			_, err := store.ReverseTransferTx(ctx, ReverseTransferTxParams{TransferID: original.Transfer.ID})
+			errs <- err
+		}()
+	}
+
+	succeeded := 0
+	for i := 0; i < n; i++ {
+		err := <-errs
+		if err == nil {
+			succeeded++
+			continue
+		}
+		require.ErrorIs(t, err, ErrTransferAlreadyReversed)
+	}
+	require.Equal(t, 1, succeeded)
+
+	updatedAccount1, err := store.GetAccount(ctx, account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, account1.Balance, updatedAccount1.Balance)
+}
+
+func createAccountInCurrency(t *testing.T, currency string, balance int64) Account {
+	user := createRandomUser(t)
+
+	account, err := testQueries.CreateAccount(context.Background(), CreateAccountParams{
+		Owner:    user.Username,
+		Balance:  balance,
+		Currency: currency,
+	})
+	require.NoError(t, err)
+
+	return account
+}
+
+func TestCrossCurrencyTransferTx(t *testing.T) {
+	ctx := context.Background()
+
+	store := NewStore(testDB)
+
+	account1 := createAccountInCurrency(t, "EUR", 10000)
+	account2 := createAccountInCurrency(t, "USD", 0)
+
+	rate, err := fx.ParseRate("EUR", "USD", "1.085")
+	require.NoError(t, err)
+
+	// 12.34 EUR * 1.085 = 13.3889 USD, rounded down to 13.38 USD.
+	result, err := store.CrossCurrencyTransferTx(ctx, CrossCurrencyTransferTxParams{
+		TransferTxParams: TransferTxParams{
+			FromAccountID: util.SQLNullInt64(account1.ID),
+			ToAccountID:   util.SQLNullInt64(account2.ID),
+			Amount:        1234,
+		},
+		Rate: rate,
+	})
+	require.NoError(t, err)
+
+	transfer := result.Transfer
+	require.Equal(t, int64(1234), transfer.Amount)
+	require.Equal(t, util.SQLNullInt64(1338), transfer.ToAmount)
+	require.Equal(t, sql.NullString{String: "1.08500000", Valid: true}, transfer.ExchangeRate)
+
+	require.Equal(t, int64(-1234), result.FromEntry.Amount)
+	require.Equal(t, int64(1338), result.ToEntry.Amount)
+	require.Equal(t, account1.Balance-1234, result.FromAccount.Balance)
+	require.Equal(t, account2.Balance+1338, result.ToAccount.Balance)
+
+	// The reversal gives back the recorded amounts without converting them again.
+	reversal, err := store.ReverseTransferTx(ctx, ReverseTransferTxParams{TransferID: transfer.ID})
+	require.NoError(t, err)
+	require.Equal(t, int64(1338), reversal.Transfer.Amount)
+	require.Equal(t, util.SQLNullInt64(1234), reversal.Transfer.ToAmount)
+	require.False(t, reversal.Transfer.ExchangeRate.Valid)
+	require.Equal(t, account1.Balance, reversal.ToAccount.Balance)
+	require.Equal(t, account2.Balance, reversal.FromAccount.Balance)
+
+	// Both sides of a cross-currency transfer are matched by the ledger check.
+	report, err := CheckLedger(ctx, testDB)
+	require.NoError(t, err)
+	for _, e := range report.OrphanedEntries {
+		require.NotEqual(t, result.ToEntry.ID, e.EntryID)
+		require.NotEqual(t, reversal.FromEntry.ID, e.EntryID)
+	}
+	for _, trn := range report.UnbalancedTransfers {
+		require.NotEqual(t, transfer.ID, trn.TransferID)
+		require.NotEqual(t, reversal.Transfer.ID, trn.TransferID)
+	}
+}
+
+func TestCrossCurrencyTransferTxErrors(t *testing.T) {
+	ctx := context.Background()
+
+	store := NewStore(testDB)
+
+	account1 := createAccountInCurrency(t, "USD", 100)
+	account2 := createAccountInCurrency(t, "EUR", 0)
+
+	usdEUR, err := fx.ParseRate("USD", "EUR", "0.9")
+	require.NoError(t, err)
+	eurUSD, err := fx.ParseRate("EUR", "USD", "1.085")
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name   string
+		amount int64
+		rate   fx.Rate
+		err    error
+	}{
+		{name: "RateMismatch", amount: 10, rate: eurUSD, err: ErrExchangeRateMismatch},
+		{name: "AmountTooSmall", amount: 1, rate: usdEUR, err: fx.ErrAmountTooSmall},
+		{name: "InsufficientFunds", amount: 101, rate: usdEUR, err: ErrInsufficientFunds},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := store.CrossCurrencyTransferTx(ctx, CrossCurrencyTransferTxParams{
+				TransferTxParams: TransferTxParams{
+					FromAccountID: util.SQLNullInt64(account1.ID),
+					ToAccountID:   util.SQLNullInt64(account2.ID),
+					Amount:        tc.amount,
+				},
+				Rate: tc.rate,
+			})
+			require.ErrorIs(t, err, tc.err)
+		})
+	}
+
+	updatedAccount1, err := store.GetAccount(ctx, account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, account1.Balance, updatedAccount1.Balance)
+}
+
+func TestTransferTxFee(t *testing.T) {
+	ctx := context.Background()
+
+	account1 := createAccountInCurrency(t, "USD", 10000)
+	account2 := createAccountInCurrency(t, "USD", 0)
+	revenueAccount := createAccountInCurrency(t, "USD", 0)
+
+	// 1% of 50.00 USD plus 0.25 USD.
+	schedule, err := fees.NewSchedule(fees.Rule{
+		Currency:         "USD",
+		RevenueAccountID: revenueAccount.ID,
+		Flat:             25,
+		BasisPoints:      100,
+	})
+	require.NoError(t, err)
+
+	store := NewStore(testDB, WithFees(schedule))
+
+	result, err := store.TransferTx(ctx, TransferTxParams{
+		FromAccountID: util.SQLNullInt64(account1.ID),
+		ToAccountID:   util.SQLNullInt64(account2.ID),
+		Amount:        5000,
+	})
+	require.NoError(t, err)
+
+	require.NotNil(t, result.Fee)
+	require.Equal(t, fees.Fee{Flat: 25, Percentage: 50, Total: 75}, result.Fee.Fee)
+
+	feeTransfer := result.Fee.Transfer
+	require.Equal(t, util.SQLNullInt64(result.Transfer.ID), feeTransfer.FeeOfTransferID)
+	require.Equal(t, util.SQLNullInt64(account1.ID), feeTransfer.FromAccountID)
+	require.Equal(t, util.SQLNullInt64(revenueAccount.ID), feeTransfer.ToAccountID)
+	require.Equal(t, int64(75), feeTransfer.Amount)
+	require.Equal(t, int64(-75), result.Fee.FromEntry.Amount)
+	require.Equal(t, int64(75), result.Fee.ToEntry.Amount)
+
+	require.Equal(t, account1.Balance-5000-75, result.FromAccount.Balance)
+	require.Equal(t, account2.Balance+5000, result.ToAccount.Balance)
+
+	updatedRevenueAccount, err := store.GetAccount(ctx, revenueAccount.ID)
+	require.NoError(t, err)
+	require.Equal(t, revenueAccount.Balance+75, updatedRevenueAccount.Balance)
+
+	// The amount alone is covered, but not the amount plus the fee.
+	_, err = store.TransferTx(ctx, TransferTxParams{
+		FromAccountID: util.SQLNullInt64(account1.ID),
+		ToAccountID:   util.SQLNullInt64(account2.ID),
+		Amount:        result.FromAccount.Balance,
+	})
+	require.ErrorIs(t, err, ErrInsufficientFunds)
+
+	_, err = store.ReverseTransferTx(ctx, ReverseTransferTxParams{TransferID: feeTransfer.ID})
+	require.ErrorIs(t, err, ErrFeeNotReversible)
+
+	// Transfers in currencies without a fee rule are free.
+	account3 := createAccountInCurrency(t, "EUR", 100)
+	account4 := createAccountInCurrency(t, "EUR", 0)
+
+	result, err = store.TransferTx(ctx, TransferTxParams{
+		FromAccountID: util.SQLNullInt64(account3.ID),
+		ToAccountID:   util.SQLNullInt64(account4.ID),
+		Amount:        100,
+	})
+	require.NoError(t, err)
+	require.Nil(t, result.Fee)
+}
+
+func placeHold(t *testing.T, store Store, from, to Account, amount int64, expiresAt time.Time) Hold {
+	hold, err := store.PlaceHoldTx(context.Background(), PlaceHoldTxParams{
+		CreateHoldParams: CreateHoldParams{
+			AccountID:   from.ID,
+			ToAccountID: to.ID,
+			Amount:      amount,
+			ExpiresAt:   expiresAt,
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, HoldStatusActive, hold.Status)
+	require.False(t, hold.TransferID.Valid)
+	require.False(t, hold.ResolvedAt.Valid)
+
+	return hold
+}
+
+func TestPlaceHoldTx(t *testing.T) {
+	ctx := context.Background()
+
+	store := NewStore(testDB)
+
+	account1 := createFundedAccount(t, 100)
+	account2 := createFundedAccount(t, 0)
+
+	placeHold(t, store, account1, account2, 60, time.Now().Add(time.Hour))
+
+	held, err := store.GetAccountHeldAmount(ctx, account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(60), held)
+
+	// The balance is left untouched, but only the rest is available.
+	account, err := store.GetAccount(ctx, account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, account1.Balance, account.Balance)
+
+	_, err = store.PlaceHoldTx(ctx, PlaceHoldTxParams{
+		CreateHoldParams: CreateHoldParams{
+			AccountID:   account1.ID,
+			ToAccountID: account2.ID,
+			Amount:      50,
+			ExpiresAt:   time.Now().Add(time.Hour),
+		},
+	})
+	require.ErrorIs(t, err, ErrInsufficientFunds)
+
+	_, err = store.TransferTx(ctx, TransferTxParams{
+		FromAccountID: util.SQLNullInt64(account1.ID),
+		ToAccountID:   util.SQLNullInt64(account2.ID),
+		Amount:        50,
+	})
+	require.ErrorIs(t, err, ErrInsufficientFunds)
+
+	_, err = store.TransferTx(ctx, TransferTxParams{
+		FromAccountID: util.SQLNullInt64(account1.ID),
+		ToAccountID:   util.SQLNullInt64(account2.ID),
+		Amount:        40,
+	})
+	require.NoError(t, err)
+}
+
+func TestCaptureHoldTx(t *testing.T) {
+	ctx := context.Background()
+
+	store := NewStore(testDB)
+
+	account1 := createFundedAccount(t, 100)
+	account2 := createFundedAccount(t, 0)
+
+	hold := placeHold(t, store, account1, account2, 60, time.Now().Add(time.Hour))
+
+	_, err := store.CaptureHoldTx(ctx, CaptureHoldTxParams{HoldID: hold.ID, Amount: 61})
+	require.ErrorIs(t, err, ErrCaptureExceedsHold)
+
+	// A partial capture settles the hold and releases the rest.
+	result, err := store.CaptureHoldTx(ctx, CaptureHoldTxParams{HoldID: hold.ID, Amount: 45})
+	require.NoError(t, err)
+
+	require.Equal(t, HoldStatusCaptured, result.Hold.Status)
+	require.Equal(t, util.SQLNullInt64(result.Transfer.ID), result.Hold.TransferID)
+	require.True(t, result.Hold.ResolvedAt.Valid)
+
+	require.Equal(t, int64(45), result.Transfer.Amount)
+	require.Equal(t, util.SQLNullInt64(account1.ID), result.Transfer.FromAccountID)
+	require.Equal(t, util.SQLNullInt64(account2.ID), result.Transfer.ToAccountID)
+	require.Equal(t, account1.Balance-45, result.FromAccount.Balance)
+	require.Equal(t, account2.Balance+45, result.ToAccount.Balance)
+
+	held, err := store.GetAccountHeldAmount(ctx, account1.ID)
+	require.NoError(t, err)
+	require.Zero(t, held)
+
+	_, err = store.CaptureHoldTx(ctx, CaptureHoldTxParams{HoldID: hold.ID})
+	require.ErrorIs(t, err, ErrHoldNotActive)
+
+	_, err = store.ReleaseHoldTx(ctx, hold.ID)
+	require.ErrorIs(t, err, ErrHoldNotActive)
+
+	_, err = store.CaptureHoldTx(ctx, CaptureHoldTxParams{HoldID: -1})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestReleaseHoldTx(t *testing.T) {
+	ctx := context.Background()
+
+	store := NewStore(testDB)
+
+	account1 := createFundedAccount(t, 100)
+	account2 := createFundedAccount(t, 0)
+
+	hold := placeHold(t, store, account1, account2, 100, time.Now().Add(time.Hour))
+
+	released, err := store.ReleaseHoldTx(ctx, hold.ID)
+	require.NoError(t, err)
+	require.Equal(t, HoldStatusReleased, released.Status)
+	require.False(t, released.TransferID.Valid)
+	require.True(t, released.ResolvedAt.Valid)
+
+	held, err := store.GetAccountHeldAmount(ctx, account1.ID)
+	require.NoError(t, err)
+	require.Zero(t, held)
+
+	_, err = store.CaptureHoldTx(ctx, CaptureHoldTxParams{HoldID: hold.ID})
+	require.ErrorIs(t, err, ErrHoldNotActive)
+}
+
+func TestExpiredHold(t *testing.T) {
+	ctx := context.Background()
+
+	store := NewStore(testDB)
+
+	account1 := createFundedAccount(t, 100)
+	account2 := createFundedAccount(t, 0)
+
+	hold := placeHold(t, store, account1, account2, 100, time.Now().Add(-time.Minute))
+
+	// An expired hold no longer reserves funds, even before its status is updated.
+	held, err := store.GetAccountHeldAmount(ctx, account1.ID)
+	require.NoError(t, err)
+	require.Zero(t, held)
+
+	_, err = store.CaptureHoldTx(ctx, CaptureHoldTxParams{HoldID: hold.ID})
+	require.ErrorIs(t, err, ErrHoldExpired)
+
+	expired, err := store.ExpireHolds(ctx)
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, expired, int64(1))
+
+	hold, err = store.GetHold(ctx, hold.ID)
+	require.NoError(t, err)
+	require.Equal(t, HoldStatusExpired, hold.Status)
+	require.True(t, hold.ResolvedAt.Valid)
+
+	_, err = store.ReleaseHoldTx(ctx, hold.ID)
+	require.ErrorIs(t, err, ErrHoldNotActive)
+}
+
+func TestUpdateAccountStatusTx(t *testing.T) {
+	ctx := context.Background()
+
+	store := NewStore(testDB)
+
+	account1 := createFundedAccount(t, 100)
+	account2 := createFundedAccount(t, 0)
+
+	transferArg := TransferTxParams{
+		FromAccountID: util.SQLNullInt64(account1.ID),
+		ToAccountID:   util.SQLNullInt64(account2.ID),
+		Amount:        10,
+	}
+
+	account, err := store.UpdateAccountStatusTx(ctx, UpdateAccountStatusTxParams{ID: account2.ID, Status: AccountStatusFrozen})
+	require.NoError(t, err)
+	require.Equal(t, AccountStatusFrozen, account.Status)
+
+	// A frozen account can neither receive nor send money.
+	_, err = store.TransferTx(ctx, transferArg)
+	require.ErrorIs(t, err, ErrAccountFrozen)
+
+	account, err = store.UpdateAccountStatusTx(ctx, UpdateAccountStatusTxParams{ID: account2.ID, Status: AccountStatusActive})
+	require.NoError(t, err)
+	require.Equal(t, AccountStatusActive, account.Status)
+
+	_, err = store.TransferTx(ctx, transferArg)
+	require.NoError(t, err)
+
+	_, err = store.UpdateAccountStatusTx(ctx, UpdateAccountStatusTxParams{ID: account2.ID, Status: AccountStatusClosed})
+	require.ErrorIs(t, err, ErrAccountBalanceNotZero)
+
+	_, err = store.TransferTx(ctx, TransferTxParams{
+		FromAccountID: util.SQLNullInt64(account2.ID),
+		ToAccountID:   util.SQLNullInt64(account1.ID),
+		Amount:        10,
+	})
+	require.NoError(t, err)
+
+	account, err = store.UpdateAccountStatusTx(ctx, UpdateAccountStatusTxParams{ID: account2.ID, Status: AccountStatusClosed})
+	require.NoError(t, err)
+	require.Equal(t, AccountStatusClosed, account.Status)
+	require.True(t, account.ClosedAt.Valid)
+
+	_, err = store.TransferTx(ctx, transferArg)
+	require.ErrorIs(t, err, ErrAccountClosed)
+
+	// Closing is final.
+	_, err = store.UpdateAccountStatusTx(ctx, UpdateAccountStatusTxParams{ID: account2.ID, Status: AccountStatusActive})
+	require.ErrorIs(t, err, ErrAccountClosed)
+
+	_, err = store.UpdateAccountStatusTx(ctx, UpdateAccountStatusTxParams{ID: -1, Status: AccountStatusFrozen})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func scheduleTransfer(t *testing.T, store Store, from, to Account, amount int64, schedule string, nextRunAt time.Time) ScheduledTransfer {
+	scheduled, err := store.CreateScheduledTransferTx(context.Background(), CreateScheduledTransferTxParams{
+		CreateScheduledTransferParams: CreateScheduledTransferParams{
+			Owner:         from.Owner,
+			FromAccountID: from.ID,
+			ToAccountID:   to.ID,
+			Amount:        amount,
+			Currency:      from.Currency,
+			Schedule:      sql.NullString{String: schedule, Valid: schedule != ""},
+			NextRunAt:     nextRunAt,
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, ScheduledTransferStatusActive, scheduled.Status)
+
+	return scheduled
+}
+
+// runScheduledTransfer runs the due scheduled transfers until the given one has run.
+func runScheduledTransfer(t *testing.T, store Store, id int64) RunScheduledTransferTxResult {
+	for {
+		result, err := store.RunScheduledTransferTx(context.Background())
+		require.NoError(t, err)
+
+		if result.ScheduledTransfer.ID == id {
+			return result
+		}
+	}
+}
+
+func TestRunScheduledTransferTx(t *testing.T) {
+	ctx := context.Background()
+
+	store := NewStore(testDB)
+
+	account1 := createFundedAccount(t, 100)
+	account2 := createFundedAccount(t, 0)
+
+	dueAt := time.Now().Add(-time.Minute).UTC().Truncate(time.Second)
+	scheduled := scheduleTransfer(t, store, account1, account2, 30, "@every 1h", dueAt)
+
+	result := runScheduledTransfer(t, store, scheduled.ID)
+
+	require.Equal(t, ScheduledTransferStatusActive, result.ScheduledTransfer.Status)
+	require.WithinDuration(t, dueAt.Add(time.Hour), result.ScheduledTransfer.NextRunAt, time.Second)
+
+	require.Equal(t, scheduled.ID, result.Run.ScheduledTransferID)
+	require.WithinDuration(t, dueAt, result.Run.ScheduledAt, time.Second)
+	require.False(t, result.Run.Error.Valid)
+
+	require.NotNil(t, result.Transfer)
+	require.Equal(t, util.SQLNullInt64(result.Transfer.Transfer.ID), result.Run.TransferID)
+	require.Equal(t, int64(30), result.Transfer.Transfer.Amount)
+	require.Equal(t, account1.Balance-30, result.Transfer.FromAccount.Balance)
+	require.Equal(t, account2.Balance+30, result.Transfer.ToAccount.Balance)
+
+	// The next occurrence isn't due yet.
+	scheduled, err := store.GetScheduledTransfer(ctx, scheduled.ID)
+	require.NoError(t, err)
+	require.True(t, scheduled.NextRunAt.After(time.Now()))
+
+	runs, err := store.ListScheduledTransferRuns(ctx, ListScheduledTransferRunsParams{
+		ScheduledTransferID: scheduled.ID,
+		Limit:               5,
+	})
+	require.NoError(t, err)
+	require.Equal(t, []ScheduledTransferRun{result.Run}, runs)
+}
+
+func TestRunScheduledTransferTxFailure(t *testing.T) {
+	ctx := context.Background()
+
+	store := NewStore(testDB)
+
+	account1 := createFundedAccount(t, 10)
+	account2 := createFundedAccount(t, 0)
+
+	scheduled := scheduleTransfer(t, store, account1, account2, 30, "", time.Now().Add(-time.Minute))
+
+	result := runScheduledTransfer(t, store, scheduled.ID)
+
+	// A failed run is recorded, and a one-off transfer is completed after its single run.
+	require.Equal(t, ScheduledTransferStatusCompleted, result.ScheduledTransfer.Status)
+	require.Nil(t, result.Transfer)
+	require.False(t, result.Run.TransferID.Valid)
+	require.True(t, result.Run.Error.Valid)
+	require.Contains(t, result.Run.Error.String, ErrInsufficientFunds.Error())
+
+	account, err := testQueries.GetAccount(ctx, account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, account1.Balance, account.Balance)
+}
+
+func TestRunScheduledTransferTxConcurrent(t *testing.T) {
+	ctx := context.Background()
+
+	store := NewStore(testDB)
+
+	account1 := createFundedAccount(t, 100)
+	account2 := createFundedAccount(t, 0)
+
+	scheduled := scheduleTransfer(t, store, account1, account2, 10, "@every 24h", time.Now().Add(-time.Minute))
+
+	// Concurrent schedulers, as in several replicas, never run the same occurrence twice.
+	n := 5
+	type outcome struct {
+		result RunScheduledTransferTxResult
+		err    error
+	}
+	outcomes := make(chan outcome)
+
+	for i := 0; i < n; i++ {
+		go func() {
+			result, err := store.RunScheduledTransferTx(ctx)
+			outcomes <- outcome{result, err}
+		}()
+	}
+
+	runs := 0
+	for i := 0; i < n; i++ {
+		o := <-outcomes
+		if o.err != nil {
+			require.ErrorIs(t, o.err, ErrNoScheduledTransferDue)
+			continue
+		}
+		if o.result.ScheduledTransfer.ID == scheduled.ID {
+			runs++
+		}
+	}
+	require.LessOrEqual(t, runs, 1)
+
+	// Other due scheduled transfers may have been claimed first.
+	if runs == 0 {
+		runScheduledTransfer(t, store, scheduled.ID)
+	}
+
+	account, err := store.GetAccount(ctx, account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, account1.Balance-10, account.Balance)
+}
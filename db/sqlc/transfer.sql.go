@@ -8,26 +8,43 @@ package db
 import (
 	"context"
 	"database/sql"
+	"time"
 )
 
 const createTransfer = `-- name: CreateTransfer :one
 INSERT INTO transfers (
     from_account_id,
     to_account_id,
-    amount
+    amount,
+    reversed_transfer_id,
+    to_amount,
+    exchange_rate,
+    fee_of_transfer_id
 ) VALUES (
-    $1, $2, $3
-) RETURNING id, from_account_id, to_account_id, amount, created_at
+    $1, $2, $3, $4, $5, $6, $7
+) RETURNING id, from_account_id, to_account_id, amount, created_at, reversed_transfer_id, to_amount, exchange_rate, fee_of_transfer_id
 `
 
 type CreateTransferParams struct {
-	FromAccountID sql.NullInt64
-	ToAccountID   sql.NullInt64
-	Amount        int64
+	FromAccountID      sql.NullInt64
+	ToAccountID        sql.NullInt64
+	Amount             int64
+	ReversedTransferID sql.NullInt64
+	ToAmount           sql.NullInt64
+	ExchangeRate       sql.NullString
+	FeeOfTransferID    sql.NullInt64
 }
 
 func (q *Queries) CreateTransfer(ctx context.Context, arg CreateTransferParams) (Transfer, error) {
-	row := q.db.QueryRowContext(ctx, createTransfer, arg.FromAccountID, arg.ToAccountID, arg.Amount)
+	row := q.db.QueryRowContext(ctx, createTransfer,
+		arg.FromAccountID,
+		arg.ToAccountID,
+		arg.Amount,
+		arg.ReversedTransferID,
+		arg.ToAmount,
+		arg.ExchangeRate,
+		arg.FeeOfTransferID,
+	)
 	var i Transfer
 	err := row.Scan(
 		&i.ID,
@@ -35,26 +52,44 @@ func (q *Queries) CreateTransfer(ctx context.Context, arg CreateTransferParams)
 		&i.ToAccountID,
 		&i.Amount,
 		&i.CreatedAt,
+		&i.ReversedTransferID,
+		&i.ToAmount,
+		&i.ExchangeRate,
+		&i.FeeOfTransferID,
 	)
 	return i, err
 }
 
-const deleteTransfer = `-- name: DeleteTransfer :exec
-DELETE FROM transfers WHERE id = $1
+const getTransfer = `-- name: GetTransfer :one
+SELECT id, from_account_id, to_account_id, amount, created_at, reversed_transfer_id, to_amount, exchange_rate, fee_of_transfer_id FROM transfers
+WHERE id = $1 LIMIT 1
 `
 
-func (q *Queries) DeleteTransfer(ctx context.Context, id int64) error {
-	_, err := q.db.ExecContext(ctx, deleteTransfer, id)
-	return err
+func (q *Queries) GetTransfer(ctx context.Context, id int64) (Transfer, error) {
+	row := q.db.QueryRowContext(ctx, getTransfer, id)
+	var i Transfer
+	err := row.Scan(
+		&i.ID,
+		&i.FromAccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.CreatedAt,
+		&i.ReversedTransferID,
+		&i.ToAmount,
+		&i.ExchangeRate,
+		&i.FeeOfTransferID,
+	)
+	return i, err
 }
 
-const getTransfer = `-- name: GetTransfer :one
-SELECT id, from_account_id, to_account_id, amount, created_at FROM transfers
+const getTransferForUpdate = `-- name: GetTransferForUpdate :one
+SELECT id, from_account_id, to_account_id, amount, created_at, reversed_transfer_id, to_amount, exchange_rate, fee_of_transfer_id FROM transfers
 WHERE id = $1 LIMIT 1
+FOR UPDATE
 `
 
-func (q *Queries) GetTransfer(ctx context.Context, id int64) (Transfer, error) {
-	row := q.db.QueryRowContext(ctx, getTransfer, id)
+func (q *Queries) GetTransferForUpdate(ctx context.Context, id int64) (Transfer, error) {
+	row := q.db.QueryRowContext(ctx, getTransferForUpdate, id)
 	var i Transfer
 	err := row.Scan(
 		&i.ID,
@@ -62,12 +97,117 @@ func (q *Queries) GetTransfer(ctx context.Context, id int64) (Transfer, error) {
 		&i.ToAccountID,
 		&i.Amount,
 		&i.CreatedAt,
+		&i.ReversedTransferID,
+		&i.ToAmount,
+		&i.ExchangeRate,
+		&i.FeeOfTransferID,
 	)
 	return i, err
 }
 
+const getTransferReversal = `-- name: GetTransferReversal :one
+SELECT id, from_account_id, to_account_id, amount, created_at, reversed_transfer_id, to_amount, exchange_rate, fee_of_transfer_id FROM transfers
+WHERE reversed_transfer_id = $1 LIMIT 1
+`
+
+func (q *Queries) GetTransferReversal(ctx context.Context, reversedTransferID sql.NullInt64) (Transfer, error) {
+	row := q.db.QueryRowContext(ctx, getTransferReversal, reversedTransferID)
+	var i Transfer
+	err := row.Scan(
+		&i.ID,
+		&i.FromAccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.CreatedAt,
+		&i.ReversedTransferID,
+		&i.ToAmount,
+		&i.ExchangeRate,
+		&i.FeeOfTransferID,
+	)
+	return i, err
+}
+
+const listAccountTransfers = `-- name: ListAccountTransfers :many
+SELECT id, from_account_id, to_account_id, amount, created_at, reversed_transfer_id, to_amount, exchange_rate, fee_of_transfer_id FROM transfers
+WHERE (
+    ($1::boolean AND from_account_id = $2::bigint)
+    OR ($3::boolean AND to_account_id = $2::bigint)
+  )
+  AND ($4::bigint IS NULL
+    OR (from_account_id = $2::bigint AND to_account_id = $4::bigint)
+    OR (to_account_id = $2::bigint AND from_account_id = $4::bigint))
+  AND ($5::bigint IS NULL
+    OR CASE WHEN to_account_id = $2::bigint THEN COALESCE(to_amount, amount) ELSE amount END >= $5::bigint)
+  AND ($6::bigint IS NULL
+    OR CASE WHEN to_account_id = $2::bigint THEN COALESCE(to_amount, amount) ELSE amount END <= $6::bigint)
+  AND created_at >= $7
+  AND created_at < $8
+  AND (created_at, id) > ($9::timestamptz, $10::bigint)
+ORDER BY created_at, id
+LIMIT $11
+`
+
+type ListAccountTransfersParams struct {
+	IncludeOutgoing bool
+	AccountID       int64
+	IncludeIncoming bool
+	CounterpartyID  sql.NullInt64
+	MinAmount       sql.NullInt64
+	MaxAmount       sql.NullInt64
+	PeriodStart     time.Time
+	PeriodEnd       time.Time
+	AfterCreatedAt  time.Time
+	AfterID         int64
+	PageSize        int32
+}
+
+func (q *Queries) ListAccountTransfers(ctx context.Context, arg ListAccountTransfersParams) ([]Transfer, error) {
+	rows, err := q.db.QueryContext(ctx, listAccountTransfers,
+		arg.IncludeOutgoing,
+		arg.AccountID,
+		arg.IncludeIncoming,
+		arg.CounterpartyID,
+		arg.MinAmount,
+		arg.MaxAmount,
+		arg.PeriodStart,
+		arg.PeriodEnd,
+		arg.AfterCreatedAt,
+		arg.AfterID,
+		arg.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Transfer{}
+	for rows.Next() {
+		var i Transfer
+		if err := rows.Scan(
+			&i.ID,
+			&i.FromAccountID,
+			&i.ToAccountID,
+			&i.Amount,
+			&i.CreatedAt,
+			&i.ReversedTransferID,
+			&i.ToAmount,
+			&i.ExchangeRate,
+			&i.FeeOfTransferID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listTransfers = `-- name: ListTransfers :many
-SELECT id, from_account_id, to_account_id, amount, created_at FROM transfers
+SELECT id, from_account_id, to_account_id, amount, created_at, reversed_transfer_id, to_amount, exchange_rate, fee_of_transfer_id FROM transfers
 ORDER BY id
 LIMIT $1
 OFFSET $2
@@ -93,6 +233,10 @@ func (q *Queries) ListTransfers(ctx context.Context, arg ListTransfersParams) ([
 			&i.ToAccountID,
 			&i.Amount,
 			&i.CreatedAt,
+			&i.ReversedTransferID,
+			&i.ToAmount,
+			&i.ExchangeRate,
+			&i.FeeOfTransferID,
 		); err != nil {
 			return nil, err
 		}
@@ -106,28 +250,3 @@ func (q *Queries) ListTransfers(ctx context.Context, arg ListTransfersParams) ([
 	}
 	return items, nil
 }
-
-const updateTransfer = `-- name: UpdateTransfer :one
-UPDATE transfers
-SET amount = $2
-WHERE id = $1
-RETURNING id, from_account_id, to_account_id, amount, created_at
-`
-
-type UpdateTransferParams struct {
-	ID     int64
-	Amount int64
-}
-
-func (q *Queries) UpdateTransfer(ctx context.Context, arg UpdateTransferParams) (Transfer, error) {
-	row := q.db.QueryRowContext(ctx, updateTransfer, arg.ID, arg.Amount)
-	var i Transfer
-	err := row.Scan(
-		&i.ID,
-		&i.FromAccountID,
-		&i.ToAccountID,
-		&i.Amount,
-		&i.CreatedAt,
-	)
-	return i, err
-}
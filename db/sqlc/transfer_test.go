@@ -60,39 +60,20 @@ func TestGetTransfer(t *testing.T) {
 	require.Equal(t, transfer1.FromAccountID, transfer2.FromAccountID)
 }
 
-func TestUpdateTransfer(t *testing.T) {
+func TestTransfersAppendOnly(t *testing.T) {
 	ctx := context.Background()
 
 	transfer1 := createRandomTransfer(t)
 
-	arg := UpdateTransferParams{
-		ID:     transfer1.ID,
-		Amount: util.RandomMoney(),
-	}
-
-	transfer2, err := testQueries.UpdateTransfer(ctx, arg)
-	require.NoError(t, err)
-	require.NotEmpty(t, transfer2)
-
-	require.Equal(t, transfer1.ID, transfer2.ID)
-	require.Equal(t, transfer1.FromAccountID, transfer2.FromAccountID)
-	require.Equal(t, transfer1.ToAccountID, transfer2.ToAccountID)
-	require.Equal(t, arg.Amount, transfer2.Amount)
-	require.WithinDuration(t, transfer1.CreatedAt, transfer2.CreatedAt, time.Second)
-}
-
-func TestDeleteTransfer(t *testing.T) {
-	ctx := context.Background()
-
-	transfer1 := createRandomTransfer(t)
+	_, err := testDB.ExecContext(ctx, "UPDATE transfers SET amount = $2 WHERE id = $1", transfer1.ID, util.RandomMoney())
+	require.Error(t, err)
 
-	err := testQueries.DeleteTransfer(ctx, transfer1.ID)
-	require.NoError(t, err)
+	_, err = testDB.ExecContext(ctx, "DELETE FROM transfers WHERE id = $1", transfer1.ID)
+	require.Error(t, err)
 
 	transfer2, err := testQueries.GetTransfer(ctx, transfer1.ID)
-	require.Error(t, err)
-	require.EqualError(t, err, sql.ErrNoRows.Error())
-	require.Empty(t, transfer2)
+	require.NoError(t, err)
+	require.Equal(t, transfer1, transfer2)
 }
 
 func TestListTransfers(t *testing.T) {
@@ -116,3 +97,138 @@ func TestListTransfers(t *testing.T) {
 	}
 
 }
+
+func TestListAccountTransfers(t *testing.T) {
+	ctx := context.Background()
+
+	account1 := createRandomAccount(t)
+	account2 := createRandomAccount(t)
+	account3 := createRandomAccount(t)
+
+	var transfers []Transfer
+	for _, arg := range []CreateTransferParams{
+		{FromAccountID: util.SQLNullInt64(account1.ID), ToAccountID: util.SQLNullInt64(account2.ID), Amount: 10},
+		{FromAccountID: util.SQLNullInt64(account2.ID), ToAccountID: util.SQLNullInt64(account1.ID), Amount: 20},
+		{FromAccountID: util.SQLNullInt64(account1.ID), ToAccountID: util.SQLNullInt64(account3.ID), Amount: 30},
+	} {
+		transfer, err := testQueries.CreateTransfer(ctx, arg)
+		require.NoError(t, err)
+		transfers = append(transfers, transfer)
+	}
+
+	all := ListAccountTransfersParams{
+		IncludeOutgoing: true,
+		AccountID:       account1.ID,
+		IncludeIncoming: true,
+		PeriodEnd:       time.Now().Add(time.Minute),
+		PageSize:        10,
+	}
+
+	testCases := []struct {
+		name     string
+		modify   func(arg *ListAccountTransfersParams)
+		expected []Transfer
+	}{
+		{
+			name:     "Both",
+			modify:   func(arg *ListAccountTransfersParams) {},
+			expected: transfers,
+		},
+		{
+			name:     "Outgoing",
+			modify:   func(arg *ListAccountTransfersParams) { arg.IncludeIncoming = false },
+			expected: []Transfer{transfers[0], transfers[2]},
+		},
+		{
+			name:     "Incoming",
+			modify:   func(arg *ListAccountTransfersParams) { arg.IncludeOutgoing = false },
+			expected: []Transfer{transfers[1]},
+		},
+		{
+			name:     "Counterparty",
+			modify:   func(arg *ListAccountTransfersParams) { arg.CounterpartyID = util.SQLNullInt64(account2.ID) },
+			expected: []Transfer{transfers[0], transfers[1]},
+		},
+		{
+			name:     "SelfCounterparty",
+			modify:   func(arg *ListAccountTransfersParams) { arg.CounterpartyID = util.SQLNullInt64(account1.ID) },
+			expected: []Transfer{},
+		},
+		{
+			name: "AmountRange",
+			modify: func(arg *ListAccountTransfersParams) {
+				arg.MinAmount = util.SQLNullInt64(15)
+				arg.MaxAmount = util.SQLNullInt64(25)
+			},
+			expected: []Transfer{transfers[1]},
+		},
+		{
+			name:     "Period",
+			modify:   func(arg *ListAccountTransfersParams) { arg.PeriodStart = transfers[2].CreatedAt },
+			expected: []Transfer{transfers[2]},
+		},
+		{
+			name: "AfterCursor",
+			modify: func(arg *ListAccountTransfersParams) {
+				arg.AfterCreatedAt = transfers[0].CreatedAt
+				arg.AfterID = transfers[0].ID
+			},
+			expected: transfers[1:],
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			arg := all
+			tc.modify(&arg)
+
+			got, err := testQueries.ListAccountTransfers(ctx, arg)
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, got)
+		})
+	}
+}
+
+func TestListAccountTransfersCrossCurrencyAmount(t *testing.T) {
+	ctx := context.Background()
+
+	sender := createRandomAccount(t)
+	receiver := createRandomAccount(t)
+
+	// 10 units of the sender currency credit 50 units of the receiver currency.
+	transfer, err := testQueries.CreateTransfer(ctx, CreateTransferParams{
+		FromAccountID: util.SQLNullInt64(sender.ID),
+		ToAccountID:   util.SQLNullInt64(receiver.ID),
+		Amount:        10,
+		ToAmount:      util.SQLNullInt64[int64](50),
+		ExchangeRate:  sql.NullString{String: "5", Valid: true},
+	})
+	require.NoError(t, err)
+
+	arg := ListAccountTransfersParams{
+		IncludeOutgoing: true,
+		IncludeIncoming: true,
+		MinAmount:       util.SQLNullInt64(40),
+		MaxAmount:       util.SQLNullInt64(60),
+		PeriodEnd:       time.Now().Add(time.Minute),
+		PageSize:        10,
+	}
+
+	// The receiver sees the amount credited in its currency.
+	arg.AccountID = receiver.ID
+	got, err := testQueries.ListAccountTransfers(ctx, arg)
+	require.NoError(t, err)
+	require.Equal(t, []Transfer{transfer}, got)
+
+	// The sender sees the amount debited in its currency.
+	arg.AccountID = sender.ID
+	got, err = testQueries.ListAccountTransfers(ctx, arg)
+	require.NoError(t, err)
+	require.Empty(t, got)
+
+	arg.MinAmount = util.SQLNullInt64(5)
+	arg.MaxAmount = util.SQLNullInt64(15)
+	got, err = testQueries.ListAccountTransfers(ctx, arg)
+	require.NoError(t, err)
+	require.Equal(t, []Transfer{transfer}, got)
+}
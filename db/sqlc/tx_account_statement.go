@@ -0,0 +1,69 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// AccountStatementTxParams contains the input parameters of the account statement transaction.
+// Entries are returned for the half-open period [PeriodStart, PeriodEnd), in (created_at, id) order,
+// starting after the given keyset position.
+type AccountStatementTxParams struct {
+	AccountID      int64
+	PeriodStart    time.Time
+	PeriodEnd      time.Time
+	AfterCreatedAt time.Time
+	AfterID        int64
+	PageSize       int32
+}
+
+// AccountStatementTxResult is the result of the account statement transaction.
+type AccountStatementTxResult struct {
+	OpeningBalance int64
+	ClosingBalance int64
+	Entries        []ListAccountStatementRow
+}
+
+// AccountStatementTx reads a page of account entries with their running balance, together with the opening
+// and closing balance of the period, from a single snapshot so that the numbers always add up.
+func (store *SQLStore) AccountStatementTx(ctx context.Context, arg AccountStatementTxParams) (AccountStatementTxResult, error) {
+	var result AccountStatementTxResult
+
+	opts := &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true}
+
+	if _, err := store.execTx(ctx, opts, func(q *Queries) error {
+		balances, err := q.GetAccountStatementBalances(ctx, GetAccountStatementBalancesParams{
+			PeriodStart: arg.PeriodStart,
+			PeriodEnd:   arg.PeriodEnd,
+			AccountID:   arg.AccountID,
+		})
+		if err != nil {
+			return err
+		}
+
+		entries, err := q.ListAccountStatement(ctx, ListAccountStatementParams{
+			AccountID:      sql.NullInt64{Int64: arg.AccountID, Valid: true},
+			PeriodStart:    arg.PeriodStart,
+			PeriodEnd:      arg.PeriodEnd,
+			AfterCreatedAt: arg.AfterCreatedAt,
+			AfterID:        arg.AfterID,
+			PageSize:       arg.PageSize,
+		})
+		if err != nil {
+			return err
+		}
+
+		result = AccountStatementTxResult{
+			OpeningBalance: balances.OpeningBalance,
+			ClosingBalance: balances.ClosingBalance,
+			Entries:        entries,
+		}
+
+		return nil
+	}); err != nil {
+		return AccountStatementTxResult{}, err
+	}
+
+	return result, nil
+}
@@ -0,0 +1,79 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Statuses of an account.
+const (
+	AccountStatusActive = "active"
+	AccountStatusFrozen = "frozen"
+	AccountStatusClosed = "closed"
+)
+
+var (
+	// ErrAccountFrozen is returned when moving money from or to a frozen account.
+	ErrAccountFrozen = errors.New("account is frozen")
+	// ErrAccountClosed is returned when moving money from or to a closed account, or changing its status.
+	ErrAccountClosed = errors.New("account is closed")
+	// ErrAccountBalanceNotZero is returned when closing an account that still holds money.
+	ErrAccountBalanceNotZero = errors.New("account balance is not zero")
+)
+
+// checkAccountActive returns ErrAccountFrozen or ErrAccountClosed unless money can move from or to the account.
+func checkAccountActive(account Account) error {
+	switch account.Status {
+	case AccountStatusFrozen:
+		return fmt.Errorf("%w: account [%d]", ErrAccountFrozen, account.ID)
+	case AccountStatusClosed:
+		return fmt.Errorf("%w: account [%d]", ErrAccountClosed, account.ID)
+	}
+
+	return nil
+}
+
+// UpdateAccountStatusTxParams contains the input parameters of the update account status transaction.
+type UpdateAccountStatusTxParams struct {
+	ID     int64
+	Status string
+}
+
+// UpdateAccountStatusTx freezes, unfreezes or closes an account. Closing is final and replaces deleting:
+// the account and its ledger are kept, but it no longer takes part in transfers nor appears in listings.
+// It fails with ErrAccountClosed if the account is already closed, and with ErrAccountBalanceNotZero
+// when closing an account whose balance isn't zero. Setting the current status again is a no-op.
+func (store *SQLStore) UpdateAccountStatusTx(ctx context.Context, arg UpdateAccountStatusTxParams) (Account, error) {
+	var account Account
+
+	if _, err := store.execTx(ctx, nil, func(q *Queries) error {
+		// Locking the account serializes the status change with concurrent transfers.
+		before, err := q.GetAccountForUpdate(ctx, arg.ID)
+		if err != nil {
+			return err
+		}
+		account = before
+
+		if account.Status != arg.Status {
+			if account.Status == AccountStatusClosed {
+				return fmt.Errorf("%w: account [%d]", ErrAccountClosed, account.ID)
+			}
+
+			if arg.Status == AccountStatusClosed && account.Balance != 0 {
+				return fmt.Errorf("%w: account [%d] balance is %d", ErrAccountBalanceNotZero, account.ID, account.Balance)
+			}
+
+			account, err = q.UpdateAccountStatus(ctx, UpdateAccountStatusParams{ID: arg.ID, Status: arg.Status})
+			if err != nil {
+				return err
+			}
+		}
+
+		return audit(ctx, q, AuditChange{ResourceType: "accounts", ResourceID: AuditID(account.ID), Before: before, After: account})
+	}); err != nil {
+		return Account{}, err
+	}
+
+	return account, nil
+}
@@ -0,0 +1,176 @@
+package db
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrAuditLogTampered is returned when an audit log entry doesn't match its hash, or isn't chained to the previous one.
+var ErrAuditLogTampered = errors.New("audit log tampered")
+
+// CreateAuditLogTxParams contains the input parameters of the create audit log transaction.
+type CreateAuditLogTxParams struct {
+	Actor        sql.NullString
+	Action       string
+	ResourceType string
+	ResourceID   sql.NullString
+	RequestID    string
+	ClientIp     string
+	// Before and After are JSON snapshots of the resource, null when they don't apply.
+	Before     json.RawMessage
+	After      json.RawMessage
+	Outcome    string
+	StatusCode int32
+}
+
+// CreateAuditLogTx appends an entry to the audit log in its own transaction, see appendAuditLog.
+func (store *SQLStore) CreateAuditLogTx(ctx context.Context, arg CreateAuditLogTxParams) (AuditLog, error) {
+	var entry AuditLog
+
+	if _, err := store.execTx(ctx, nil, func(q *Queries) error {
+		var err error
+		entry, err = appendAuditLog(ctx, q, arg)
+		return err
+	}); err != nil {
+		return AuditLog{}, err
+	}
+
+	return entry, nil
+}
+
+// appendAuditLog appends an entry to the audit log using the given transaction queries, chained to the previous
+// entry by its hash, see AuditLogHash. Appends are serialized with a global advisory lock, held until the transaction
+// ends, so that the chain never forks. This serializes the commits of all the audited transactions, transfers
+// included: taking the lock as their last statement keeps it held only for the append and the commit, not while
+// the accounts are locked and updated, and since no other lock is taken after it, it can't deadlock with them.
+func appendAuditLog(ctx context.Context, q *Queries, arg CreateAuditLogTxParams) (AuditLog, error) {
+	if err := q.LockAuditLog(ctx); err != nil {
+		return AuditLog{}, err
+	}
+
+	var prevHash string
+
+	last, err := q.GetLastAuditLog(ctx)
+	switch {
+	case err == nil:
+		prevHash = last.Hash
+	case !errors.Is(err, sql.ErrNoRows):
+		return AuditLog{}, err
+	}
+
+	params := CreateAuditLogParams{
+		Actor:        arg.Actor,
+		Action:       arg.Action,
+		ResourceType: arg.ResourceType,
+		ResourceID:   arg.ResourceID,
+		RequestID:    arg.RequestID,
+		ClientIp:     arg.ClientIp,
+		Before:       jsonOrNull(arg.Before),
+		After:        jsonOrNull(arg.After),
+		Outcome:      arg.Outcome,
+		StatusCode:   arg.StatusCode,
+		// Postgres keeps microseconds: the hash must cover the time as it is stored.
+		CreatedAt: time.Now().UTC().Truncate(time.Microsecond),
+		PrevHash:  prevHash,
+	}
+	params.Hash = AuditLogHash(params)
+
+	return q.CreateAuditLog(ctx, params)
+}
+
+// AuditLogHash returns the hex-encoded SHA-256 of an audit log entry, including the hash of the previous entry
+// but not its own Hash. Changing any entry breaks the chain from that entry on.
+func AuditLogHash(arg CreateAuditLogParams) string {
+	// The fields are hashed in a fixed JSON layout, with the time in UTC so that it doesn't depend
+	// on the time zone it was read in.
+	data, _ := json.Marshal(struct {
+		PrevHash     string          `json:"prev_hash"`
+		Actor        *string         `json:"actor"`
+		Action       string          `json:"action"`
+		ResourceType string          `json:"resource_type"`
+		ResourceID   *string         `json:"resource_id"`
+		RequestID    string          `json:"request_id"`
+		ClientIP     string          `json:"client_ip"`
+		Before       json.RawMessage `json:"before"`
+		After        json.RawMessage `json:"after"`
+		Outcome      string          `json:"outcome"`
+		StatusCode   int32           `json:"status_code"`
+		CreatedAt    string          `json:"created_at"`
+	}{
+		PrevHash:     arg.PrevHash,
+		Actor:        nullStringPtr(arg.Actor),
+		Action:       arg.Action,
+		ResourceType: arg.ResourceType,
+		ResourceID:   nullStringPtr(arg.ResourceID),
+		RequestID:    arg.RequestID,
+		ClientIP:     arg.ClientIp,
+		Before:       jsonOrNull(arg.Before),
+		After:        jsonOrNull(arg.After),
+		Outcome:      arg.Outcome,
+		StatusCode:   arg.StatusCode,
+		CreatedAt:    arg.CreatedAt.UTC().Format(time.RFC3339Nano),
+	})
+
+	sum := sha256.Sum256(data)
+
+	return hex.EncodeToString(sum[:])
+}
+
+// VerifyAuditLog checks that the entries, in ID order, are chained to prevHash and to each other,
+// and that none of them was changed. It returns the hash of the last entry, to verify the next ones with.
+// The first entry of the log is chained to an empty hash.
+func VerifyAuditLog(prevHash string, entries []AuditLog) (string, error) {
+	for _, entry := range entries {
+		if entry.PrevHash != prevHash {
+			return prevHash, fmt.Errorf("%w: entry [%d] isn't chained to the previous entry", ErrAuditLogTampered, entry.ID)
+		}
+
+		if AuditLogHash(auditLogParams(entry)) != entry.Hash {
+			return prevHash, fmt.Errorf("%w: entry [%d] doesn't match its hash", ErrAuditLogTampered, entry.ID)
+		}
+
+		prevHash = entry.Hash
+	}
+
+	return prevHash, nil
+}
+
+func auditLogParams(entry AuditLog) CreateAuditLogParams {
+	return CreateAuditLogParams{
+		Actor:        entry.Actor,
+		Action:       entry.Action,
+		ResourceType: entry.ResourceType,
+		ResourceID:   entry.ResourceID,
+		RequestID:    entry.RequestID,
+		ClientIp:     entry.ClientIp,
+		Before:       entry.Before,
+		After:        entry.After,
+		Outcome:      entry.Outcome,
+		StatusCode:   entry.StatusCode,
+		CreatedAt:    entry.CreatedAt,
+		PrevHash:     entry.PrevHash,
+		Hash:         entry.Hash,
+	}
+}
+
+func jsonOrNull(data json.RawMessage) json.RawMessage {
+	if len(data) == 0 {
+		return json.RawMessage("null")
+	}
+
+	return data
+}
+
+func nullStringPtr(s sql.NullString) *string {
+	if !s.Valid {
+		return nil
+	}
+
+	return &s.String
+}
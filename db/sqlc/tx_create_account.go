@@ -0,0 +1,38 @@
+package db
+
+import "context"
+
+// CreateAccountTxParams contains the input parameters of the create account transaction.
+type CreateAccountTxParams struct {
+	CreateAccountParams
+	Idempotency *IdempotencyParams
+}
+
+// CreateAccountTx creates an account, writes its AccountCreated event to the outbox and, if requested,
+// stores its idempotency key within a single database transaction.
+func (store *SQLStore) CreateAccountTx(ctx context.Context, arg CreateAccountTxParams) (Account, error) {
+	var account Account
+
+	if _, err := store.execTx(ctx, nil, func(q *Queries) error {
+		var err error
+
+		account, err = q.CreateAccount(ctx, arg.CreateAccountParams)
+		if err != nil {
+			return err
+		}
+
+		if err := recordAccountCreated(ctx, q, account); err != nil {
+			return err
+		}
+
+		if err := saveIdempotencyKey(ctx, q, arg.Idempotency, account); err != nil {
+			return err
+		}
+
+		return audit(ctx, q, AuditChange{ResourceType: "accounts", ResourceID: AuditID(account.ID), After: account})
+	}); err != nil {
+		return Account{}, err
+	}
+
+	return account, nil
+}
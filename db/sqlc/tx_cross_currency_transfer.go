@@ -0,0 +1,83 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"tech-school/fx"
+	"tech-school/money"
+)
+
+// ErrExchangeRateMismatch is returned when the exchange rate doesn't convert between the currencies of the accounts.
+var ErrExchangeRateMismatch = errors.New("exchange rate doesn't match the accounts currencies")
+
+// CrossCurrencyTransferTxParams contains the input parameters of the cross-currency transfer transaction.
+type CrossCurrencyTransferTxParams struct {
+	TransferTxParams
+
+	// Rate converts Amount, in the source account currency, to the destination account currency.
+	Rate fx.Rate `json:"-"`
+}
+
+// CrossCurrencyTransferTx performs a money transfer between accounts in different currencies.
+// Amount is debited from the source account and converted with the given rate, following the rounding rules
+// of fx.Rate.Convert, before being credited to the destination account. The transfer records the rate,
+// the source amount and the destination amount. The fee, if any, is charged in the source account currency.
+// It fails with ErrInsufficientFunds if the source account balance is lower than the amount plus the fee,
+// and with fx.ErrAmountTooSmall if the amount converts to less than one minor unit.
+func (store *SQLStore) CrossCurrencyTransferTx(ctx context.Context, arg CrossCurrencyTransferTxParams) (TransferTxResult, error) {
+	var result TransferTxResult
+
+	retries, err := store.execTx(ctx, nil, func(q *Queries) error {
+		accounts, err := store.lockChargedTransferAccounts(ctx, q, arg.FromAccountID.Int64, arg.ToAccountID.Int64, arg.Amount)
+		if err != nil {
+			return err
+		}
+
+		fromAccount, toAccount := accounts.From, accounts.To
+
+		if arg.Rate.From != fromAccount.Currency || arg.Rate.To != toAccount.Currency {
+			return fmt.Errorf("%w: rate %s/%s for accounts in %s and %s",
+				ErrExchangeRateMismatch, arg.Rate.From, arg.Rate.To, fromAccount.Currency, toAccount.Currency)
+		}
+
+		converted, err := arg.Rate.Convert(money.Money{Amount: arg.Amount, Currency: fromAccount.Currency})
+		if err != nil {
+			return err
+		}
+
+		if !converted.IsPositive() {
+			return fmt.Errorf("%w: %d %s at %s", fx.ErrAmountTooSmall, arg.Amount, fromAccount.Currency, arg.Rate)
+		}
+
+		result, err = transfer(ctx, q, transferParams{
+			FromAccount:  fromAccount,
+			ToAccount:    toAccount,
+			Amount:       arg.Amount,
+			ToAmount:     sql.NullInt64{Int64: converted.Amount, Valid: true},
+			ExchangeRate: sql.NullString{String: arg.Rate.String(), Valid: true},
+		})
+		if err != nil {
+			return err
+		}
+
+		if err := chargeFee(ctx, q, &result, accounts); err != nil {
+			return err
+		}
+
+		if err := saveIdempotencyKey(ctx, q, arg.Idempotency, result); err != nil {
+			return err
+		}
+
+		return audit(ctx, q, AuditChange{ResourceType: "transfers", ResourceID: AuditID(result.Transfer.ID), After: result})
+	})
+	if err != nil {
+		return TransferTxResult{}, err
+	}
+
+	result.Retries = retries
+
+	return result, nil
+}
@@ -0,0 +1,117 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"tech-school/fees"
+)
+
+// TransferFee is the fee charged on a transfer. It is posted as a transfer of its own,
+// from the source account to the fee-revenue account, linked to the charged transfer via fee_of_transfer_id.
+type TransferFee struct {
+	fees.Fee
+	Transfer  Transfer `json:"transfer"`
+	FromEntry Entry    `json:"from_entry"`
+	ToEntry   Entry    `json:"to_entry"`
+}
+
+// chargedTransferAccounts holds the accounts of a transfer, locked for update, and the fee charged on it.
+type chargedTransferAccounts struct {
+	From Account
+	To   Account
+	// Revenue is the fee-revenue account. It is only set when Fee.Total is positive.
+	Revenue Account
+	Fee     fees.Fee
+}
+
+// lockChargedTransferAccounts selects for update the accounts of a transfer together with the fee-revenue account
+// of the source account currency, and computes the fee charged on the amount.
+// It fails with ErrInsufficientFunds if the source account available balance can't cover both the amount and the fee.
+func (store *SQLStore) lockChargedTransferAccounts(ctx context.Context, q *Queries, fromAccountID, toAccountID, amount int64) (chargedTransferAccounts, error) {
+	var (
+		result chargedTransferAccounts
+		rule   fees.Rule
+		ok     bool
+	)
+
+	if store.fees != nil {
+		// The currency of an account never changes, so it can be read before locking
+		// the fee-revenue account along with the others.
+		fromAccount, err := q.GetAccount(ctx, fromAccountID)
+		if err != nil {
+			return result, err
+		}
+		rule, ok = store.fees.Rule(fromAccount.Currency)
+	}
+
+	// The fee-revenue account doesn't charge fees on its own transfers.
+	if !ok || rule.RevenueAccountID == fromAccountID {
+		from, to, err := lockTransferAccounts(ctx, q, fromAccountID, toAccountID)
+		return chargedTransferAccounts{From: from, To: to}, err
+	}
+
+	accounts, err := lockAccounts(ctx, q, fromAccountID, toAccountID, rule.RevenueAccountID)
+	if err != nil {
+		return result, err
+	}
+
+	result.From, result.To = accounts[fromAccountID], accounts[toAccountID]
+
+	result.Fee, err = rule.Fee(amount)
+	if err != nil {
+		return result, err
+	}
+
+	if result.Fee.Total > 0 {
+		result.Revenue = accounts[rule.RevenueAccountID]
+		if result.Revenue.Currency != result.From.Currency {
+			return result, fmt.Errorf("fee-revenue account [%d] is in %s, not %s", result.Revenue.ID, result.Revenue.Currency, result.From.Currency)
+		}
+	}
+
+	available, err := availableBalance(ctx, q, result.From)
+	if err != nil {
+		return result, err
+	}
+
+	if available-amount < result.Fee.Total {
+		return result, fmt.Errorf("%w: account [%d] available balance %d is less than %d plus a fee of %d",
+			ErrInsufficientFunds, result.From.ID, available, amount, result.Fee.Total)
+	}
+
+	return result, nil
+}
+
+// chargeFee posts the fee of the transfer in result, if any, to the fee-revenue account,
+// and updates the accounts of result with their balance after the fee.
+func chargeFee(ctx context.Context, q *Queries, result *TransferTxResult, accounts chargedTransferAccounts) error {
+	if accounts.Fee.Total <= 0 {
+		return nil
+	}
+
+	feeResult, err := transfer(ctx, q, transferParams{
+		FromAccount:     result.FromAccount,
+		ToAccount:       accounts.Revenue,
+		Amount:          accounts.Fee.Total,
+		FeeOfTransferID: sql.NullInt64{Int64: result.Transfer.ID, Valid: true},
+	})
+	if err != nil {
+		return err
+	}
+
+	result.FromAccount = feeResult.FromAccount
+	if result.ToAccount.ID == feeResult.ToAccount.ID {
+		result.ToAccount = feeResult.ToAccount
+	}
+
+	result.Fee = &TransferFee{
+		Fee:       accounts.Fee,
+		Transfer:  feeResult.Transfer,
+		FromEntry: feeResult.FromEntry,
+		ToEntry:   feeResult.ToEntry,
+	}
+
+	return nil
+}
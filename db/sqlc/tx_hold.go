@@ -0,0 +1,225 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+// Statuses of a hold.
+const (
+	HoldStatusActive   = "active"
+	HoldStatusCaptured = "captured"
+	HoldStatusReleased = "released"
+	HoldStatusExpired  = "expired"
+)
+
+var (
+	// ErrHoldNotActive is returned when capturing or releasing a hold that was already captured, released or expired.
+	ErrHoldNotActive = errors.New("hold is not active")
+	// ErrHoldExpired is returned when capturing a hold past its expiry time.
+	ErrHoldExpired = errors.New("hold has expired")
+	// ErrCaptureExceedsHold is returned when capturing more than the held amount.
+	ErrCaptureExceedsHold = errors.New("capture amount exceeds the held amount")
+)
+
+// availableBalance returns the account balance minus the amount of its active, unexpired holds.
+func availableBalance(ctx context.Context, q *Queries, account Account) (int64, error) {
+	held, err := q.GetAccountHeldAmount(ctx, account.ID)
+	if err != nil {
+		return 0, err
+	}
+
+	return account.Balance - held, nil
+}
+
+// PlaceHoldTxParams contains the input parameters of the place hold transaction.
+type PlaceHoldTxParams struct {
+	CreateHoldParams
+
+	// Idempotency, when set, stores the hold under the client's idempotency key.
+	Idempotency *IdempotencyParams
+}
+
+// PlaceHoldTx reserves an amount of the account available balance for a later capture by the destination account.
+// It fails with ErrInsufficientFunds if the account available balance is lower than the amount,
+// and with ErrAccountFrozen or ErrAccountClosed if the account is frozen or closed.
+func (store *SQLStore) PlaceHoldTx(ctx context.Context, arg PlaceHoldTxParams) (Hold, error) {
+	var hold Hold
+
+	if _, err := store.execTx(ctx, nil, func(q *Queries) error {
+		// Locking the account serializes the hold with concurrent transfers and holds spending its balance.
+		account, err := q.GetAccountForUpdate(ctx, arg.AccountID)
+		if err != nil {
+			return err
+		}
+
+		if err := checkAccountActive(account); err != nil {
+			return err
+		}
+
+		available, err := availableBalance(ctx, q, account)
+		if err != nil {
+			return err
+		}
+
+		if available < arg.Amount {
+			return fmt.Errorf("%w: account [%d] available balance %d is less than %d", ErrInsufficientFunds, account.ID, available, arg.Amount)
+		}
+
+		hold, err = q.CreateHold(ctx, arg.CreateHoldParams)
+		if err != nil {
+			return err
+		}
+
+		if err := saveIdempotencyKey(ctx, q, arg.Idempotency, hold); err != nil {
+			return err
+		}
+
+		return audit(ctx, q, AuditChange{ResourceType: "holds", ResourceID: AuditID(hold.ID), After: hold})
+	}); err != nil {
+		return Hold{}, err
+	}
+
+	return hold, nil
+}
+
+// CaptureHoldTxParams contains the input parameters of the capture hold transaction.
+type CaptureHoldTxParams struct {
+	HoldID int64
+	// Amount is the captured amount. Zero captures the whole hold.
+	Amount int64
+}
+
+// CaptureHoldTxResult is the result of the capture hold transaction.
+type CaptureHoldTxResult struct {
+	Hold Hold
+	TransferTxResult
+}
+
+// CaptureHoldTx turns an active hold into a transfer to its destination account. Capturing less than the held
+// amount settles the hold: the rest is released. No fee is charged on captures.
+// It fails with ErrHoldNotActive, ErrHoldExpired or ErrCaptureExceedsHold.
+func (store *SQLStore) CaptureHoldTx(ctx context.Context, arg CaptureHoldTxParams) (CaptureHoldTxResult, error) {
+	var result CaptureHoldTxResult
+
+	retries, err := store.execTx(ctx, nil, func(q *Queries) error {
+		hold, err := lockActiveHold(ctx, q, arg.HoldID)
+		if err != nil {
+			return err
+		}
+
+		if !hold.ExpiresAt.After(time.Now()) {
+			return fmt.Errorf("%w: hold [%d] expired at %s", ErrHoldExpired, hold.ID, hold.ExpiresAt)
+		}
+
+		amount := arg.Amount
+		if amount == 0 {
+			amount = hold.Amount
+		}
+		if amount > hold.Amount {
+			return fmt.Errorf("%w: %d is more than %d held by hold [%d]", ErrCaptureExceedsHold, amount, hold.Amount, hold.ID)
+		}
+
+		fromAccount, toAccount, err := lockTransferAccounts(ctx, q, hold.AccountID, hold.ToAccountID)
+		if err != nil {
+			return err
+		}
+
+		// The hold is resolved before the transfer, so that the funds it reserved become available to it.
+		if _, err := q.ResolveHold(ctx, ResolveHoldParams{ID: hold.ID, Status: HoldStatusCaptured}); err != nil {
+			return err
+		}
+
+		result.TransferTxResult, err = transfer(ctx, q, transferParams{
+			FromAccount: fromAccount,
+			ToAccount:   toAccount,
+			Amount:      amount,
+		})
+		if err != nil {
+			return err
+		}
+
+		result.Hold, err = q.ResolveHold(ctx, ResolveHoldParams{
+			ID:         hold.ID,
+			Status:     HoldStatusCaptured,
+			TransferID: sql.NullInt64{Int64: result.Transfer.ID, Valid: true},
+		})
+		if err != nil {
+			return err
+		}
+
+		return audit(ctx, q, AuditChange{ResourceType: "holds", ResourceID: AuditID(hold.ID), Before: hold, After: result})
+	})
+	if err != nil {
+		return CaptureHoldTxResult{}, err
+	}
+
+	result.Retries = retries
+
+	return result, nil
+}
+
+// ReleaseHoldTx cancels an active hold, making the funds it reserved available again.
+// It fails with ErrHoldNotActive if the hold was already captured, released or expired.
+func (store *SQLStore) ReleaseHoldTx(ctx context.Context, holdID int64) (Hold, error) {
+	var hold Hold
+
+	if _, err := store.execTx(ctx, nil, func(q *Queries) error {
+		before, err := lockActiveHold(ctx, q, holdID)
+		if err != nil {
+			return err
+		}
+
+		hold, err = q.ResolveHold(ctx, ResolveHoldParams{ID: holdID, Status: HoldStatusReleased})
+		if err != nil {
+			return err
+		}
+
+		return audit(ctx, q, AuditChange{ResourceType: "holds", ResourceID: AuditID(hold.ID), Before: before, After: hold})
+	}); err != nil {
+		return Hold{}, err
+	}
+
+	return hold, nil
+}
+
+// lockActiveHold selects the hold for update and checks that it is still active.
+func lockActiveHold(ctx context.Context, q *Queries, holdID int64) (Hold, error) {
+	hold, err := q.GetHoldForUpdate(ctx, holdID)
+	if err != nil {
+		return Hold{}, err
+	}
+
+	if hold.Status != HoldStatusActive {
+		return Hold{}, fmt.Errorf("%w: hold [%d] is %s", ErrHoldNotActive, hold.ID, hold.Status)
+	}
+
+	return hold, nil
+}
+
+// ExpireHoldsPeriodically marks the active holds past their expiry time as expired every interval, until ctx is done.
+// Expired holds already stop counting against the available balance; this only keeps their status accurate.
+func ExpireHoldsPeriodically(ctx context.Context, q Querier, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			expired, err := q.ExpireHolds(ctx)
+			if err != nil {
+				log.Printf("failed to expire holds: %v", err)
+				continue
+			}
+			if expired > 0 {
+				log.Printf("expired %d holds", expired)
+			}
+		}
+	}
+}
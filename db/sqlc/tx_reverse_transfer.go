@@ -0,0 +1,99 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrTransferAlreadyReversed is returned when a transfer already has a compensating transfer.
+	ErrTransferAlreadyReversed = errors.New("transfer already reversed")
+	// ErrReversalNotReversible is returned when asked to reverse a compensating transfer.
+	ErrReversalNotReversible = errors.New("reversal transfers can't be reversed")
+	// ErrFeeNotReversible is returned when asked to reverse the transfer of a fee.
+	ErrFeeNotReversible = errors.New("fee transfers can't be reversed")
+)
+
+// ReverseTransferTxParams contains the input parameters of the reverse transfer transaction.
+type ReverseTransferTxParams struct {
+	TransferID int64
+
+	// Idempotency, when set, stores the reversal result under the client's idempotency key.
+	Idempotency *IdempotencyParams
+}
+
+// ReverseTransferTx undoes a transfer without rewriting history: it records a compensating transfer
+// of the same amount in the opposite direction, linked to the original one via reversed_transfer_id.
+// A cross-currency transfer is reversed with its recorded amounts, without any exchange rate.
+// The fee charged on the original transfer isn't refunded, and no fee is charged on the reversal.
+// It fails with ErrInsufficientFunds if the original destination account has already spent the money.
+func (store *SQLStore) ReverseTransferTx(ctx context.Context, arg ReverseTransferTxParams) (TransferTxResult, error) {
+	var (
+		result   TransferTxResult
+		original Transfer
+	)
+
+	retries, err := store.execTx(ctx, nil, func(q *Queries) error {
+		// Locking the original transfer serializes concurrent reversals of the same transfer.
+		var err error
+		original, err = q.GetTransferForUpdate(ctx, arg.TransferID)
+		if err != nil {
+			return err
+		}
+
+		if original.ReversedTransferID.Valid {
+			return fmt.Errorf("%w: transfer [%d] reverses transfer [%d]", ErrReversalNotReversible, original.ID, original.ReversedTransferID.Int64)
+		}
+
+		if original.FeeOfTransferID.Valid {
+			return fmt.Errorf("%w: transfer [%d] is the fee of transfer [%d]", ErrFeeNotReversible, original.ID, original.FeeOfTransferID.Int64)
+		}
+
+		reversedTransferID := sql.NullInt64{Int64: original.ID, Valid: true}
+
+		reversal, err := q.GetTransferReversal(ctx, reversedTransferID)
+		if err == nil {
+			return fmt.Errorf("%w: transfer [%d] is reversed by transfer [%d]", ErrTransferAlreadyReversed, original.ID, reversal.ID)
+		}
+		if err != sql.ErrNoRows {
+			return err
+		}
+
+		fromAccount, toAccount, err := lockTransferAccounts(ctx, q, original.ToAccountID.Int64, original.FromAccountID.Int64)
+		if err != nil {
+			return err
+		}
+
+		compensation := transferParams{
+			FromAccount:        fromAccount,
+			ToAccount:          toAccount,
+			Amount:             original.Amount,
+			ReversedTransferID: reversedTransferID,
+		}
+		if original.ToAmount.Valid {
+			// Give back the exact amounts of a cross-currency transfer rather than converting them again.
+			compensation.Amount = original.ToAmount.Int64
+			compensation.ToAmount = sql.NullInt64{Int64: original.Amount, Valid: true}
+		}
+
+		result, err = transfer(ctx, q, compensation)
+		if err != nil {
+			return err
+		}
+
+		if err := saveIdempotencyKey(ctx, q, arg.Idempotency, result); err != nil {
+			return err
+		}
+
+		return audit(ctx, q, AuditChange{ResourceType: "transfers", ResourceID: AuditID(original.ID), Before: original, After: result})
+	})
+	if err != nil {
+		return TransferTxResult{}, err
+	}
+
+	result.Retries = retries
+
+	return result, nil
+}
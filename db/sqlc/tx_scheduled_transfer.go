@@ -0,0 +1,252 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"tech-school/recurrence"
+)
+
+// Statuses of a scheduled transfer.
+const (
+	ScheduledTransferStatusActive    = "active"
+	ScheduledTransferStatusCompleted = "completed"
+	ScheduledTransferStatusCancelled = "cancelled"
+)
+
+// ErrNoScheduledTransferDue is returned when no scheduled transfer is due, or the due ones are all being run
+// by other transactions.
+var ErrNoScheduledTransferDue = errors.New("no scheduled transfer is due")
+
+// CreateScheduledTransferTxParams contains the input parameters of the create scheduled transfer transaction.
+type CreateScheduledTransferTxParams struct {
+	CreateScheduledTransferParams
+	Idempotency *IdempotencyParams
+}
+
+// CreateScheduledTransferTx creates a scheduled transfer and, if requested, stores its idempotency key
+// within a single database transaction.
+func (store *SQLStore) CreateScheduledTransferTx(ctx context.Context, arg CreateScheduledTransferTxParams) (ScheduledTransfer, error) {
+	var scheduled ScheduledTransfer
+
+	if _, err := store.execTx(ctx, nil, func(q *Queries) error {
+		var err error
+
+		scheduled, err = q.CreateScheduledTransfer(ctx, arg.CreateScheduledTransferParams)
+		if err != nil {
+			return err
+		}
+
+		if err := saveIdempotencyKey(ctx, q, arg.Idempotency, scheduled); err != nil {
+			return err
+		}
+
+		return audit(ctx, q, AuditChange{ResourceType: "scheduled_transfers", ResourceID: AuditID(scheduled.ID), After: scheduled})
+	}); err != nil {
+		return ScheduledTransfer{}, err
+	}
+
+	return scheduled, nil
+}
+
+// UpdateScheduledTransferTx changes the amount, schedule or next run of an active scheduled transfer.
+// It fails with sql.ErrNoRows if the scheduled transfer isn't active anymore.
+func (store *SQLStore) UpdateScheduledTransferTx(ctx context.Context, arg UpdateScheduledTransferParams) (ScheduledTransfer, error) {
+	var scheduled ScheduledTransfer
+
+	if _, err := store.execTx(ctx, nil, func(q *Queries) error {
+		before, err := q.GetScheduledTransferForUpdate(ctx, arg.ID)
+		if err != nil {
+			return err
+		}
+
+		scheduled, err = q.UpdateScheduledTransfer(ctx, arg)
+		if err != nil {
+			return err
+		}
+
+		return audit(ctx, q, AuditChange{ResourceType: "scheduled_transfers", ResourceID: AuditID(scheduled.ID), Before: before, After: scheduled})
+	}); err != nil {
+		return ScheduledTransfer{}, err
+	}
+
+	return scheduled, nil
+}
+
+// CancelScheduledTransferTx stops an active scheduled transfer. It fails with sql.ErrNoRows if it isn't active anymore.
+func (store *SQLStore) CancelScheduledTransferTx(ctx context.Context, id int64) (ScheduledTransfer, error) {
+	var scheduled ScheduledTransfer
+
+	if _, err := store.execTx(ctx, nil, func(q *Queries) error {
+		before, err := q.GetScheduledTransferForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		scheduled, err = q.CancelScheduledTransfer(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		return audit(ctx, q, AuditChange{ResourceType: "scheduled_transfers", ResourceID: AuditID(scheduled.ID), Before: before, After: scheduled})
+	}); err != nil {
+		return ScheduledTransfer{}, err
+	}
+
+	return scheduled, nil
+}
+
+// RunScheduledTransferTxResult is the result of the run scheduled transfer transaction.
+type RunScheduledTransferTxResult struct {
+	// ScheduledTransfer is the scheduled transfer after the run, with its next run time and status.
+	ScheduledTransfer ScheduledTransfer
+	Run               ScheduledTransferRun
+	// Transfer is set when the run succeeded.
+	Transfer *TransferTxResult
+}
+
+// RunScheduledTransferTx claims the scheduled transfer that is due the earliest, performs the transfer,
+// records the outcome of the run and schedules the next one, within a single database transaction.
+// A failed transfer, for instance for insufficient funds, is recorded as a failed run and isn't retried
+// before the next occurrence. One-off transfers are completed after their single run.
+//
+// The scheduled transfer is claimed with FOR UPDATE SKIP LOCKED, so concurrent callers, possibly in different
+// replicas, each claim a different one and every run is performed exactly once.
+// It fails with ErrNoScheduledTransferDue when there is nothing left to claim.
+func (store *SQLStore) RunScheduledTransferTx(ctx context.Context) (RunScheduledTransferTxResult, error) {
+	var result RunScheduledTransferTxResult
+
+	if _, err := store.execTx(ctx, nil, func(q *Queries) error {
+		result = RunScheduledTransferTxResult{}
+
+		scheduled, err := q.ClaimDueScheduledTransfer(ctx)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrNoScheduledTransferDue
+			}
+			return err
+		}
+
+		run := CreateScheduledTransferRunParams{
+			ScheduledTransferID: scheduled.ID,
+			ScheduledAt:         scheduled.NextRunAt,
+		}
+
+		transfer, err := store.runScheduledTransfer(ctx, q, scheduled)
+		switch {
+		case isRetryable(err):
+			return err
+		case err != nil:
+			run.Error = sql.NullString{String: err.Error(), Valid: true}
+		default:
+			run.TransferID = sql.NullInt64{Int64: transfer.Transfer.ID, Valid: true}
+			result.Transfer = &transfer
+		}
+
+		// A schedule that can't be parsed anymore would be claimed again and again: it is completed instead.
+		nextRunAt, status, err := nextRun(scheduled, time.Now())
+		if err != nil {
+			nextRunAt, status = scheduled.NextRunAt, ScheduledTransferStatusCompleted
+			if !run.Error.Valid {
+				run.Error = sql.NullString{String: err.Error(), Valid: true}
+			}
+		}
+
+		result.Run, err = q.CreateScheduledTransferRun(ctx, run)
+		if err != nil {
+			return err
+		}
+
+		result.ScheduledTransfer, err = q.AdvanceScheduledTransfer(ctx, AdvanceScheduledTransferParams{
+			ID:        scheduled.ID,
+			NextRunAt: nextRunAt,
+			Status:    status,
+		})
+
+		return err
+	}); err != nil {
+		return RunScheduledTransferTxResult{}, err
+	}
+
+	return result, nil
+}
+
+// runScheduledTransfer performs the transfer within a savepoint, so that a failed transfer is rolled back
+// without aborting the transaction recording the run.
+func (store *SQLStore) runScheduledTransfer(ctx context.Context, q *Queries, scheduled ScheduledTransfer) (TransferTxResult, error) {
+	if _, err := q.db.ExecContext(ctx, "SAVEPOINT scheduled_transfer"); err != nil {
+		return TransferTxResult{}, err
+	}
+
+	result, err := store.transferTx(ctx, q, TransferTxParams{
+		FromAccountID: sql.NullInt64{Int64: scheduled.FromAccountID, Valid: true},
+		ToAccountID:   sql.NullInt64{Int64: scheduled.ToAccountID, Valid: true},
+		Amount:        scheduled.Amount,
+	})
+	if err != nil {
+		if _, rbErr := q.db.ExecContext(ctx, "ROLLBACK TO SAVEPOINT scheduled_transfer"); rbErr != nil {
+			return TransferTxResult{}, fmt.Errorf("transfer error: %w, rb error: %v", err, rbErr)
+		}
+		return TransferTxResult{}, err
+	}
+
+	_, err = q.db.ExecContext(ctx, "RELEASE SAVEPOINT scheduled_transfer")
+
+	return result, err
+}
+
+// nextRun returns when the scheduled transfer runs next, and its status, after a run at now.
+// Occurrences missed while no scheduler was running are skipped: a late run isn't followed by catch-up runs.
+func nextRun(scheduled ScheduledTransfer, now time.Time) (time.Time, string, error) {
+	if !scheduled.Schedule.Valid {
+		return scheduled.NextRunAt, ScheduledTransferStatusCompleted, nil
+	}
+
+	schedule, err := recurrence.Parse(scheduled.Schedule.String)
+	if err != nil {
+		return time.Time{}, "", fmt.Errorf("scheduled transfer [%d]: %w", scheduled.ID, err)
+	}
+
+	next := scheduled.NextRunAt
+	for !next.After(now) {
+		next = schedule.Next(next)
+		if next.IsZero() {
+			return scheduled.NextRunAt, ScheduledTransferStatusCompleted, nil
+		}
+	}
+
+	return next, ScheduledTransferStatusActive, nil
+}
+
+// RunScheduledTransfersPeriodically runs the due scheduled transfers every interval, until ctx is done.
+// A transfer in progress when ctx is done is finished.
+// It is safe to run in several replicas at once, see RunScheduledTransferTx.
+func RunScheduledTransfersPeriodically(ctx context.Context, store Store, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for ctx.Err() == nil {
+				result, err := store.RunScheduledTransferTx(context.Background())
+				if errors.Is(err, ErrNoScheduledTransferDue) {
+					break
+				}
+				if err != nil {
+					log.Printf("failed to run scheduled transfer: %v", err)
+					break
+				}
+				if result.Run.Error.Valid {
+					log.Printf("scheduled transfer [%d] failed: %s", result.Run.ScheduledTransferID, result.Run.Error.String)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package db
+
+import (
+	"context"
+	"time"
+)
+
+// auditedUser is the audit snapshot of a user. It leaves out the hashed password.
+type auditedUser struct {
+	Username          string
+	FullName          string
+	Email             string
+	Role              string
+	PasswordChangedAt time.Time
+	CreatedAt         time.Time
+}
+
+func newAuditedUser(user User) auditedUser {
+	return auditedUser{
+		Username:          user.Username,
+		FullName:          user.FullName,
+		Email:             user.Email,
+		Role:              user.Role,
+		PasswordChangedAt: user.PasswordChangedAt,
+		CreatedAt:         user.CreatedAt,
+	}
+}
+
+// CreateUserTx creates a user and audits it within a single database transaction.
+func (store *SQLStore) CreateUserTx(ctx context.Context, arg CreateUserParams) (User, error) {
+	var user User
+
+	if _, err := store.execTx(ctx, nil, func(q *Queries) error {
+		var err error
+
+		user, err = q.CreateUser(ctx, arg)
+		if err != nil {
+			return err
+		}
+
+		return audit(ctx, q, AuditChange{ResourceType: "users", ResourceID: user.Username, After: newAuditedUser(user)})
+	}); err != nil {
+		return User{}, err
+	}
+
+	return user, nil
+}
@@ -0,0 +1,105 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// auditedWebhookSubscription is the audit snapshot of a webhook subscription. It leaves out the secret.
+type auditedWebhookSubscription struct {
+	ID         int64
+	Owner      string
+	Url        string
+	EventTypes []string
+	CreatedAt  time.Time
+	DisabledAt sql.NullTime
+}
+
+func newAuditedWebhookSubscription(subscription WebhookSubscription) auditedWebhookSubscription {
+	return auditedWebhookSubscription{
+		ID:         subscription.ID,
+		Owner:      subscription.Owner,
+		Url:        subscription.Url,
+		EventTypes: subscription.EventTypes,
+		CreatedAt:  subscription.CreatedAt,
+		DisabledAt: subscription.DisabledAt,
+	}
+}
+
+// CreateWebhookSubscriptionTx creates a webhook subscription and audits it within a single database transaction.
+func (store *SQLStore) CreateWebhookSubscriptionTx(ctx context.Context, arg CreateWebhookSubscriptionParams) (WebhookSubscription, error) {
+	var subscription WebhookSubscription
+
+	if _, err := store.execTx(ctx, nil, func(q *Queries) error {
+		var err error
+
+		subscription, err = q.CreateWebhookSubscription(ctx, arg)
+		if err != nil {
+			return err
+		}
+
+		return audit(ctx, q, AuditChange{
+			ResourceType: "webhook_subscriptions",
+			ResourceID:   AuditID(subscription.ID),
+			After:        newAuditedWebhookSubscription(subscription),
+		})
+	}); err != nil {
+		return WebhookSubscription{}, err
+	}
+
+	return subscription, nil
+}
+
+// DisableWebhookSubscriptionTx stops the deliveries to a webhook subscription. Its delivery log is kept.
+// It fails with sql.ErrNoRows if the subscription doesn't exist or is already disabled.
+func (store *SQLStore) DisableWebhookSubscriptionTx(ctx context.Context, id int64) (WebhookSubscription, error) {
+	var subscription WebhookSubscription
+
+	if _, err := store.execTx(ctx, nil, func(q *Queries) error {
+		before, err := q.GetWebhookSubscriptionForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		subscription, err = q.DisableWebhookSubscription(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		return audit(ctx, q, AuditChange{
+			ResourceType: "webhook_subscriptions",
+			ResourceID:   AuditID(subscription.ID),
+			Before:       newAuditedWebhookSubscription(before),
+			After:        newAuditedWebhookSubscription(subscription),
+		})
+	}); err != nil {
+		return WebhookSubscription{}, err
+	}
+
+	return subscription, nil
+}
+
+// ReplayWebhookDeliveryTx queues a delivered or dead webhook delivery to be attempted again.
+// It fails with sql.ErrNoRows if the delivery doesn't exist or is still pending.
+func (store *SQLStore) ReplayWebhookDeliveryTx(ctx context.Context, id int64) (WebhookDelivery, error) {
+	var delivery WebhookDelivery
+
+	if _, err := store.execTx(ctx, nil, func(q *Queries) error {
+		before, err := q.GetWebhookDeliveryForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		delivery, err = q.ReplayWebhookDelivery(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		return audit(ctx, q, AuditChange{ResourceType: "webhook_deliveries", ResourceID: AuditID(delivery.ID), Before: before, After: delivery})
+	}); err != nil {
+		return WebhookDelivery{}, err
+	}
+
+	return delivery, nil
+}
@@ -0,0 +1,95 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"tech-school/util"
+)
+
+func createRandomUser(t *testing.T) User {
+	ctx := context.Background()
+
+	hashedPassword, err := util.HashPassword(util.RandomString(6))
+	require.NoError(t, err)
+
+	arg := CreateUserParams{
+		Username:       util.RandomOwner(),
+		HashedPassword: hashedPassword,
+		FullName:       util.RandomOwner(),
+		Email:          util.RandomEmail(),
+	}
+
+	user, err := testQueries.CreateUser(ctx, arg)
+	require.NoError(t, err)
+	require.NotEmpty(t, user)
+
+	require.Equal(t, arg.Username, user.Username)
+	require.Equal(t, arg.HashedPassword, user.HashedPassword)
+	require.Equal(t, arg.FullName, user.FullName)
+	require.Equal(t, arg.Email, user.Email)
+
+	require.True(t, user.PasswordChangedAt.IsZero())
+	require.NotZero(t, user.CreatedAt)
+	require.Equal(t, UserRoleCustomer, user.Role)
+
+	return user
+}
+
+func TestCreateUser(t *testing.T) {
+	createRandomUser(t)
+}
+
+func TestCreateUserTx(t *testing.T) {
+	store := NewStore(testDB)
+
+	call := &AuditCall{Action: "POST /users", StatusCode: 201}
+	ctx := WithAuditCall(context.Background(), call)
+
+	hashedPassword, err := util.HashPassword(util.RandomString(6))
+	require.NoError(t, err)
+
+	user, err := store.CreateUserTx(ctx, CreateUserParams{
+		Username:       util.RandomOwner(),
+		HashedPassword: hashedPassword,
+		FullName:       util.RandomOwner(),
+		Email:          util.RandomEmail(),
+	})
+	require.NoError(t, err)
+	require.True(t, call.Audited())
+
+	entries, err := store.ListAuditLog(context.Background(), ListAuditLogParams{
+		ResourceType: sql.NullString{String: "users", Valid: true},
+		ResourceID:   sql.NullString{String: user.Username, Valid: true},
+		PageSize:     5,
+	})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+
+	// The snapshot leaves out the hashed password.
+	entry := entries[0]
+	require.Equal(t, call.Action, entry.Action)
+	require.Contains(t, string(entry.After), user.Email)
+	require.NotContains(t, string(entry.After), user.HashedPassword)
+}
+
+func TestGetUser(t *testing.T) {
+	ctx := context.Background()
+
+	user1 := createRandomUser(t)
+
+	user2, err := testQueries.GetUser(ctx, user1.Username)
+	require.NoError(t, err)
+	require.NotEmpty(t, user2)
+
+	require.Equal(t, user1.Username, user2.Username)
+	require.Equal(t, user1.HashedPassword, user2.HashedPassword)
+	require.Equal(t, user1.FullName, user2.FullName)
+	require.Equal(t, user1.Email, user2.Email)
+	require.WithinDuration(t, user1.PasswordChangedAt, user2.PasswordChangedAt, time.Second)
+	require.WithinDuration(t, user1.CreatedAt, user2.CreatedAt, time.Second)
+}
@@ -0,0 +1,9 @@
+package db
+
+// Statuses of a webhook delivery.
+const (
+	WebhookDeliveryStatusPending   = "pending"
+	WebhookDeliveryStatusDelivered = "delivered"
+	// WebhookDeliveryStatusDead is the status of the deliveries that failed too many times to be retried.
+	WebhookDeliveryStatusDead = "dead"
+)
@@ -0,0 +1,201 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"tech-school/util"
+)
+
+func subscribeWebhook(t *testing.T, owner string, eventTypes ...string) WebhookSubscription {
+	if eventTypes == nil {
+		eventTypes = []string{}
+	}
+
+	arg := CreateWebhookSubscriptionParams{
+		Owner:      owner,
+		Url:        "https://example.com/hooks/" + util.RandomString(6),
+		Secret:     util.RandomString(32),
+		EventTypes: eventTypes,
+	}
+
+	subscription, err := testQueries.CreateWebhookSubscription(context.Background(), arg)
+	require.NoError(t, err)
+	require.NotZero(t, subscription.ID)
+	require.Equal(t, arg.Owner, subscription.Owner)
+	require.Equal(t, arg.Url, subscription.Url)
+	require.Equal(t, arg.Secret, subscription.Secret)
+	require.Equal(t, arg.EventTypes, subscription.EventTypes)
+	require.False(t, subscription.DisabledAt.Valid)
+
+	return subscription
+}
+
+// listEventDeliveries returns the deliveries of the event of the given type about the given transfer.
+func listEventDeliveries(t *testing.T, eventType string, transferID int64) map[int64]WebhookDelivery {
+	event := getOutboxEvent(t, eventType, transferID)
+
+	rows, err := testDB.QueryContext(context.Background(),
+		"SELECT id, subscription_id, status FROM webhook_deliveries WHERE event_id = $1", event.ID)
+	require.NoError(t, err)
+	defer rows.Close()
+
+	deliveries := map[int64]WebhookDelivery{}
+	for rows.Next() {
+		var delivery WebhookDelivery
+		require.NoError(t, rows.Scan(&delivery.ID, &delivery.SubscriptionID, &delivery.Status))
+		deliveries[delivery.SubscriptionID] = delivery
+	}
+	require.NoError(t, rows.Err())
+
+	return deliveries
+}
+
+func TestWebhookDeliveries(t *testing.T) {
+	ctx := context.Background()
+
+	store := NewStore(testDB)
+
+	account1 := createFundedAccount(t, 100)
+	account2 := createRandomAccount(t)
+	account3 := createRandomAccount(t)
+
+	completed := subscribeWebhook(t, account1.Owner, EventTransferCompleted)
+	all := subscribeWebhook(t, account2.Owner)
+	accountsOnly := subscribeWebhook(t, account2.Owner, EventAccountCreated)
+	bystander := subscribeWebhook(t, account3.Owner)
+
+	disabled := subscribeWebhook(t, account2.Owner)
+	disabled, err := testQueries.DisableWebhookSubscription(ctx, disabled.ID)
+	require.NoError(t, err)
+	require.True(t, disabled.DisabledAt.Valid)
+
+	_, err = testQueries.DisableWebhookSubscription(ctx, disabled.ID)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	result, err := store.TransferTx(ctx, TransferTxParams{
+		FromAccountID: util.SQLNullInt64[int64](account1.ID),
+		ToAccountID:   util.SQLNullInt64[int64](account2.ID),
+		Amount:        10,
+	})
+	require.NoError(t, err)
+
+	// Both owners are notified, through their subscriptions to the event type.
+	deliveries := listEventDeliveries(t, EventTransferCompleted, result.Transfer.ID)
+	require.Len(t, deliveries, 2)
+	require.Contains(t, deliveries, completed.ID)
+	require.Contains(t, deliveries, all.ID)
+	require.NotContains(t, deliveries, accountsOnly.ID)
+	require.NotContains(t, deliveries, bystander.ID)
+	require.NotContains(t, deliveries, disabled.ID)
+	require.Equal(t, WebhookDeliveryStatusPending, deliveries[all.ID].Status)
+
+	reversal, err := store.ReverseTransferTx(ctx, ReverseTransferTxParams{TransferID: result.Transfer.ID})
+	require.NoError(t, err)
+
+	deliveries = listEventDeliveries(t, EventTransferReversed, reversal.Transfer.ID)
+	require.Len(t, deliveries, 1)
+	require.Contains(t, deliveries, all.ID)
+
+	subscriptions, err := testQueries.ListWebhookSubscriptions(ctx, account2.Owner)
+	require.NoError(t, err)
+	require.Len(t, subscriptions, 2)
+	require.Equal(t, all.ID, subscriptions[0].ID)
+	require.Equal(t, accountsOnly.ID, subscriptions[1].ID)
+}
+
+func TestReplayWebhookDelivery(t *testing.T) {
+	ctx := context.Background()
+
+	store := NewStore(testDB)
+
+	account1 := createFundedAccount(t, 100)
+	account2 := createRandomAccount(t)
+	subscription := subscribeWebhook(t, account2.Owner)
+
+	result, err := store.TransferTx(ctx, TransferTxParams{
+		FromAccountID: util.SQLNullInt64[int64](account1.ID),
+		ToAccountID:   util.SQLNullInt64[int64](account2.ID),
+		Amount:        10,
+	})
+	require.NoError(t, err)
+
+	delivery := listEventDeliveries(t, EventTransferCompleted, result.Transfer.ID)[subscription.ID]
+
+	// Pending deliveries aren't replayed.
+	_, err = testQueries.ReplayWebhookDelivery(ctx, delivery.ID)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	err = testQueries.MarkWebhookDeliveryFailed(ctx, MarkWebhookDeliveryFailedParams{
+		ID:             delivery.ID,
+		Status:         WebhookDeliveryStatusDead,
+		ResponseStatus: sql.NullInt32{Int32: 500, Valid: true},
+		LastError:      sql.NullString{String: "responded 500 Internal Server Error", Valid: true},
+		NextAttemptAt:  time.Now(),
+	})
+	require.NoError(t, err)
+
+	delivery, err = testQueries.GetWebhookDelivery(ctx, delivery.ID)
+	require.NoError(t, err)
+	require.Equal(t, WebhookDeliveryStatusDead, delivery.Status)
+	require.Equal(t, int32(1), delivery.Attempts)
+
+	replayed, err := testQueries.ReplayWebhookDelivery(ctx, delivery.ID)
+	require.NoError(t, err)
+	require.Equal(t, WebhookDeliveryStatusPending, replayed.Status)
+	require.Zero(t, replayed.Attempts)
+	require.WithinDuration(t, time.Now(), replayed.NextAttemptAt, time.Second)
+	// The outcome of the last attempt is kept until the next one.
+	require.Equal(t, delivery.LastError, replayed.LastError)
+
+	err = testQueries.MarkWebhookDeliveryDelivered(ctx, MarkWebhookDeliveryDeliveredParams{
+		ID:             delivery.ID,
+		ResponseStatus: sql.NullInt32{Int32: 204, Valid: true},
+	})
+	require.NoError(t, err)
+
+	delivered, err := testQueries.GetWebhookDelivery(ctx, delivery.ID)
+	require.NoError(t, err)
+	require.Equal(t, WebhookDeliveryStatusDelivered, delivered.Status)
+	require.Equal(t, int32(1), delivered.Attempts)
+	require.Equal(t, int32(204), delivered.ResponseStatus.Int32)
+	require.False(t, delivered.LastError.Valid)
+	require.True(t, delivered.DeliveredAt.Valid)
+}
+
+func TestDisableWebhookSubscriptionTx(t *testing.T) {
+	store := NewStore(testDB)
+
+	account := createRandomAccount(t)
+	subscription := subscribeWebhook(t, account.Owner)
+
+	call := &AuditCall{Actor: account.Owner, Action: "DELETE /webhooks/:id", StatusCode: 200}
+	ctx := WithAuditCall(context.Background(), call)
+
+	disabled, err := store.DisableWebhookSubscriptionTx(ctx, subscription.ID)
+	require.NoError(t, err)
+	require.True(t, disabled.DisabledAt.Valid)
+	require.True(t, call.Audited())
+
+	entries, err := store.ListAuditLog(context.Background(), ListAuditLogParams{
+		Actor:    sql.NullString{String: account.Owner, Valid: true},
+		PageSize: 5,
+	})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+
+	// The snapshots leave out the secret.
+	entry := entries[0]
+	require.Equal(t, "webhook_subscriptions", entry.ResourceType)
+	require.JSONEq(t, string(auditSnapshot(newAuditedWebhookSubscription(subscription))), string(entry.Before))
+	require.JSONEq(t, string(auditSnapshot(newAuditedWebhookSubscription(disabled))), string(entry.After))
+	require.NotContains(t, string(entry.After), subscription.Secret)
+
+	// A disabled subscription can't be disabled again.
+	_, err = store.DisableWebhookSubscriptionTx(ctx, subscription.ID)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
@@ -0,0 +1,132 @@
+// Package fees computes the fees charged on transfers.
+package fees
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"os"
+
+	"tech-school/money"
+)
+
+// basisPointsPerUnit is the number of basis points in 100%.
+const basisPointsPerUnit = 10000
+
+// ErrInvalidRule is returned when a fee rule is inconsistent.
+var ErrInvalidRule = errors.New("invalid fee rule")
+
+// Rule describes the fee charged on transfers in one currency.
+// Amounts are in minor units of the currency.
+type Rule struct {
+	Currency string `json:"currency"`
+	// RevenueAccountID is the account, in Currency, credited with the fees.
+	RevenueAccountID int64 `json:"revenue_account_id"`
+	Flat             int64 `json:"flat"`
+	// BasisPoints is the share of the amount charged, in hundredths of a percent.
+	BasisPoints int64 `json:"basis_points"`
+	Min         int64 `json:"min"`
+	// Max caps the fee. Zero means no cap.
+	Max int64 `json:"max"`
+}
+
+// Fee is the breakdown of the fee charged on a transfer, in minor units.
+type Fee struct {
+	Flat       int64 `json:"flat"`
+	Percentage int64 `json:"percentage"`
+	// Total is Flat + Percentage, raised to the rule minimum and capped to its maximum.
+	Total int64 `json:"total"`
+}
+
+func (r Rule) validate() error {
+	if _, err := money.LookupCurrency(r.Currency); err != nil {
+		return err
+	}
+
+	switch {
+	case r.RevenueAccountID <= 0:
+		return fmt.Errorf("%w: %s: revenue account is required", ErrInvalidRule, r.Currency)
+	case r.Flat < 0, r.BasisPoints < 0, r.Min < 0, r.Max < 0:
+		return fmt.Errorf("%w: %s: amounts must not be negative", ErrInvalidRule, r.Currency)
+	case r.BasisPoints > basisPointsPerUnit:
+		return fmt.Errorf("%w: %s: percentage is above 100%%", ErrInvalidRule, r.Currency)
+	case r.Max > 0 && r.Min > r.Max:
+		return fmt.Errorf("%w: %s: min %d is above max %d", ErrInvalidRule, r.Currency, r.Min, r.Max)
+	}
+
+	return nil
+}
+
+// Fee computes the fee charged on a transfer of the given amount.
+// The percentage part is rounded half up to a whole minor unit.
+func (r Rule) Fee(amount int64) (Fee, error) {
+	percentage := new(big.Int).Mul(big.NewInt(amount), big.NewInt(r.BasisPoints))
+	percentage.Add(percentage, big.NewInt(basisPointsPerUnit/2))
+	percentage.Quo(percentage, big.NewInt(basisPointsPerUnit))
+
+	if !percentage.IsInt64() || percentage.Int64() > math.MaxInt64-r.Flat {
+		return Fee{}, fmt.Errorf("%w: fee on %d", money.ErrOverflow, amount)
+	}
+
+	fee := Fee{
+		Flat:       r.Flat,
+		Percentage: percentage.Int64(),
+	}
+
+	fee.Total = fee.Flat + fee.Percentage
+	if fee.Total < r.Min {
+		fee.Total = r.Min
+	}
+	if r.Max > 0 && fee.Total > r.Max {
+		fee.Total = r.Max
+	}
+
+	return fee, nil
+}
+
+// Schedule holds the fee rules of every currency charging fees.
+type Schedule struct {
+	rules map[string]Rule
+}
+
+// NewSchedule creates a Schedule with at most one rule per currency.
+func NewSchedule(rules ...Rule) (*Schedule, error) {
+	s := &Schedule{rules: make(map[string]Rule, len(rules))}
+
+	for _, rule := range rules {
+		if err := rule.validate(); err != nil {
+			return nil, err
+		}
+		if _, ok := s.rules[rule.Currency]; ok {
+			return nil, fmt.Errorf("%w: %s is listed twice", ErrInvalidRule, rule.Currency)
+		}
+		s.rules[rule.Currency] = rule
+	}
+
+	return s, nil
+}
+
+// LoadSchedule reads the rules of a JSON file such as
+//
+//	[{"currency": "USD", "revenue_account_id": 1, "flat": 25, "basis_points": 50, "min": 50, "max": 1000}]
+func LoadSchedule(path string) (*Schedule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var rules []Rule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", path, err)
+	}
+
+	return NewSchedule(rules...)
+}
+
+// Rule returns the rule of the currency. Transfers in currencies without a rule are free.
+func (s *Schedule) Rule(currency string) (Rule, bool) {
+	rule, ok := s.rules[currency]
+	return rule, ok
+}
@@ -0,0 +1,146 @@
+package fees
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"tech-school/money"
+)
+
+func TestRuleFee(t *testing.T) {
+	testCases := []struct {
+		name     string
+		rule     Rule
+		amount   int64
+		expected Fee
+		err      error
+	}{
+		{
+			name:     "Flat",
+			rule:     Rule{Flat: 25},
+			amount:   10000,
+			expected: Fee{Flat: 25, Total: 25},
+		},
+		{
+			// 0.5% of 123.45 = 0.61725, rounded to 0.62
+			name:     "Percentage",
+			rule:     Rule{BasisPoints: 50},
+			amount:   12345,
+			expected: Fee{Percentage: 62, Total: 62},
+		},
+		{
+			// 0.5% of 1.01 = 0.00505, rounded half up to 0.01
+			name:     "PercentageRoundsHalfUp",
+			rule:     Rule{BasisPoints: 50},
+			amount:   101,
+			expected: Fee{Percentage: 1, Total: 1},
+		},
+		{
+			// 0.5% of 0.99 = 0.00495, rounded down to 0
+			name:     "PercentageRoundsDownBelowHalf",
+			rule:     Rule{BasisPoints: 50},
+			amount:   99,
+			expected: Fee{Percentage: 0, Total: 0},
+		},
+		{
+			name:     "FlatAndPercentage",
+			rule:     Rule{Flat: 25, BasisPoints: 100},
+			amount:   10000,
+			expected: Fee{Flat: 25, Percentage: 100, Total: 125},
+		},
+		{
+			name:     "Min",
+			rule:     Rule{BasisPoints: 100, Min: 50},
+			amount:   1000,
+			expected: Fee{Percentage: 10, Total: 50},
+		},
+		{
+			name:     "Max",
+			rule:     Rule{Flat: 25, BasisPoints: 100, Max: 500},
+			amount:   100000,
+			expected: Fee{Flat: 25, Percentage: 1000, Total: 500},
+		},
+		{
+			name:     "NoMax",
+			rule:     Rule{BasisPoints: 100},
+			amount:   100000000,
+			expected: Fee{Percentage: 1000000, Total: 1000000},
+		},
+		{
+			name:   "Overflow",
+			rule:   Rule{Flat: math.MaxInt64, BasisPoints: 100},
+			amount: 100,
+			err:    money.ErrOverflow,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fee, err := tc.rule.Fee(tc.amount)
+			if tc.err != nil {
+				require.ErrorIs(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, fee)
+		})
+	}
+}
+
+func TestNewSchedule(t *testing.T) {
+	usd := Rule{Currency: "USD", RevenueAccountID: 1, Flat: 25}
+	eur := Rule{Currency: "EUR", RevenueAccountID: 2, BasisPoints: 50, Min: 10, Max: 100}
+
+	schedule, err := NewSchedule(usd, eur)
+	require.NoError(t, err)
+
+	rule, ok := schedule.Rule("EUR")
+	require.True(t, ok)
+	require.Equal(t, eur, rule)
+
+	_, ok = schedule.Rule("CAD")
+	require.False(t, ok)
+
+	testCases := []struct {
+		name string
+		rule Rule
+		err  error
+	}{
+		{name: "UnknownCurrency", rule: Rule{Currency: "XXX", RevenueAccountID: 1}, err: money.ErrUnknownCurrency},
+		{name: "NoRevenueAccount", rule: Rule{Currency: "USD"}, err: ErrInvalidRule},
+		{name: "Negative", rule: Rule{Currency: "USD", RevenueAccountID: 1, Flat: -1}, err: ErrInvalidRule},
+		{name: "AboveHundredPercent", rule: Rule{Currency: "USD", RevenueAccountID: 1, BasisPoints: 10001}, err: ErrInvalidRule},
+		{name: "MinAboveMax", rule: Rule{Currency: "USD", RevenueAccountID: 1, Min: 10, Max: 5}, err: ErrInvalidRule},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewSchedule(tc.rule)
+			require.ErrorIs(t, err, tc.err)
+		})
+	}
+
+	_, err = NewSchedule(usd, usd)
+	require.ErrorIs(t, err, ErrInvalidRule)
+}
+
+func TestLoadSchedule(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fees.json")
+	data := `[{"currency": "USD", "revenue_account_id": 1, "flat": 25, "basis_points": 50, "min": 50, "max": 1000}]`
+	require.NoError(t, os.WriteFile(path, []byte(data), 0o600))
+
+	schedule, err := LoadSchedule(path)
+	require.NoError(t, err)
+
+	rule, ok := schedule.Rule("USD")
+	require.True(t, ok)
+	require.Equal(t, Rule{Currency: "USD", RevenueAccountID: 1, Flat: 25, BasisPoints: 50, Min: 50, Max: 1000}, rule)
+
+	require.NoError(t, os.WriteFile(path, []byte(`{`), 0o600))
+	_, err = LoadSchedule(path)
+	require.Error(t, err)
+}
@@ -0,0 +1,115 @@
+package fx
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// ErrRateNotFound is returned when a provider has no rate for a currency pair.
+var ErrRateNotFound = errors.New("exchange rate not found")
+
+// RateProvider looks up exchange rates.
+type RateProvider interface {
+	// Rate returns the price of one unit of from in units of to.
+	Rate(ctx context.Context, from, to string) (Rate, error)
+}
+
+type pair struct {
+	from, to string
+}
+
+type rateTable map[pair]Rate
+
+func (t rateTable) lookup(from, to string) (Rate, error) {
+	if from == to {
+		return identity(from), nil
+	}
+
+	rate, ok := t[pair{from, to}]
+	if !ok {
+		return Rate{}, fmt.Errorf("%w: %s/%s", ErrRateNotFound, from, to)
+	}
+
+	return rate, nil
+}
+
+// MemoryProvider serves rates kept in memory, which can be changed at any time.
+type MemoryProvider struct {
+	mu    sync.RWMutex
+	rates rateTable
+}
+
+// NewMemoryProvider creates a MemoryProvider holding the given rates.
+func NewMemoryProvider(rates ...Rate) *MemoryProvider {
+	p := &MemoryProvider{rates: rateTable{}}
+	p.Set(rates...)
+
+	return p
+}
+
+// Set adds the given rates, replacing the existing rates of the same currency pairs.
+func (p *MemoryProvider) Set(rates ...Rate) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, rate := range rates {
+		p.rates[pair{rate.From, rate.To}] = rate
+	}
+}
+
+// Rate implements RateProvider.
+func (p *MemoryProvider) Rate(_ context.Context, from, to string) (Rate, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return p.rates.lookup(from, to)
+}
+
+// StaticFileProvider serves rates loaded once from a JSON file such as
+//
+//	[{"from": "EUR", "to": "USD", "rate": "1.085"}]
+//
+// Inverse rates aren't derived: each direction must be listed explicitly.
+type StaticFileProvider struct {
+	rates rateTable
+}
+
+// NewStaticFileProvider loads the rates of the given file.
+func NewStaticFileProvider(path string) (*StaticFileProvider, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []struct {
+		From string `json:"from"`
+		To   string `json:"to"`
+		Rate string `json:"rate"`
+	}
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", path, err)
+	}
+
+	p := &StaticFileProvider{rates: rateTable{}}
+	for _, entry := range entries {
+		rate, err := ParseRate(entry.From, entry.To, entry.Rate)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s/%s: %w", path, entry.From, entry.To, err)
+		}
+		if _, ok := p.rates[pair{rate.From, rate.To}]; ok {
+			return nil, fmt.Errorf("%s: %s/%s is listed twice", path, rate.From, rate.To)
+		}
+		p.rates[pair{rate.From, rate.To}] = rate
+	}
+
+	return p, nil
+}
+
+// Rate implements RateProvider.
+func (p *StaticFileProvider) Rate(_ context.Context, from, to string) (Rate, error) {
+	return p.rates.lookup(from, to)
+}
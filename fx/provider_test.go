@@ -0,0 +1,97 @@
+package fx
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryProvider(t *testing.T) {
+	ctx := context.Background()
+
+	eurUSD, err := ParseRate("EUR", "USD", "1.085")
+	require.NoError(t, err)
+
+	provider := NewMemoryProvider(eurUSD)
+
+	rate, err := provider.Rate(ctx, "EUR", "USD")
+	require.NoError(t, err)
+	require.Equal(t, eurUSD, rate)
+
+	_, err = provider.Rate(ctx, "USD", "EUR")
+	require.ErrorIs(t, err, ErrRateNotFound)
+
+	rate, err = provider.Rate(ctx, "USD", "USD")
+	require.NoError(t, err)
+	require.Equal(t, "1.00000000", rate.String())
+
+	eurUSD, err = ParseRate("EUR", "USD", "1.1")
+	require.NoError(t, err)
+	provider.Set(eurUSD)
+
+	rate, err = provider.Rate(ctx, "EUR", "USD")
+	require.NoError(t, err)
+	require.Equal(t, eurUSD, rate)
+}
+
+func TestStaticFileProvider(t *testing.T) {
+	ctx := context.Background()
+
+	testCases := []struct {
+		name  string
+		data  string
+		check func(t *testing.T, provider *StaticFileProvider, err error)
+	}{
+		{
+			name: "OK",
+			data: `[{"from": "EUR", "to": "USD", "rate": "1.085"}, {"from": "USD", "to": "EUR", "rate": "0.92"}]`,
+			check: func(t *testing.T, provider *StaticFileProvider, err error) {
+				require.NoError(t, err)
+
+				rate, err := provider.Rate(ctx, "USD", "EUR")
+				require.NoError(t, err)
+				require.Equal(t, Rate{From: "USD", To: "EUR", Value: 92000000}, rate)
+
+				_, err = provider.Rate(ctx, "EUR", "CAD")
+				require.ErrorIs(t, err, ErrRateNotFound)
+			},
+		},
+		{
+			name: "InvalidRate",
+			data: `[{"from": "EUR", "to": "USD", "rate": "-1"}]`,
+			check: func(t *testing.T, provider *StaticFileProvider, err error) {
+				require.ErrorIs(t, err, ErrInvalidRate)
+			},
+		},
+		{
+			name: "Duplicate",
+			data: `[{"from": "EUR", "to": "USD", "rate": "1"}, {"from": "EUR", "to": "USD", "rate": "2"}]`,
+			check: func(t *testing.T, provider *StaticFileProvider, err error) {
+				require.Error(t, err)
+			},
+		},
+		{
+			name: "InvalidJSON",
+			data: `{`,
+			check: func(t *testing.T, provider *StaticFileProvider, err error) {
+				require.Error(t, err)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "rates.json")
+			require.NoError(t, os.WriteFile(path, []byte(tc.data), 0o600))
+
+			provider, err := NewStaticFileProvider(path)
+			tc.check(t, provider, err)
+		})
+	}
+
+	_, err := NewStaticFileProvider(filepath.Join(t.TempDir(), "missing.json"))
+	require.Error(t, err)
+}
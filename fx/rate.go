@@ -0,0 +1,96 @@
+// Package fx converts amounts between currencies using exchange rates.
+package fx
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"tech-school/money"
+)
+
+// RateScale is the number of decimal places kept in exchange rates.
+const RateScale = 8
+
+var (
+	// ErrInvalidRate is returned when a string isn't a positive rate with at most RateScale decimals.
+	ErrInvalidRate = errors.New("invalid exchange rate")
+	// ErrAmountTooSmall is returned when an amount converts to less than one minor unit.
+	ErrAmountTooSmall = errors.New("converted amount is too small")
+)
+
+// Rate is the price of one unit of the From currency in units of the To currency.
+type Rate struct {
+	From string
+	To   string
+	// Value is the rate scaled by 10^RateScale, e.g. 108500000 for 1.085.
+	Value int64
+}
+
+// ParseRate parses a decimal rate such as "1.085" between two known currencies.
+func ParseRate(from, to, rate string) (Rate, error) {
+	for _, code := range []string{from, to} {
+		if _, err := money.LookupCurrency(code); err != nil {
+			return Rate{}, err
+		}
+	}
+
+	whole, frac, _ := strings.Cut(rate, ".")
+	if whole == "" || len(frac) > RateScale || strings.ContainsAny(rate, "+-") {
+		return Rate{}, fmt.Errorf("%w: %q", ErrInvalidRate, rate)
+	}
+
+	value, err := strconv.ParseInt(whole+frac+strings.Repeat("0", RateScale-len(frac)), 10, 64)
+	if err != nil || value <= 0 {
+		return Rate{}, fmt.Errorf("%w: %q", ErrInvalidRate, rate)
+	}
+
+	return Rate{From: from, To: to, Value: value}, nil
+}
+
+// identity returns the rate of a currency to itself.
+func identity(code string) Rate {
+	return Rate{From: code, To: code, Value: pow10(RateScale).Int64()}
+}
+
+// String formats the rate with RateScale decimals, e.g. "1.08500000".
+func (r Rate) String() string {
+	digits := fmt.Sprintf("%0*d", RateScale+1, r.Value)
+	return digits[:len(digits)-RateScale] + "." + digits[len(digits)-RateScale:]
+}
+
+// Convert returns the amount, in the From currency, expressed in the To currency.
+// The result is rounded toward zero to a whole minor unit of the To currency,
+// so a conversion never credits more than the exact converted value.
+func (r Rate) Convert(amount money.Money) (money.Money, error) {
+	if amount.Currency != r.From {
+		return money.Money{}, fmt.Errorf("%w: %s vs %s", money.ErrCurrencyMismatch, amount.Currency, r.From)
+	}
+
+	from, err := money.LookupCurrency(r.From)
+	if err != nil {
+		return money.Money{}, err
+	}
+	to, err := money.LookupCurrency(r.To)
+	if err != nil {
+		return money.Money{}, err
+	}
+
+	// amount / 10^from.Exponent major units * Value / 10^RateScale, in units of 10^-to.Exponent.
+	num := new(big.Int).Mul(big.NewInt(amount.Amount), big.NewInt(r.Value))
+	num.Mul(num, pow10(to.Exponent))
+	den := new(big.Int).Mul(pow10(RateScale), pow10(from.Exponent))
+
+	converted := num.Quo(num, den)
+	if !converted.IsInt64() {
+		return money.Money{}, fmt.Errorf("%w: %s at %s", money.ErrOverflow, amount, r)
+	}
+
+	return money.Money{Amount: converted.Int64(), Currency: r.To}, nil
+}
+
+func pow10(exponent int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(exponent)), nil)
+}
@@ -0,0 +1,139 @@
+package fx
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"tech-school/money"
+)
+
+func TestParseRate(t *testing.T) {
+	testCases := []struct {
+		name     string
+		rate     string
+		expected int64
+		err      error
+	}{
+		{name: "Decimal", rate: "1.085", expected: 108500000},
+		{name: "Whole", rate: "150", expected: 15000000000},
+		{name: "FullScale", rate: "0.00000001", expected: 1},
+		{name: "TooPrecise", rate: "0.000000001", err: ErrInvalidRate},
+		{name: "Zero", rate: "0", err: ErrInvalidRate},
+		{name: "Negative", rate: "-1.5", err: ErrInvalidRate},
+		{name: "Signed", rate: "+1.5", err: ErrInvalidRate},
+		{name: "MissingWhole", rate: ".5", err: ErrInvalidRate},
+		{name: "NotANumber", rate: "abc", err: ErrInvalidRate},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rate, err := ParseRate("EUR", "USD", tc.rate)
+			if tc.err != nil {
+				require.ErrorIs(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, Rate{From: "EUR", To: "USD", Value: tc.expected}, rate)
+		})
+	}
+
+	_, err := ParseRate("EUR", "XXX", "1")
+	require.ErrorIs(t, err, money.ErrUnknownCurrency)
+}
+
+func TestRateString(t *testing.T) {
+	require.Equal(t, "1.08500000", Rate{Value: 108500000}.String())
+	require.Equal(t, "0.00000001", Rate{Value: 1}.String())
+	require.Equal(t, "150.00000000", Rate{Value: 15000000000}.String())
+}
+
+// TestConvert documents the rounding rules: the converted amount is rounded toward zero
+// to a whole minor unit of the destination currency, taking the exponent of both currencies into account.
+func TestConvert(t *testing.T) {
+	testCases := []struct {
+		name     string
+		from, to string
+		rate     string
+		amount   int64
+		expected int64
+		err      error
+	}{
+		{
+			name: "Exact",
+			from: "EUR", to: "USD", rate: "1.1",
+			amount: 1000, expected: 1100,
+		},
+		{
+			// 12.34 EUR * 1.085 = 13.3889 USD
+			name: "RoundsDown",
+			from: "EUR", to: "USD", rate: "1.085",
+			amount: 1234, expected: 1338,
+		},
+		{
+			// 0.01 EUR * 1.999 = 0.01999 USD
+			name: "RoundsDownAboveHalf",
+			from: "EUR", to: "USD", rate: "1.999",
+			amount: 1, expected: 1,
+		},
+		{
+			// 0.01 USD * 0.9 = 0.009 EUR
+			name: "BelowOneMinorUnit",
+			from: "USD", to: "EUR", rate: "0.9",
+			amount: 1, expected: 0,
+		},
+		{
+			// 1.99 USD * 150.5 = 299.495 JPY
+			name: "ToZeroExponent",
+			from: "USD", to: "JPY", rate: "150.5",
+			amount: 199, expected: 299,
+		},
+		{
+			// 1000 JPY * 0.00664 = 6.64 USD
+			name: "FromZeroExponent",
+			from: "JPY", to: "USD", rate: "0.00664",
+			amount: 1000, expected: 664,
+		},
+		{
+			// 1.00 USD * 0.3075 = 0.3075 KWD
+			name: "ToThreeDecimals",
+			from: "USD", to: "KWD", rate: "0.3075",
+			amount: 100, expected: 307,
+		},
+		{
+			// -12.34 EUR * 1.085 = -13.3889 USD
+			name: "NegativeRoundsTowardZero",
+			from: "EUR", to: "USD", rate: "1.085",
+			amount: -1234, expected: -1338,
+		},
+		{
+			name: "Overflow",
+			from: "EUR", to: "JPY", rate: "160",
+			amount: math.MaxInt64, err: money.ErrOverflow,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rate, err := ParseRate(tc.from, tc.to, tc.rate)
+			require.NoError(t, err)
+
+			converted, err := rate.Convert(money.Money{Amount: tc.amount, Currency: tc.from})
+			if tc.err != nil {
+				require.ErrorIs(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, money.Money{Amount: tc.expected, Currency: tc.to}, converted)
+		})
+	}
+}
+
+func TestConvertCurrencyMismatch(t *testing.T) {
+	rate, err := ParseRate("EUR", "USD", "1.085")
+	require.NoError(t, err)
+
+	_, err = rate.Convert(money.Money{Amount: 100, Currency: "USD"})
+	require.ErrorIs(t, err, money.ErrCurrencyMismatch)
+}
@@ -0,0 +1,96 @@
+package gapi
+
+import (
+	"context"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	db "tech-school/db/sqlc"
+	"tech-school/pb"
+)
+
+func (s *Server) CreateAccount(ctx context.Context, req *pb.CreateAccountRequest) (*pb.CreateAccountResponse, error) {
+	if err := validateCurrency("currency", req.GetCurrency()); err != nil {
+		return nil, err
+	}
+
+	arg := db.CreateAccountTxParams{
+		CreateAccountParams: db.CreateAccountParams{
+			Owner:    authPayload(ctx).Username,
+			Currency: req.GetCurrency(),
+			Balance:  0,
+		},
+	}
+
+	account, err := s.store.CreateAccountTx(ctx, arg)
+	if err != nil {
+		return nil, dbError(err)
+	}
+
+	return &pb.CreateAccountResponse{Account: convertAccount(account)}, nil
+}
+
+func (s *Server) GetAccount(ctx context.Context, req *pb.GetAccountRequest) (*pb.GetAccountResponse, error) {
+	if err := validateField("id", req.GetId(), "min=1"); err != nil {
+		return nil, err
+	}
+
+	account, err := s.ownedAccount(ctx, req.GetId())
+	if err != nil {
+		return nil, err
+	}
+
+	held, err := s.store.GetAccountHeldAmount(ctx, account.ID)
+	if err != nil {
+		return nil, dbError(err)
+	}
+
+	rsp := convertAccount(account)
+	rsp.AvailableBalance = convertMoney(account.Balance-held, account.Currency)
+
+	return &pb.GetAccountResponse{Account: rsp}, nil
+}
+
+func (s *Server) ListAccounts(ctx context.Context, req *pb.ListAccountsRequest) (*pb.ListAccountsResponse, error) {
+	for _, err := range []error{
+		validateField("page_id", req.GetPageId(), "min=1"),
+		validateField("page_size", req.GetPageSize(), "min=5,max=100"),
+	} {
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	arg := db.ListAccountsByOwnerParams{
+		Owner:  authPayload(ctx).Username,
+		Limit:  req.GetPageSize(),
+		Offset: (req.GetPageId() - 1) * req.GetPageSize(),
+	}
+
+	accounts, err := s.store.ListAccountsByOwner(ctx, arg)
+	if err != nil {
+		return nil, dbError(err)
+	}
+
+	rsp := &pb.ListAccountsResponse{Accounts: make([]*pb.Account, len(accounts))}
+	for i, account := range accounts {
+		rsp.Accounts[i] = convertAccount(account)
+	}
+
+	return rsp, nil
+}
+
+// ownedAccount loads the account and checks that it belongs to the authenticated user.
+func (s *Server) ownedAccount(ctx context.Context, accountID int64) (db.Account, error) {
+	account, err := s.store.GetAccount(ctx, accountID)
+	if err != nil {
+		return account, dbError(err)
+	}
+
+	if account.Owner != authPayload(ctx).Username {
+		return account, status.Error(codes.PermissionDenied, "account doesn't belong to the authenticated user")
+	}
+
+	return account, nil
+}
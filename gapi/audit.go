@@ -0,0 +1,171 @@
+package gapi
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	db "tech-school/db/sqlc"
+)
+
+const (
+	requestIDMetadataKey    = "x-request-id"
+	forwardedForMetadataKey = "x-forwarded-for"
+)
+
+// auditInterceptor audits every call, except to the Get and List methods, whatever its outcome, as auditMiddleware
+// does for the gin API: the store transactions append the changes they commit to the audit log themselves,
+// which is why the methods only change resources with them. The calls that changed nothing are appended once
+// handled, with the HTTP equivalent of their gRPC code as status. The request ID is taken from the x-request-id
+// metadata, or generated, and sent back in the header.
+func auditInterceptor(store db.Store, trustedProxies []*net.IPNet) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		service, method := splitFullMethod(info.FullMethod)
+		if strings.HasPrefix(method, "Get") || strings.HasPrefix(method, "List") {
+			return handler(ctx, req)
+		}
+
+		md, _ := metadata.FromIncomingContext(ctx)
+
+		requestID := db.AuditRequestID(firstMetadataValue(md, requestIDMetadataKey))
+		_ = grpc.SetHeader(ctx, metadata.Pairs(requestIDMetadataKey, requestID))
+
+		call := &db.AuditCall{
+			Action:       info.FullMethod,
+			ResourceType: auditResourceType(service),
+			RequestID:    requestID,
+			ClientIP:     clientIP(ctx, md, trustedProxies),
+			StatusCode:   http.StatusOK,
+		}
+
+		rsp, err := handler(db.WithAuditCall(ctx, call), req)
+
+		if call.Audited() {
+			return rsp, err
+		}
+
+		// Nothing was changed: the entry is only written on a best-effort basis, even if the client
+		// has gone away meanwhile.
+		arg := call.Entry(int32(runtime.HTTPStatusFromCode(status.Code(err))), nil)
+		if _, auditErr := store.CreateAuditLogTx(context.Background(), arg); auditErr != nil {
+			log.Printf("failed to write the audit log of %s call [%s]: %v", arg.Action, arg.RequestID, auditErr)
+		}
+
+		return rsp, err
+	}
+}
+
+// splitFullMethod splits "/pb.TransferService/CreateTransfer" into "pb.TransferService" and "CreateTransfer".
+func splitFullMethod(fullMethod string) (string, string) {
+	service, method, _ := strings.Cut(strings.TrimPrefix(fullMethod, "/"), "/")
+
+	return service, method
+}
+
+// auditResourceType names the resource of a service after it, such as transfers for pb.TransferService.
+func auditResourceType(service string) string {
+	if i := strings.LastIndex(service, "."); i >= 0 {
+		service = service[i+1:]
+	}
+
+	return strings.ToLower(strings.TrimSuffix(service, "Service")) + "s"
+}
+
+// clientIP returns the address of the client. The x-forwarded-for metadata, as set by the gateway, is only
+// believed when the peer is a trusted proxy: the client is then the right-most hop that isn't trusted.
+// Otherwise, or if the metadata is malformed, the client is the peer itself.
+func clientIP(ctx context.Context, md metadata.MD, trustedProxies []*net.IPNet) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+
+	peerIP, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		peerIP = p.Addr.String()
+	}
+
+	if !isTrustedProxy(net.ParseIP(peerIP), trustedProxies) {
+		return peerIP
+	}
+
+	// Each proxy appends the address it received the call from, possibly in another value of the metadata.
+	var hops []string
+	for _, value := range md.Get(forwardedForMetadataKey) {
+		hops = append(hops, strings.Split(value, ",")...)
+	}
+
+	for i := len(hops) - 1; i >= 0; i-- {
+		ip := net.ParseIP(strings.TrimSpace(hops[i]))
+		if ip == nil {
+			return peerIP
+		}
+
+		if i == 0 || !isTrustedProxy(ip, trustedProxies) {
+			return ip.String()
+		}
+	}
+
+	return peerIP
+}
+
+func isTrustedProxy(ip net.IP, trustedProxies []*net.IPNet) bool {
+	if ip == nil {
+		return false
+	}
+
+	for _, network := range trustedProxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parseTrustedProxies parses the addresses, or networks in CIDR notation, of the trusted proxies.
+func parseTrustedProxies(proxies []string) ([]*net.IPNet, error) {
+	networks := make([]*net.IPNet, 0, len(proxies))
+
+	for _, proxy := range proxies {
+		if !strings.Contains(proxy, "/") {
+			ip := net.ParseIP(proxy)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q", proxy)
+			}
+
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+
+		_, network, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", proxy, err)
+		}
+		networks = append(networks, network)
+	}
+
+	return networks, nil
+}
+
+func firstMetadataValue(md metadata.MD, key string) string {
+	values := md.Get(key)
+	if len(values) == 0 {
+		return ""
+	}
+
+	return values[0]
+}
@@ -0,0 +1,80 @@
+package gapi
+
+import (
+	"context"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	db "tech-school/db/sqlc"
+	"tech-school/pb"
+	"tech-school/token"
+)
+
+const (
+	authorizationMetadataKey = "authorization"
+	authorizationTypeBearer  = "bearer"
+)
+
+// publicMethods don't require an access token.
+var publicMethods = map[string]bool{
+	pb.UserService_CreateUser_FullMethodName: true,
+	pb.UserService_LoginUser_FullMethodName:  true,
+}
+
+type authPayloadKey struct{}
+
+// authInterceptor verifies the bearer token in the authorization metadata of the calls to non-public methods,
+// and stores its payload in the context.
+func authInterceptor(tokenMaker token.Maker) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if publicMethods[info.FullMethod] {
+			return handler(ctx, req)
+		}
+
+		payload, err := verifyAuthorization(ctx, tokenMaker)
+		if err != nil {
+			return nil, err
+		}
+
+		if call, ok := db.AuditCallFromContext(ctx); ok {
+			call.Actor = payload.Username
+		}
+
+		return handler(context.WithValue(ctx, authPayloadKey{}, payload), req)
+	}
+}
+
+func verifyAuthorization(ctx context.Context, tokenMaker token.Maker) (*token.Payload, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	values := md.Get(authorizationMetadataKey)
+	if len(values) == 0 {
+		return nil, status.Error(codes.Unauthenticated, "authorization metadata is not provided")
+	}
+
+	fields := strings.Fields(values[0])
+	if len(fields) != 2 {
+		return nil, status.Error(codes.Unauthenticated, "invalid authorization metadata format")
+	}
+
+	authorizationType := strings.ToLower(fields[0])
+	if authorizationType != authorizationTypeBearer {
+		return nil, status.Errorf(codes.Unauthenticated, "unsupported authorization type %s", authorizationType)
+	}
+
+	payload, err := tokenMaker.VerifyToken(fields[1])
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+
+	return payload, nil
+}
+
+// authPayload returns the payload of the access token of the call. It must only be called by the non-public methods.
+func authPayload(ctx context.Context) *token.Payload {
+	return ctx.Value(authPayloadKey{}).(*token.Payload)
+}
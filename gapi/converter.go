@@ -0,0 +1,77 @@
+package gapi
+
+import (
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	db "tech-school/db/sqlc"
+	"tech-school/money"
+	"tech-school/pb"
+)
+
+func convertUser(user db.User) *pb.User {
+	return &pb.User{
+		Username:          user.Username,
+		FullName:          user.FullName,
+		Email:             user.Email,
+		PasswordChangedAt: timestamppb.New(user.PasswordChangedAt),
+		CreatedAt:         timestamppb.New(user.CreatedAt),
+	}
+}
+
+// convertMoney formats an amount of minor units in major units, as the gin API does, see money.Money.MarshalJSON.
+func convertMoney(amount int64, currency string) *pb.Money {
+	return &pb.Money{Amount: money.Money{Amount: amount, Currency: currency}.Decimal(), Currency: currency}
+}
+
+func convertAccount(account db.Account) *pb.Account {
+	return &pb.Account{
+		Id:        account.ID,
+		Owner:     account.Owner,
+		Balance:   convertMoney(account.Balance, account.Currency),
+		Status:    account.Status,
+		CreatedAt: timestamppb.New(account.CreatedAt),
+	}
+}
+
+func convertEntry(entry db.Entry, currency string) *pb.Entry {
+	return &pb.Entry{
+		Id:        entry.ID,
+		AccountId: entry.AccountID.Int64,
+		Amount:    convertMoney(entry.Amount, currency),
+		CreatedAt: timestamppb.New(entry.CreatedAt),
+	}
+}
+
+func convertTransferResult(result db.TransferTxResult) *pb.TransferResult {
+	rsp := &pb.TransferResult{
+		Transfer: &pb.Transfer{
+			Id:                 result.Transfer.ID,
+			FromAccountId:      result.Transfer.FromAccountID.Int64,
+			ToAccountId:        result.Transfer.ToAccountID.Int64,
+			Amount:             convertMoney(result.Transfer.Amount, result.FromAccount.Currency),
+			ReversedTransferId: result.Transfer.ReversedTransferID.Int64,
+			ExchangeRate:       result.Transfer.ExchangeRate.String,
+			CreatedAt:          timestamppb.New(result.Transfer.CreatedAt),
+		},
+		FromAccount: convertAccount(result.FromAccount),
+		ToAccount:   convertAccount(result.ToAccount),
+		FromEntry:   convertEntry(result.FromEntry, result.FromAccount.Currency),
+		ToEntry:     convertEntry(result.ToEntry, result.ToAccount.Currency),
+	}
+
+	if result.Transfer.ToAmount.Valid {
+		rsp.Transfer.ToAmount = convertMoney(result.Transfer.ToAmount.Int64, result.ToAccount.Currency)
+	}
+
+	if fee := result.Fee; fee != nil {
+		currency := result.FromAccount.Currency
+		rsp.Fee = &pb.Fee{
+			TransferId: fee.Transfer.ID,
+			Flat:       convertMoney(fee.Flat, currency),
+			Percentage: convertMoney(fee.Percentage, currency),
+			Total:      convertMoney(fee.Total, currency),
+		}
+	}
+
+	return rsp
+}
@@ -0,0 +1,46 @@
+package gapi
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/lib/pq"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	db "tech-school/db/sqlc"
+)
+
+const (
+	uniqueViolation     = "unique_violation"
+	foreignKeyViolation = "foreign_key_violation"
+)
+
+// dbError maps missing rows, postgres constraint violations, and transactions refused because an account
+// is frozen or closed, to gRPC status errors, as dbErrorStatus does to HTTP status codes for the gin API.
+// Any other error is reported as an internal error.
+func dbError(err error) error {
+	return status.Error(dbErrorCode(err), err.Error())
+}
+
+func dbErrorCode(err error) codes.Code {
+	if errors.Is(err, sql.ErrNoRows) {
+		return codes.NotFound
+	}
+
+	if errors.Is(err, db.ErrAccountFrozen) || errors.Is(err, db.ErrAccountClosed) {
+		return codes.FailedPrecondition
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code.Name() {
+		case uniqueViolation:
+			return codes.AlreadyExists
+		case foreignKeyViolation:
+			return codes.PermissionDenied
+		}
+	}
+
+	return codes.Internal
+}
@@ -0,0 +1,95 @@
+package gapi
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	db "tech-school/db/sqlc"
+)
+
+const (
+	// idempotencyKeyMetadataKey is the metadata the gateway forwards the Idempotency-Key header as.
+	idempotencyKeyMetadataKey = "idempotency-key"
+	maxIdempotencyKeyLength   = 255
+)
+
+// idempotencyParams builds the idempotency parameters of the call from the idempotency-key metadata,
+// as the gin API does from the Idempotency-Key header. It returns nil params when the metadata is absent.
+func idempotencyParams(ctx context.Context, username, fullMethod string, req proto.Message) (*db.IdempotencyParams, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	key := firstMetadataValue(md, idempotencyKeyMetadataKey)
+	if key == "" {
+		return nil, nil
+	}
+
+	if len(key) > maxIdempotencyKeyLength {
+		return nil, status.Error(codes.InvalidArgument, "invalid idempotency key: too long")
+	}
+
+	hash, err := requestHash(req)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to hash the request: %v", err)
+	}
+
+	return &db.IdempotencyParams{
+		Key:            key,
+		Username:       username,
+		RequestPath:    fullMethod,
+		RequestHash:    hash,
+		ResponseStatus: http.StatusOK,
+	}, nil
+}
+
+// requestHash returns the SHA-256 hex digest of the request, encoded deterministically.
+func requestHash(req proto.Message) (string, error) {
+	data, err := proto.MarshalOptions{Deterministic: true}.Marshal(req)
+	if err != nil {
+		return "", err
+	}
+
+	hash := sha256.Sum256(data)
+
+	return hex.EncodeToString(hash[:]), nil
+}
+
+// storedResult decodes the store result saved with the idempotency key by a previous call, so that the call
+// answers it again. It returns nil if there is none. A key reused with a different request fails
+// with FailedPrecondition.
+func storedResult[T any](ctx context.Context, store db.Store, params *db.IdempotencyParams) (*T, error) {
+	if params == nil {
+		return nil, nil
+	}
+
+	stored, err := store.GetIdempotencyKey(ctx, db.GetIdempotencyKeyParams{
+		Username: params.Username,
+		Key:      params.Key,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	if stored.RequestPath != params.RequestPath || stored.RequestHash != params.RequestHash {
+		return nil, status.Error(codes.FailedPrecondition, "idempotency key has already been used with a different request")
+	}
+
+	var result T
+	if err := json.Unmarshal(stored.ResponseBody, &result); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to decode the stored response: %v", err)
+	}
+
+	return &result, nil
+}
@@ -0,0 +1,192 @@
+package gapi
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	mockdb "tech-school/db/mock"
+	db "tech-school/db/sqlc"
+	"tech-school/internal/testutil"
+	"tech-school/pb"
+	"tech-school/util"
+)
+
+func TestCreateTransferIdempotencyRPC(t *testing.T) {
+	user, _ := testutil.RandomUser(t)
+
+	account1 := testutil.RandomAccount(user.Username)
+	account2 := testutil.RandomAccount(user.Username)
+	account2.ID = account1.ID + 1
+
+	key := util.RandomString(16)
+	req := &pb.CreateTransferRequest{
+		FromAccountId: account1.ID,
+		ToAccountId:   account2.ID,
+		Amount:        convertMoney(10, "USD"),
+	}
+
+	hash, err := requestHash(req)
+	require.NoError(t, err)
+
+	storedResult := db.TransferTxResult{
+		FromAccount: account1,
+		ToAccount:   account2,
+	}
+	storedBody, err := json.Marshal(storedResult)
+	require.NoError(t, err)
+
+	storedKey := func(requestHash string) db.IdempotencyKey {
+		return db.IdempotencyKey{
+			Key:            key,
+			Username:       user.Username,
+			RequestPath:    pb.TransferService_CreateTransfer_FullMethodName,
+			RequestHash:    requestHash,
+			ResponseStatus: http.StatusOK,
+			ResponseBody:   storedBody,
+		}
+	}
+
+	testCases := []struct {
+		name          string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, rsp *pb.TransferResult, err error)
+	}{
+		{
+			name: "FirstCall",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetIdempotencyKey(gomock.Any(), gomock.Eq(db.GetIdempotencyKeyParams{Username: user.Username, Key: key})).
+					Times(1).
+					Return(db.IdempotencyKey{}, sql.ErrNoRows)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(1).Return(account2, nil)
+
+				arg := db.TransferTxParams{
+					FromAccountID: util.SQLNullInt64(account1.ID),
+					ToAccountID:   util.SQLNullInt64(account2.ID),
+					Amount:        10,
+					Idempotency: &db.IdempotencyParams{
+						Key:            key,
+						Username:       user.Username,
+						RequestPath:    pb.TransferService_CreateTransfer_FullMethodName,
+						RequestHash:    hash,
+						ResponseStatus: http.StatusOK,
+					},
+				}
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Eq(arg)).Times(1).Return(storedResult, nil)
+			},
+			checkResponse: func(t *testing.T, rsp *pb.TransferResult, err error) {
+				require.NoError(t, err)
+				require.True(t, proto.Equal(convertTransferResult(storedResult), rsp))
+			},
+		},
+		{
+			name: "Replay",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetIdempotencyKey(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(storedKey(hash), nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rsp *pb.TransferResult, err error) {
+				require.NoError(t, err)
+				require.True(t, proto.Equal(convertTransferResult(storedResult), rsp))
+			},
+		},
+		{
+			name: "DifferentRequest",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetIdempotencyKey(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(storedKey("another-hash"), nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rsp *pb.TransferResult, err error) {
+				require.Equal(t, codes.FailedPrecondition, status.Code(err))
+			},
+		},
+		{
+			name: "ConcurrentDuplicate",
+			buildStubs: func(store *mockdb.MockStore) {
+				gomock.InOrder(
+					store.EXPECT().
+						GetIdempotencyKey(gomock.Any(), gomock.Any()).
+						Times(1).
+						Return(db.IdempotencyKey{}, sql.ErrNoRows),
+					store.EXPECT().
+						GetIdempotencyKey(gomock.Any(), gomock.Any()).
+						Times(1).
+						Return(storedKey(hash), nil),
+				)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(1).Return(account2, nil)
+				store.EXPECT().
+					TransferTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.TransferTxResult{}, db.ErrDuplicateIdempotencyKey)
+			},
+			checkResponse: func(t *testing.T, rsp *pb.TransferResult, err error) {
+				require.NoError(t, err)
+				require.True(t, proto.Equal(convertTransferResult(storedResult), rsp))
+			},
+		},
+		{
+			name: "InternalError",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetIdempotencyKey(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.IdempotencyKey{}, sql.ErrConnDone)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rsp *pb.TransferResult, err error) {
+				require.Equal(t, codes.Internal, status.Code(err))
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+
+			ctx := metadata.NewIncomingContext(withAuthorization(t, user.Username), metadata.Pairs(idempotencyKeyMetadataKey, key))
+
+			rsp, err := server.CreateTransfer(ctx, req)
+			tc.checkResponse(t, rsp, err)
+		})
+	}
+}
+
+func TestIdempotencyKeyTooLong(t *testing.T) {
+	ctx := metadata.NewIncomingContext(context.Background(),
+		metadata.Pairs(idempotencyKeyMetadataKey, util.RandomString(maxIdempotencyKeyLength+1)))
+
+	_, err := idempotencyParams(ctx, util.RandomOwner(), pb.TransferService_CreateTransfer_FullMethodName, &pb.CreateTransferRequest{})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestGatewayHeaderMatcher(t *testing.T) {
+	key, ok := gatewayHeaderMatcher("Idempotency-Key")
+	require.True(t, ok)
+	require.Equal(t, idempotencyKeyMetadataKey, key)
+}
@@ -0,0 +1,41 @@
+package gapi
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+
+	mockdb "tech-school/db/mock"
+	db "tech-school/db/sqlc"
+	"tech-school/token"
+	"tech-school/util"
+)
+
+func newTestServer(t *testing.T, store db.Store) *Server {
+	config := util.Config{
+		TokenSymmetricKey:   util.RandomString(32),
+		AccessTokenDuration: time.Minute,
+		Currencies:          util.DefaultCurrencies,
+	}
+
+	// Every mutating call is audited: tests that don't check the audit log let it be written.
+	if mockStore, ok := store.(*mockdb.MockStore); ok {
+		mockStore.EXPECT().CreateAuditLogTx(gomock.Any(), gomock.Any()).AnyTimes()
+	}
+
+	server, err := NewServer(config, store)
+	require.NoError(t, err)
+
+	return server
+}
+
+// withAuthorization returns a context authenticated as the given user, as authInterceptor would.
+func withAuthorization(t *testing.T, username string) context.Context {
+	payload, err := token.NewPayload(username, time.Minute)
+	require.NoError(t, err)
+
+	return context.WithValue(context.Background(), authPayloadKey{}, payload)
+}
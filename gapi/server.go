@@ -0,0 +1,127 @@
+// Package gapi serves the accounts, transfers and users of the bank over gRPC, and over HTTP with grpc-gateway.
+// It is backed by the same store as the gin API, see package api.
+package gapi
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	db "tech-school/db/sqlc"
+	"tech-school/fx"
+	"tech-school/pb"
+	"tech-school/token"
+	"tech-school/util"
+)
+
+// Server implements the gRPC services.
+type Server struct {
+	pb.UnimplementedAccountServiceServer
+	pb.UnimplementedTransferServiceServer
+	pb.UnimplementedUserServiceServer
+
+	config     util.Config
+	store      db.Store
+	tokenMaker token.Maker
+	rates      fx.RateProvider
+	// trustedProxies are the networks of the proxies, such as the gateway, whose x-forwarded-for metadata is believed.
+	trustedProxies []*net.IPNet
+}
+
+func NewServer(config util.Config, store db.Store) (*Server, error) {
+	tokenMaker, err := token.NewMaker(config.TokenType, config.TokenSymmetricKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create token maker: %w", err)
+	}
+
+	if err := util.SetSupportedCurrencies(config.Currencies...); err != nil {
+		return nil, fmt.Errorf("failed to load supported currencies: %w", err)
+	}
+
+	trustedProxies, err := parseTrustedProxies(config.TrustedProxies)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Server{
+		config:         config,
+		store:          store,
+		tokenMaker:     tokenMaker,
+		trustedProxies: trustedProxies,
+	}
+
+	// Cross-currency transfers are only enabled when exchange rates are configured.
+	if config.FXRatesFile != "" {
+		rates, err := fx.NewStaticFileProvider(config.FXRatesFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load exchange rates: %w", err)
+		}
+		s.rates = rates
+	}
+
+	return s, nil
+}
+
+// GRPCServer returns a gRPC server serving the services, with the calls authenticated and audited.
+func (s *Server) GRPCServer() *grpc.Server {
+	grpcServer := grpc.NewServer(
+		// The audit interceptor goes first, so that rejected calls are audited too.
+		grpc.ChainUnaryInterceptor(auditInterceptor(s.store, s.trustedProxies), authInterceptor(s.tokenMaker)),
+	)
+
+	pb.RegisterAccountServiceServer(grpcServer, s)
+	pb.RegisterTransferServiceServer(grpcServer, s)
+	pb.RegisterUserServiceServer(grpcServer, s)
+
+	return grpcServer
+}
+
+// NewGateway returns an HTTP handler translating the routes annotated in the protobuf definitions
+// into calls to the gRPC server listening on grpcAddress. JSON fields keep their protobuf names.
+func NewGateway(ctx context.Context, grpcAddress string) (http.Handler, error) {
+	mux := runtime.NewServeMux(
+		runtime.WithIncomingHeaderMatcher(gatewayHeaderMatcher),
+		runtime.WithMarshalerOption(runtime.MIMEWildcard, &runtime.JSONPb{
+			MarshalOptions: protojson.MarshalOptions{
+				UseProtoNames:   true,
+				EmitUnpopulated: true,
+			},
+			UnmarshalOptions: protojson.UnmarshalOptions{
+				DiscardUnknown: true,
+			},
+		}),
+	)
+
+	opts := []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}
+
+	for _, register := range []func(context.Context, *runtime.ServeMux, string, []grpc.DialOption) error{
+		pb.RegisterAccountServiceHandlerFromEndpoint,
+		pb.RegisterTransferServiceHandlerFromEndpoint,
+		pb.RegisterUserServiceHandlerFromEndpoint,
+	} {
+		if err := register(ctx, mux, grpcAddress, opts); err != nil {
+			return nil, fmt.Errorf("failed to register the gateway handlers: %w", err)
+		}
+	}
+
+	return mux, nil
+}
+
+// gatewayHeaderMatcher forwards the request ID and Idempotency-Key headers to the gRPC server, on top of the headers
+// forwarded by default.
+func gatewayHeaderMatcher(key string) (string, bool) {
+	for _, forwarded := range []string{requestIDMetadataKey, idempotencyKeyMetadataKey} {
+		if strings.EqualFold(key, forwarded) {
+			return forwarded, true
+		}
+	}
+
+	return runtime.DefaultHeaderMatcher(key)
+}
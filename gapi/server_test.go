@@ -0,0 +1,493 @@
+package gapi
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	mockdb "tech-school/db/mock"
+	db "tech-school/db/sqlc"
+	"tech-school/internal/testutil"
+	"tech-school/pb"
+	"tech-school/token"
+)
+
+// startTestGRPCServer serves the services of a test server on a local port, until the test ends.
+// It returns the server and its address.
+func startTestGRPCServer(t *testing.T, store db.Store) (*Server, string) {
+	server := newTestServer(t, store)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	grpcServer := server.GRPCServer()
+	go func() {
+		_ = grpcServer.Serve(listener)
+	}()
+	t.Cleanup(grpcServer.Stop)
+
+	return server, listener.Addr().String()
+}
+
+func dialTestGRPCServer(t *testing.T, address string) *grpc.ClientConn {
+	conn, err := grpc.Dial(address, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	return conn
+}
+
+func withAuthorizationMetadata(t *testing.T, tokenMaker token.Maker, authorizationType, username string, duration time.Duration) context.Context {
+	accessToken, _, err := tokenMaker.CreateToken(username, duration)
+	require.NoError(t, err)
+
+	return metadata.AppendToOutgoingContext(context.Background(),
+		authorizationMetadataKey, fmt.Sprintf("%s %s", authorizationType, accessToken))
+}
+
+func TestAuthInterceptor(t *testing.T) {
+	user, _ := testutil.RandomUser(t)
+
+	testCases := []struct {
+		name       string
+		ctx        func(t *testing.T, tokenMaker token.Maker) context.Context
+		buildStubs func(store *mockdb.MockStore)
+		checkCode  codes.Code
+	}{
+		{
+			name: "OK",
+			ctx: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return withAuthorizationMetadata(t, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListAccountsByOwner(gomock.Any(), gomock.Eq(db.ListAccountsByOwnerParams{Owner: user.Username, Limit: 5})).
+					Times(1).
+					Return([]db.Account{}, nil)
+			},
+			checkCode: codes.OK,
+		},
+		{
+			name: "NoAuthorization",
+			ctx: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return context.Background()
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListAccountsByOwner(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkCode: codes.Unauthenticated,
+		},
+		{
+			name: "UnsupportedAuthorization",
+			ctx: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return withAuthorizationMetadata(t, tokenMaker, "unsupported", user.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListAccountsByOwner(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkCode: codes.Unauthenticated,
+		},
+		{
+			name: "InvalidAuthorizationFormat",
+			ctx: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return withAuthorizationMetadata(t, tokenMaker, "", user.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListAccountsByOwner(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkCode: codes.Unauthenticated,
+		},
+		{
+			name: "ExpiredToken",
+			ctx: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return withAuthorizationMetadata(t, tokenMaker, authorizationTypeBearer, user.Username, -time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListAccountsByOwner(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkCode: codes.Unauthenticated,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server, address := startTestGRPCServer(t, store)
+			client := pb.NewAccountServiceClient(dialTestGRPCServer(t, address))
+
+			_, err := client.ListAccounts(tc.ctx(t, server.tokenMaker), &pb.ListAccountsRequest{PageId: 1, PageSize: 5})
+			require.Equal(t, tc.checkCode, status.Code(err))
+		})
+	}
+}
+
+func TestAuthInterceptorPublicMethod(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(1).Return(db.User{}, sql.ErrNoRows)
+
+	_, address := startTestGRPCServer(t, store)
+	client := pb.NewUserServiceClient(dialTestGRPCServer(t, address))
+
+	// The call reaches the handler without an access token.
+	_, err := client.LoginUser(context.Background(), &pb.LoginUserRequest{Username: "someone", Password: "secret"})
+	require.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestAuditInterceptor(t *testing.T) {
+	user, password := testutil.RandomUser(t)
+	account := testutil.RandomAccount(user.Username)
+
+	testCases := []struct {
+		name       string
+		call       func(t *testing.T, conn *grpc.ClientConn, tokenMaker token.Maker) (metadata.MD, error)
+		buildStubs func(store *mockdb.MockStore)
+		auditErr   error
+		checkAudit func(t *testing.T, header metadata.MD, err error, audited []db.CreateAuditLogTxParams)
+	}{
+		{
+			name: "OK",
+			call: func(t *testing.T, conn *grpc.ClientConn, tokenMaker token.Maker) (metadata.MD, error) {
+				ctx := withAuthorizationMetadata(t, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+				ctx = metadata.AppendToOutgoingContext(ctx, requestIDMetadataKey, "request-1")
+
+				var header metadata.MD
+				_, err := pb.NewAccountServiceClient(conn).CreateAccount(ctx, &pb.CreateAccountRequest{Currency: "USD"}, grpc.Header(&header))
+				return header, err
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				// The store transaction audits the change on behalf of the call, see db.AuditCall.
+				store.EXPECT().
+					CreateAccountTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(ctx context.Context, _ db.CreateAccountTxParams) (db.Account, error) {
+						call, ok := db.AuditCallFromContext(ctx)
+						if !ok || call.Actor != user.Username || call.RequestID != "request-1" {
+							return db.Account{}, status.Error(codes.Internal, "unexpected audit call")
+						}
+						return account, nil
+					})
+			},
+			checkAudit: func(t *testing.T, header metadata.MD, err error, audited []db.CreateAuditLogTxParams) {
+				require.NoError(t, err)
+				require.Equal(t, []string{"request-1"}, header.Get(requestIDMetadataKey))
+
+				require.Len(t, audited, 1)
+				arg := audited[0]
+				require.Equal(t, sql.NullString{String: user.Username, Valid: true}, arg.Actor)
+				require.Equal(t, pb.AccountService_CreateAccount_FullMethodName, arg.Action)
+				require.Equal(t, "accounts", arg.ResourceType)
+				require.Equal(t, "request-1", arg.RequestID)
+				require.Equal(t, "127.0.0.1", arg.ClientIp)
+				require.Equal(t, db.AuditOutcomeSuccess, arg.Outcome)
+				require.Equal(t, int32(http.StatusOK), arg.StatusCode)
+			},
+		},
+		{
+			name: "Anonymous",
+			call: func(t *testing.T, conn *grpc.ClientConn, tokenMaker token.Maker) (metadata.MD, error) {
+				_, err := pb.NewUserServiceClient(conn).CreateUser(context.Background(), &pb.CreateUserRequest{
+					Username: user.Username,
+					Password: password,
+					FullName: user.FullName,
+					Email:    user.Email,
+				})
+				return nil, err
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateUserTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(ctx context.Context, _ db.CreateUserParams) (db.User, error) {
+						call, ok := db.AuditCallFromContext(ctx)
+						if !ok || call.Actor != "" {
+							return db.User{}, status.Error(codes.Internal, "unexpected audit call")
+						}
+						return user, nil
+					})
+			},
+			checkAudit: func(t *testing.T, header metadata.MD, err error, audited []db.CreateAuditLogTxParams) {
+				require.NoError(t, err)
+
+				require.Len(t, audited, 1)
+				arg := audited[0]
+				require.False(t, arg.Actor.Valid)
+				require.Equal(t, pb.UserService_CreateUser_FullMethodName, arg.Action)
+				require.Equal(t, "users", arg.ResourceType)
+				require.Equal(t, db.AuditOutcomeSuccess, arg.Outcome)
+			},
+		},
+		{
+			name: "AuditError",
+			call: func(t *testing.T, conn *grpc.ClientConn, tokenMaker token.Maker) (metadata.MD, error) {
+				ctx := withAuthorizationMetadata(t, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+				_, err := pb.NewAccountServiceClient(conn).CreateAccount(ctx, &pb.CreateAccountRequest{Currency: "USD"})
+				return nil, err
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateAccountTx(gomock.Any(), gomock.Any()).Times(1).Return(account, nil)
+			},
+			auditErr: sql.ErrConnDone,
+			checkAudit: func(t *testing.T, header metadata.MD, err error, audited []db.CreateAuditLogTxParams) {
+				// The entry of a call that changed nothing in a store transaction is only written on
+				// a best-effort basis.
+				require.NoError(t, err)
+			},
+		},
+		{
+			name: "Unauthenticated",
+			call: func(t *testing.T, conn *grpc.ClientConn, tokenMaker token.Maker) (metadata.MD, error) {
+				var header metadata.MD
+				_, err := pb.NewTransferServiceClient(conn).ReverseTransfer(context.Background(), &pb.ReverseTransferRequest{Id: 1}, grpc.Header(&header))
+				return header, err
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+			},
+			checkAudit: func(t *testing.T, header metadata.MD, err error, audited []db.CreateAuditLogTxParams) {
+				require.Equal(t, codes.Unauthenticated, status.Code(err))
+
+				require.Len(t, audited, 1)
+				arg := audited[0]
+				require.False(t, arg.Actor.Valid)
+				require.Equal(t, pb.TransferService_ReverseTransfer_FullMethodName, arg.Action)
+				require.Equal(t, "transfers", arg.ResourceType)
+				require.Equal(t, header.Get(requestIDMetadataKey), []string{arg.RequestID})
+				require.Equal(t, db.AuditOutcomeFailure, arg.Outcome)
+				require.Equal(t, int32(http.StatusUnauthorized), arg.StatusCode)
+			},
+		},
+		{
+			name: "NotMutating",
+			call: func(t *testing.T, conn *grpc.ClientConn, tokenMaker token.Maker) (metadata.MD, error) {
+				ctx := withAuthorizationMetadata(t, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+				_, err := pb.NewAccountServiceClient(conn).GetAccount(ctx, &pb.GetAccountRequest{Id: account.ID})
+				return nil, err
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().GetAccountHeldAmount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(int64(0), nil)
+			},
+			checkAudit: func(t *testing.T, header metadata.MD, err error, audited []db.CreateAuditLogTxParams) {
+				require.NoError(t, err)
+				require.Empty(t, audited)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			var (
+				mu      sync.Mutex
+				audited []db.CreateAuditLogTxParams
+			)
+
+			store := mockdb.NewMockStore(ctrl)
+			store.EXPECT().
+				CreateAuditLogTx(gomock.Any(), gomock.Any()).
+				AnyTimes().
+				DoAndReturn(func(_ context.Context, arg db.CreateAuditLogTxParams) (db.AuditLog, error) {
+					if tc.auditErr != nil {
+						return db.AuditLog{}, tc.auditErr
+					}
+					mu.Lock()
+					defer mu.Unlock()
+					audited = append(audited, arg)
+					return db.AuditLog{}, nil
+				})
+			tc.buildStubs(store)
+
+			server, address := startTestGRPCServer(t, store)
+
+			header, err := tc.call(t, dialTestGRPCServer(t, address), server.tokenMaker)
+
+			mu.Lock()
+			defer mu.Unlock()
+			tc.checkAudit(t, header, err, audited)
+		})
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	trustedProxies, err := parseTrustedProxies([]string{"127.0.0.1", "10.0.0.0/8"})
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name         string
+		peer         string
+		forwardedFor []string
+		clientIP     string
+	}{
+		{
+			name:     "NoForwardedFor",
+			peer:     "127.0.0.1:1234",
+			clientIP: "127.0.0.1",
+		},
+		{
+			name:         "TrustedPeer",
+			peer:         "127.0.0.1:1234",
+			forwardedFor: []string{"203.0.113.7"},
+			clientIP:     "203.0.113.7",
+		},
+		{
+			name:         "UntrustedPeer",
+			peer:         "198.51.100.1:1234",
+			forwardedFor: []string{"203.0.113.7"},
+			clientIP:     "198.51.100.1",
+		},
+		{
+			// The left-most hops are given by the client, and can't be believed.
+			name:         "SpoofedHops",
+			peer:         "127.0.0.1:1234",
+			forwardedFor: []string{"192.0.2.1, 203.0.113.7, 10.0.0.2"},
+			clientIP:     "203.0.113.7",
+		},
+		{
+			name:         "SeveralValues",
+			peer:         "127.0.0.1:1234",
+			forwardedFor: []string{"192.0.2.1, 203.0.113.7", "10.0.0.2"},
+			clientIP:     "203.0.113.7",
+		},
+		{
+			name:         "AllTrusted",
+			peer:         "127.0.0.1:1234",
+			forwardedFor: []string{"10.0.0.3, 10.0.0.2"},
+			clientIP:     "10.0.0.3",
+		},
+		{
+			name:         "Malformed",
+			peer:         "127.0.0.1:1234",
+			forwardedFor: []string{"203.0.113.7, unknown"},
+			clientIP:     "127.0.0.1",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			addr, err := net.ResolveTCPAddr("tcp", tc.peer)
+			require.NoError(t, err)
+
+			ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: addr})
+
+			md := metadata.MD{}
+			for _, value := range tc.forwardedFor {
+				md.Append(forwardedForMetadataKey, value)
+			}
+
+			require.Equal(t, tc.clientIP, clientIP(ctx, md, trustedProxies))
+		})
+	}
+}
+
+func TestParseTrustedProxies(t *testing.T) {
+	networks, err := parseTrustedProxies([]string{"127.0.0.1", "::1", "10.0.0.0/8"})
+	require.NoError(t, err)
+	require.Len(t, networks, 3)
+	require.True(t, networks[0].Contains(net.ParseIP("127.0.0.1")))
+	require.False(t, networks[0].Contains(net.ParseIP("127.0.0.2")))
+	require.True(t, networks[1].Contains(net.ParseIP("::1")))
+	require.True(t, networks[2].Contains(net.ParseIP("10.1.2.3")))
+
+	_, err = parseTrustedProxies([]string{"localhost"})
+	require.Error(t, err)
+
+	_, err = parseTrustedProxies([]string{"10.0.0.0/33"})
+	require.Error(t, err)
+}
+
+func TestGateway(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	user, password := testutil.RandomUser(t)
+	account := testutil.RandomAccount(user.Username)
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().CreateUserTx(gomock.Any(), gomock.Any()).Times(1).Return(user, nil)
+	store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+	store.EXPECT().GetAccountHeldAmount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(int64(0), nil)
+
+	server, address := startTestGRPCServer(t, store)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	gateway, err := NewGateway(ctx, address)
+	require.NoError(t, err)
+
+	// The routes are the ones annotated in the protobuf definitions.
+	body, err := json.Marshal(map[string]string{
+		"username":  user.Username,
+		"password":  password,
+		"full_name": user.FullName,
+		"email":     user.Email,
+	})
+	require.NoError(t, err)
+
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest(http.MethodPost, "/v1/users", bytes.NewReader(body))
+	require.NoError(t, err)
+
+	gateway.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var created struct {
+		User struct {
+			Username string `json:"username"`
+			FullName string `json:"full_name"`
+		} `json:"user"`
+	}
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &created))
+	require.Equal(t, user.Username, created.User.Username)
+	require.Equal(t, user.FullName, created.User.FullName)
+
+	// The authorization header is forwarded to the gRPC server.
+	recorder = httptest.NewRecorder()
+	request, err = http.NewRequest(http.MethodGet, fmt.Sprintf("/v1/accounts/%d", account.ID), nil)
+	require.NoError(t, err)
+
+	gateway.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusUnauthorized, recorder.Code)
+
+	accessToken, _, err := server.tokenMaker.CreateToken(user.Username, time.Minute)
+	require.NoError(t, err)
+
+	recorder = httptest.NewRecorder()
+	request, err = http.NewRequest(http.MethodGet, fmt.Sprintf("/v1/accounts/%d", account.ID), nil)
+	require.NoError(t, err)
+	request.Header.Set("Authorization", "Bearer "+accessToken)
+
+	gateway.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	data, err := io.ReadAll(recorder.Body)
+	require.NoError(t, err)
+	require.Contains(t, string(data), `"owner":"`+user.Username+`"`)
+}
@@ -0,0 +1,165 @@
+package gapi
+
+import (
+	"context"
+	"errors"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	db "tech-school/db/sqlc"
+	"tech-school/fx"
+	"tech-school/pb"
+	"tech-school/util"
+)
+
+func (s *Server) CreateTransfer(ctx context.Context, req *pb.CreateTransferRequest) (*pb.TransferResult, error) {
+	for _, err := range []error{
+		validateField("from_account_id", req.GetFromAccountId(), "min=1"),
+		validateField("to_account_id", req.GetToAccountId(), "min=1"),
+	} {
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	amount, err := parseAmount("amount", req.GetAmount())
+	if err != nil {
+		return nil, err
+	}
+
+	if req.GetToAccountId() == req.GetFromAccountId() {
+		return nil, status.Error(codes.InvalidArgument, "invalid to_account_id: same as from_account_id")
+	}
+
+	username := authPayload(ctx).Username
+
+	idempotency, err := idempotencyParams(ctx, username, pb.TransferService_CreateTransfer_FullMethodName, req)
+	if err != nil {
+		return nil, err
+	}
+
+	stored, err := storedResult[db.TransferTxResult](ctx, s.store, idempotency)
+	if err != nil {
+		return nil, err
+	}
+	if stored != nil {
+		return convertTransferResult(*stored), nil
+	}
+
+	currency := amount.Currency
+
+	fromAccount, err := s.validAccount(ctx, req.GetFromAccountId(), currency)
+	if err != nil {
+		return nil, err
+	}
+
+	if fromAccount.Owner != username {
+		return nil, status.Error(codes.PermissionDenied, "from account doesn't belong to the authenticated user")
+	}
+
+	// Without exchange rates, both accounts must be in the currency of the amount.
+	var toAccount db.Account
+	if s.rates == nil {
+		toAccount, err = s.validAccount(ctx, req.GetToAccountId(), currency)
+	} else {
+		toAccount, err = s.store.GetAccount(ctx, req.GetToAccountId())
+		if err != nil {
+			err = dbError(err)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	arg := db.TransferTxParams{
+		FromAccountID: util.SQLNullInt64(req.GetFromAccountId()),
+		ToAccountID:   util.SQLNullInt64(req.GetToAccountId()),
+		Amount:        amount.Amount,
+		Idempotency:   idempotency,
+	}
+
+	var result db.TransferTxResult
+	if toAccount.Currency == fromAccount.Currency {
+		result, err = s.store.TransferTx(ctx, arg)
+	} else {
+		rate, rateErr := s.rates.Rate(ctx, fromAccount.Currency, toAccount.Currency)
+		if rateErr != nil {
+			if errors.Is(rateErr, fx.ErrRateNotFound) {
+				return nil, status.Error(codes.FailedPrecondition, rateErr.Error())
+			}
+			return nil, status.Error(codes.Internal, rateErr.Error())
+		}
+		result, err = s.store.CrossCurrencyTransferTx(ctx, db.CrossCurrencyTransferTxParams{
+			TransferTxParams: arg,
+			Rate:             rate,
+		})
+	}
+	if err != nil {
+		if errors.Is(err, db.ErrInsufficientFunds) || errors.Is(err, fx.ErrAmountTooSmall) {
+			return nil, status.Error(codes.FailedPrecondition, err.Error())
+		}
+		// A concurrent call with the same idempotency key won the race.
+		if errors.Is(err, db.ErrDuplicateIdempotencyKey) {
+			stored, storedErr := storedResult[db.TransferTxResult](ctx, s.store, idempotency)
+			if storedErr != nil {
+				return nil, storedErr
+			}
+			if stored != nil {
+				return convertTransferResult(*stored), nil
+			}
+		}
+		return nil, dbError(err)
+	}
+
+	return convertTransferResult(result), nil
+}
+
+func (s *Server) ReverseTransfer(ctx context.Context, req *pb.ReverseTransferRequest) (*pb.TransferResult, error) {
+	if err := validateField("id", req.GetId(), "min=1"); err != nil {
+		return nil, err
+	}
+
+	transfer, err := s.store.GetTransfer(ctx, req.GetId())
+	if err != nil {
+		return nil, dbError(err)
+	}
+
+	toAccount, err := s.store.GetAccount(ctx, transfer.ToAccountID.Int64)
+	if err != nil {
+		return nil, dbError(err)
+	}
+
+	if toAccount.Owner != authPayload(ctx).Username {
+		return nil, status.Error(codes.PermissionDenied, "transfer wasn't received by an account of the authenticated user")
+	}
+
+	result, err := s.store.ReverseTransferTx(ctx, db.ReverseTransferTxParams{TransferID: req.GetId()})
+	if err != nil {
+		switch {
+		case errors.Is(err, db.ErrTransferAlreadyReversed):
+			return nil, status.Error(codes.AlreadyExists, err.Error())
+		case errors.Is(err, db.ErrInsufficientFunds),
+			errors.Is(err, db.ErrReversalNotReversible),
+			errors.Is(err, db.ErrFeeNotReversible):
+			return nil, status.Error(codes.FailedPrecondition, err.Error())
+		}
+		return nil, dbError(err)
+	}
+
+	return convertTransferResult(result), nil
+}
+
+// validAccount loads the account and checks that its currency matches the given one.
+func (s *Server) validAccount(ctx context.Context, accountID int64, currency string) (db.Account, error) {
+	account, err := s.store.GetAccount(ctx, accountID)
+	if err != nil {
+		return account, dbError(err)
+	}
+
+	if account.Currency != currency {
+		return account, status.Errorf(codes.InvalidArgument, "account [%d] currency mismatch: %s vs %s", account.ID, account.Currency, currency)
+	}
+
+	return account, nil
+}
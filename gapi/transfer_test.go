@@ -0,0 +1,306 @@
+package gapi
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	mockdb "tech-school/db/mock"
+	db "tech-school/db/sqlc"
+	"tech-school/internal/testutil"
+	"tech-school/pb"
+	"tech-school/util"
+)
+
+func TestCreateTransferRPC(t *testing.T) {
+	amount := int64(10)
+
+	user1, _ := testutil.RandomUser(t)
+	user2, _ := testutil.RandomUser(t)
+
+	account1 := testutil.RandomAccount(user1.Username)
+	account2 := testutil.RandomAccount(user2.Username)
+	account2.ID = account1.ID + 1
+	account3 := testutil.RandomAccount(user1.Username)
+	account3.ID = account1.ID + 2
+	account3.Currency = "EUR"
+
+	usd := func(amount int64) *pb.Money {
+		return convertMoney(amount, "USD")
+	}
+
+	testCases := []struct {
+		name          string
+		username      string
+		req           *pb.CreateTransferRequest
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, rsp *pb.TransferResult, err error)
+	}{
+		{
+			name:     "OK",
+			username: user1.Username,
+			req:      &pb.CreateTransferRequest{FromAccountId: account1.ID, ToAccountId: account2.ID, Amount: usd(amount)},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(1).Return(account2, nil)
+
+				arg := db.TransferTxParams{
+					FromAccountID: util.SQLNullInt64(account1.ID),
+					ToAccountID:   util.SQLNullInt64(account2.ID),
+					Amount:        amount,
+				}
+				result := db.TransferTxResult{
+					Transfer: db.Transfer{
+						ID:            util.RandomInt(1, 1000),
+						FromAccountID: arg.FromAccountID,
+						ToAccountID:   arg.ToAccountID,
+						Amount:        amount,
+					},
+					FromAccount: account1,
+					ToAccount:   account2,
+				}
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Eq(arg)).Times(1).Return(result, nil)
+			},
+			checkResponse: func(t *testing.T, rsp *pb.TransferResult, err error) {
+				require.NoError(t, err)
+				require.Equal(t, account1.ID, rsp.GetTransfer().GetFromAccountId())
+				require.Equal(t, account2.ID, rsp.GetTransfer().GetToAccountId())
+				require.Equal(t, usd(amount), rsp.GetTransfer().GetAmount())
+				require.Nil(t, rsp.GetTransfer().GetToAmount())
+				require.Nil(t, rsp.GetFee())
+			},
+		},
+		{
+			name:     "NotOwner",
+			username: user2.Username,
+			req:      &pb.CreateTransferRequest{FromAccountId: account1.ID, ToAccountId: account2.ID, Amount: usd(amount)},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(0)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rsp *pb.TransferResult, err error) {
+				require.Equal(t, codes.PermissionDenied, status.Code(err))
+			},
+		},
+		{
+			name:     "CurrencyMismatch",
+			username: user1.Username,
+			req:      &pb.CreateTransferRequest{FromAccountId: account1.ID, ToAccountId: account3.ID, Amount: usd(amount)},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account3.ID)).Times(1).Return(account3, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rsp *pb.TransferResult, err error) {
+				require.Equal(t, codes.InvalidArgument, status.Code(err))
+			},
+		},
+		{
+			name:     "FromAccountNotFound",
+			username: user1.Username,
+			req:      &pb.CreateTransferRequest{FromAccountId: account1.ID, ToAccountId: account2.ID, Amount: usd(amount)},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(db.Account{}, sql.ErrNoRows)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rsp *pb.TransferResult, err error) {
+				require.Equal(t, codes.NotFound, status.Code(err))
+			},
+		},
+		{
+			name:     "InsufficientFunds",
+			username: user1.Username,
+			req:      &pb.CreateTransferRequest{FromAccountId: account1.ID, ToAccountId: account2.ID, Amount: usd(amount)},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(1).Return(account2, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(1).Return(db.TransferTxResult{}, db.ErrInsufficientFunds)
+			},
+			checkResponse: func(t *testing.T, rsp *pb.TransferResult, err error) {
+				require.Equal(t, codes.FailedPrecondition, status.Code(err))
+			},
+		},
+		{
+			name:     "FrozenAccount",
+			username: user1.Username,
+			req:      &pb.CreateTransferRequest{FromAccountId: account1.ID, ToAccountId: account2.ID, Amount: usd(amount)},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(1).Return(account2, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(1).Return(db.TransferTxResult{}, db.ErrAccountFrozen)
+			},
+			checkResponse: func(t *testing.T, rsp *pb.TransferResult, err error) {
+				require.Equal(t, codes.FailedPrecondition, status.Code(err))
+			},
+		},
+		{
+			name:     "SameAccount",
+			username: user1.Username,
+			req:      &pb.CreateTransferRequest{FromAccountId: account1.ID, ToAccountId: account1.ID, Amount: usd(amount)},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rsp *pb.TransferResult, err error) {
+				require.Equal(t, codes.InvalidArgument, status.Code(err))
+			},
+		},
+		{
+			name:     "NegativeAmount",
+			username: user1.Username,
+			req:      &pb.CreateTransferRequest{FromAccountId: account1.ID, ToAccountId: account2.ID, Amount: usd(-amount)},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rsp *pb.TransferResult, err error) {
+				require.Equal(t, codes.InvalidArgument, status.Code(err))
+			},
+		},
+		{
+			name:     "InvalidAmountDecimals",
+			username: user1.Username,
+			req: &pb.CreateTransferRequest{
+				FromAccountId: account1.ID,
+				ToAccountId:   account2.ID,
+				Amount:        &pb.Money{Amount: "1.234", Currency: "USD"},
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rsp *pb.TransferResult, err error) {
+				require.Equal(t, codes.InvalidArgument, status.Code(err))
+			},
+		},
+		{
+			name:     "MissingAmount",
+			username: user1.Username,
+			req:      &pb.CreateTransferRequest{FromAccountId: account1.ID, ToAccountId: account2.ID},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rsp *pb.TransferResult, err error) {
+				require.Equal(t, codes.InvalidArgument, status.Code(err))
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+
+			rsp, err := server.CreateTransfer(withAuthorization(t, tc.username), tc.req)
+			tc.checkResponse(t, rsp, err)
+		})
+	}
+}
+
+func TestReverseTransferRPC(t *testing.T) {
+	user1, _ := testutil.RandomUser(t)
+	user2, _ := testutil.RandomUser(t)
+
+	account1 := testutil.RandomAccount(user1.Username)
+	account2 := testutil.RandomAccount(user2.Username)
+	account2.ID = account1.ID + 1
+
+	transfer := db.Transfer{
+		ID:            util.RandomInt(1, 1000),
+		FromAccountID: util.SQLNullInt64(account1.ID),
+		ToAccountID:   util.SQLNullInt64(account2.ID),
+		Amount:        10,
+	}
+
+	testCases := []struct {
+		name          string
+		username      string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, rsp *pb.TransferResult, err error)
+	}{
+		{
+			name:     "OK",
+			username: user2.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTransfer(gomock.Any(), gomock.Eq(transfer.ID)).Times(1).Return(transfer, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(1).Return(account2, nil)
+
+				result := db.TransferTxResult{
+					Transfer: db.Transfer{
+						ID:                 transfer.ID + 1,
+						FromAccountID:      transfer.ToAccountID,
+						ToAccountID:        transfer.FromAccountID,
+						Amount:             transfer.Amount,
+						ReversedTransferID: util.SQLNullInt64(transfer.ID),
+					},
+					FromAccount: account2,
+					ToAccount:   account1,
+				}
+				arg := db.ReverseTransferTxParams{TransferID: transfer.ID}
+				store.EXPECT().ReverseTransferTx(gomock.Any(), gomock.Eq(arg)).Times(1).Return(result, nil)
+			},
+			checkResponse: func(t *testing.T, rsp *pb.TransferResult, err error) {
+				require.NoError(t, err)
+				require.Equal(t, transfer.ID, rsp.GetTransfer().GetReversedTransferId())
+				require.Equal(t, account2.ID, rsp.GetTransfer().GetFromAccountId())
+			},
+		},
+		{
+			name:     "NotReceiver",
+			username: user1.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTransfer(gomock.Any(), gomock.Eq(transfer.ID)).Times(1).Return(transfer, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(1).Return(account2, nil)
+				store.EXPECT().ReverseTransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rsp *pb.TransferResult, err error) {
+				require.Equal(t, codes.PermissionDenied, status.Code(err))
+			},
+		},
+		{
+			name:     "AlreadyReversed",
+			username: user2.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTransfer(gomock.Any(), gomock.Eq(transfer.ID)).Times(1).Return(transfer, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(1).Return(account2, nil)
+				store.EXPECT().ReverseTransferTx(gomock.Any(), gomock.Any()).Times(1).Return(db.TransferTxResult{}, db.ErrTransferAlreadyReversed)
+			},
+			checkResponse: func(t *testing.T, rsp *pb.TransferResult, err error) {
+				require.Equal(t, codes.AlreadyExists, status.Code(err))
+			},
+		},
+		{
+			name:     "TransferNotFound",
+			username: user2.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTransfer(gomock.Any(), gomock.Eq(transfer.ID)).Times(1).Return(db.Transfer{}, sql.ErrNoRows)
+				store.EXPECT().ReverseTransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rsp *pb.TransferResult, err error) {
+				require.Equal(t, codes.NotFound, status.Code(err))
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+
+			rsp, err := server.ReverseTransfer(withAuthorization(t, tc.username), &pb.ReverseTransferRequest{Id: transfer.ID})
+			tc.checkResponse(t, rsp, err)
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package gapi
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	db "tech-school/db/sqlc"
+	"tech-school/pb"
+	"tech-school/util"
+)
+
+func (s *Server) CreateUser(ctx context.Context, req *pb.CreateUserRequest) (*pb.CreateUserResponse, error) {
+	for _, err := range []error{
+		validateField("username", req.GetUsername(), "required,alphanum"),
+		validateField("password", req.GetPassword(), "required,min=6,max=72"),
+		validateField("full_name", req.GetFullName(), "required"),
+		validateField("email", req.GetEmail(), "required,email"),
+	} {
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	hashedPassword, err := util.HashPassword(req.GetPassword())
+	if err != nil {
+		// The validation counts characters: a multibyte password can still be too long for bcrypt.
+		if errors.Is(err, util.ErrPasswordTooLong) {
+			return nil, status.Error(codes.InvalidArgument, "invalid password: longer than 72 bytes")
+		}
+		return nil, status.Errorf(codes.Internal, "failed to hash the password: %v", err)
+	}
+
+	arg := db.CreateUserParams{
+		Username:       req.GetUsername(),
+		HashedPassword: hashedPassword,
+		FullName:       req.GetFullName(),
+		Email:          req.GetEmail(),
+	}
+
+	user, err := s.store.CreateUserTx(ctx, arg)
+	if err != nil {
+		return nil, dbError(err)
+	}
+
+	return &pb.CreateUserResponse{User: convertUser(user)}, nil
+}
+
+func (s *Server) LoginUser(ctx context.Context, req *pb.LoginUserRequest) (*pb.LoginUserResponse, error) {
+	for _, err := range []error{
+		validateField("username", req.GetUsername(), "required,alphanum"),
+		validateField("password", req.GetPassword(), "required,min=6,max=72"),
+	} {
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	user, err := s.store.GetUser(ctx, req.GetUsername())
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, status.Error(codes.NotFound, "user not found")
+		}
+		return nil, dbError(err)
+	}
+
+	if err := util.CheckPassword(req.GetPassword(), user.HashedPassword); err != nil {
+		return nil, status.Error(codes.Unauthenticated, "incorrect password")
+	}
+
+	accessToken, accessPayload, err := s.tokenMaker.CreateToken(user.Username, s.config.AccessTokenDuration)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to create the access token: %v", err)
+	}
+
+	return &pb.LoginUserResponse{
+		AccessToken:          accessToken,
+		AccessTokenExpiresAt: timestamppb.New(accessPayload.ExpiredAt),
+		User:                 convertUser(user),
+	}, nil
+}
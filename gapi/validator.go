@@ -0,0 +1,62 @@
+package gapi
+
+import (
+	"errors"
+
+	"github.com/go-playground/validator/v10"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"tech-school/money"
+	"tech-school/pb"
+	"tech-school/util"
+)
+
+var validate = validator.New()
+
+// validateField checks a request field against validator tags, with the same rules as the binding tags of the gin API.
+// It returns an InvalidArgument error naming the field and the first rule it breaks.
+func validateField(name string, value interface{}, tag string) error {
+	err := validate.Var(value, tag)
+	if err == nil {
+		return nil
+	}
+
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) && len(validationErrs) > 0 {
+		return status.Errorf(codes.InvalidArgument, "invalid %s: failed on the %q rule", name, validationErrs[0].Tag())
+	}
+
+	return status.Errorf(codes.InvalidArgument, "invalid %s: %v", name, err)
+}
+
+// validateCurrency checks that the currency is one of the currencies enabled in the config.
+func validateCurrency(name, currency string) error {
+	if !util.IsSupportedCurrency(currency) {
+		return status.Errorf(codes.InvalidArgument, "invalid %s: unsupported currency %q", name, currency)
+	}
+
+	return nil
+}
+
+// parseAmount parses a positive amount, in one of the currencies enabled in the config, into minor units.
+func parseAmount(name string, amount *pb.Money) (money.Money, error) {
+	if amount == nil {
+		return money.Money{}, status.Errorf(codes.InvalidArgument, "invalid %s: missing", name)
+	}
+
+	if err := validateCurrency(name, amount.GetCurrency()); err != nil {
+		return money.Money{}, err
+	}
+
+	parsed, err := money.ParseDecimal(amount.GetAmount(), amount.GetCurrency())
+	if err != nil {
+		return money.Money{}, status.Errorf(codes.InvalidArgument, "invalid %s: %v", name, err)
+	}
+
+	if !parsed.IsPositive() {
+		return money.Money{}, status.Errorf(codes.InvalidArgument, "invalid %s: must be positive", name)
+	}
+
+	return parsed, nil
+}
@@ -1,16 +1,32 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
 
 	_ "github.com/lib/pq"
+	"google.golang.org/grpc"
 
 	"tech-school/api"
 	db "tech-school/db/sqlc"
+	"tech-school/fees"
+	"tech-school/gapi"
+	"tech-school/outbox"
 	"tech-school/util"
+	"tech-school/webhook"
 )
 
+// shutdownTimeout bounds how long the servers wait for the requests in progress when shutting down.
+const shutdownTimeout = 30 * time.Second
+
 func main() {
 	cfg, err := util.LoadConfig(".")
 	if err != nil {
@@ -22,10 +38,159 @@ func main() {
 		log.Fatalf("failed to connect to db: %v", err)
 	}
 
-	store := db.NewStore(conn)
+	retryPolicy := db.DefaultRetryPolicy
+	retryPolicy.MaxRetries = cfg.DBTxMaxRetries
+
+	opts := []db.StoreOption{db.WithRetryPolicy(retryPolicy)}
+
+	if cfg.FeesFile != "" {
+		schedule, err := fees.LoadSchedule(cfg.FeesFile)
+		if err != nil {
+			log.Fatalf("failed to load the fees: %v", err)
+		}
+		opts = append(opts, db.WithFees(schedule))
+	}
+
+	store := db.NewStore(conn, opts...)
+
+	// The servers and the background loops stop on SIGINT or SIGTERM.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var loops sync.WaitGroup
+	runLoop := func(loop func()) {
+		loops.Add(1)
+		go func() {
+			defer loops.Done()
+			loop()
+		}()
+	}
+
+	if cfg.HoldExpiryInterval > 0 {
+		runLoop(func() { db.ExpireHoldsPeriodically(ctx, store, cfg.HoldExpiryInterval) })
+	}
+
+	if cfg.SchedulerInterval > 0 {
+		runLoop(func() { db.RunScheduledTransfersPeriodically(ctx, store, cfg.SchedulerInterval) })
+	}
+
+	if cfg.OutboxRelayInterval > 0 {
+		publisher, err := outbox.NewPublisher(cfg.OutboxPublisher)
+		if err != nil {
+			log.Fatalf("failed to create the outbox publisher: %v", err)
+		}
+		relay := outbox.NewRelay(store, publisher)
+		runLoop(func() { relay.Run(ctx, cfg.OutboxRelayInterval) })
+	}
+
+	if cfg.WebhookInterval > 0 {
+		dispatcher := webhook.NewDispatcher(store, nil)
+		runLoop(func() { dispatcher.Run(ctx, cfg.WebhookInterval) })
+	}
+
+	var (
+		grpcServer *grpc.Server
+		gateway    sync.WaitGroup
+	)
+
+	if cfg.GRPCAddress != "" {
+		grpcServer = runGRPCServer(cfg, store)
+
+		if cfg.GatewayAddress != "" {
+			gateway.Add(1)
+			go func() {
+				defer gateway.Done()
+				runGateway(ctx, cfg)
+			}()
+		}
+	}
+
+	server, err := api.NewServer(cfg, store)
+	if err != nil {
+		log.Fatalf("failed to create the server: %v", err)
+	}
+
+	if err := serveHTTP(ctx, &http.Server{Addr: cfg.Address, Handler: server.Handler()}); err != nil {
+		log.Fatalf("failed to run the server: %v", err)
+	}
+
+	// The gateway calls the gRPC server: the gRPC server is stopped after it.
+	gateway.Wait()
+	if grpcServer != nil {
+		stopGRPCServer(grpcServer)
+	}
+
+	loops.Wait()
+	log.Print("shut down")
+}
+
+// runGRPCServer starts serving the gRPC API in the background.
+func runGRPCServer(cfg util.Config, store db.Store) *grpc.Server {
+	server, err := gapi.NewServer(cfg, store)
+	if err != nil {
+		log.Fatalf("failed to create the gRPC server: %v", err)
+	}
+
+	listener, err := net.Listen("tcp", cfg.GRPCAddress)
+	if err != nil {
+		log.Fatalf("failed to listen for gRPC: %v", err)
+	}
+
+	grpcServer := server.GRPCServer()
+
+	go func() {
+		if err := grpcServer.Serve(listener); err != nil {
+			log.Fatalf("failed to start the gRPC server: %v", err)
+		}
+	}()
+
+	return grpcServer
+}
 
-	server := api.NewServer(store)
-	if err := server.Run(cfg.Address); err != nil {
-		log.Fatalf("failed to start the server: %v", err)
+// stopGRPCServer stops the gRPC server once the calls in progress are over, or cancels them after shutdownTimeout.
+func stopGRPCServer(server *grpc.Server) {
+	stopped := make(chan struct{})
+	go func() {
+		server.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-time.After(shutdownTimeout):
+		server.Stop()
+	}
+}
+
+// runGateway serves the HTTP routes of the gRPC services, by calling the gRPC server, until ctx is done.
+func runGateway(ctx context.Context, cfg util.Config) {
+	// The connection to the gRPC server outlives ctx, for the requests in progress to finish.
+	gateway, err := gapi.NewGateway(context.Background(), cfg.GRPCAddress)
+	if err != nil {
+		log.Fatalf("failed to create the gateway: %v", err)
+	}
+
+	if err := serveHTTP(ctx, &http.Server{Addr: cfg.GatewayAddress, Handler: gateway}); err != nil {
+		log.Fatalf("failed to run the gateway: %v", err)
 	}
 }
+
+// serveHTTP serves until ctx is done, then shuts the server down once the requests in progress are over,
+// or after shutdownTimeout.
+func serveHTTP(ctx context.Context, server *http.Server) error {
+	errs := make(chan error, 1)
+	go func() {
+		errs <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	return server.Shutdown(shutdownCtx)
+}
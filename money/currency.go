@@ -0,0 +1,54 @@
+package money
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// ErrUnknownCurrency is returned for currency codes missing from the ISO 4217 table.
+var ErrUnknownCurrency = errors.New("unknown currency")
+
+// Currency holds the ISO 4217 metadata needed to convert between minor and major units.
+type Currency struct {
+	// Code is the ISO 4217 alphabetic code, e.g. "EUR".
+	Code string
+	// Exponent is the number of minor unit digits, e.g. 2 for cents.
+	Exponent int
+	// Symbol is the sign used when formatting amounts for humans.
+	Symbol string
+}
+
+var currencies = map[string]Currency{
+	"AUD": {Code: "AUD", Exponent: 2, Symbol: "A$"},
+	"BHD": {Code: "BHD", Exponent: 3, Symbol: "BD"},
+	"CAD": {Code: "CAD", Exponent: 2, Symbol: "CA$"},
+	"CHF": {Code: "CHF", Exponent: 2, Symbol: "CHF"},
+	"EUR": {Code: "EUR", Exponent: 2, Symbol: "€"},
+	"GBP": {Code: "GBP", Exponent: 2, Symbol: "£"},
+	"JPY": {Code: "JPY", Exponent: 0, Symbol: "¥"},
+	"KRW": {Code: "KRW", Exponent: 0, Symbol: "₩"},
+	"KWD": {Code: "KWD", Exponent: 3, Symbol: "KD"},
+	"USD": {Code: "USD", Exponent: 2, Symbol: "$"},
+}
+
+// LookupCurrency returns the metadata of the given ISO 4217 code.
+func LookupCurrency(code string) (Currency, error) {
+	currency, ok := currencies[code]
+	if !ok {
+		return Currency{}, fmt.Errorf("%w: %q", ErrUnknownCurrency, code)
+	}
+
+	return currency, nil
+}
+
+// Currencies returns the codes of all known currencies in alphabetical order.
+func Currencies() []string {
+	codes := make([]string, 0, len(currencies))
+	for code := range currencies {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+
+	return codes
+}
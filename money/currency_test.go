@@ -0,0 +1,35 @@
+package money
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLookupCurrency(t *testing.T) {
+	currency, err := LookupCurrency("EUR")
+	require.NoError(t, err)
+	require.Equal(t, Currency{Code: "EUR", Exponent: 2, Symbol: "€"}, currency)
+
+	currency, err = LookupCurrency("JPY")
+	require.NoError(t, err)
+	require.Zero(t, currency.Exponent)
+
+	_, err = LookupCurrency("XXX")
+	require.ErrorIs(t, err, ErrUnknownCurrency)
+
+	_, err = LookupCurrency("eur")
+	require.ErrorIs(t, err, ErrUnknownCurrency)
+}
+
+func TestCurrencies(t *testing.T) {
+	codes := Currencies()
+	require.IsIncreasing(t, codes)
+
+	for _, code := range codes {
+		currency, err := LookupCurrency(code)
+		require.NoError(t, err)
+		require.Equal(t, code, currency.Code)
+		require.NotEmpty(t, currency.Symbol)
+	}
+}
@@ -0,0 +1,245 @@
+// Package money represents amounts as integer minor units tied to an ISO 4217 currency.
+package money
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrCurrencyMismatch is returned when combining amounts in different currencies.
+	ErrCurrencyMismatch = errors.New("currency mismatch")
+	// ErrOverflow is returned when the result doesn't fit in int64 minor units.
+	ErrOverflow = errors.New("amount overflow")
+	// ErrInvalidAmount is returned when a string isn't a valid amount for its currency.
+	ErrInvalidAmount = errors.New("invalid amount")
+)
+
+// Money is an amount of minor units, e.g. cents, in a currency.
+type Money struct {
+	Amount   int64
+	Currency string
+}
+
+// New returns an amount of minor units in a known currency.
+func New(amount int64, currency string) (Money, error) {
+	if _, err := LookupCurrency(currency); err != nil {
+		return Money{}, err
+	}
+
+	return Money{Amount: amount, Currency: currency}, nil
+}
+
+// IsZero reports whether the amount is zero.
+func (m Money) IsZero() bool {
+	return m.Amount == 0
+}
+
+// IsPositive reports whether the amount is greater than zero.
+func (m Money) IsPositive() bool {
+	return m.Amount > 0
+}
+
+// IsNegative reports whether the amount is less than zero.
+func (m Money) IsNegative() bool {
+	return m.Amount < 0
+}
+
+// Add returns m + other. Both must be in the same currency.
+func (m Money) Add(other Money) (Money, error) {
+	if m.Currency != other.Currency {
+		return Money{}, fmt.Errorf("%w: %s vs %s", ErrCurrencyMismatch, m.Currency, other.Currency)
+	}
+
+	if (other.Amount > 0 && m.Amount > math.MaxInt64-other.Amount) ||
+		(other.Amount < 0 && m.Amount < math.MinInt64-other.Amount) {
+		return Money{}, fmt.Errorf("%w: %d + %d", ErrOverflow, m.Amount, other.Amount)
+	}
+
+	return Money{Amount: m.Amount + other.Amount, Currency: m.Currency}, nil
+}
+
+// Sub returns m - other. Both must be in the same currency.
+func (m Money) Sub(other Money) (Money, error) {
+	negated, err := other.Neg()
+	if err != nil {
+		return Money{}, err
+	}
+
+	return m.Add(negated)
+}
+
+// Neg returns -m.
+func (m Money) Neg() (Money, error) {
+	if m.Amount == math.MinInt64 {
+		return Money{}, fmt.Errorf("%w: -(%d)", ErrOverflow, m.Amount)
+	}
+
+	return Money{Amount: -m.Amount, Currency: m.Currency}, nil
+}
+
+// Mul returns m * factor.
+func (m Money) Mul(factor int64) (Money, error) {
+	if m.Amount == 0 || factor == 0 {
+		return Money{Amount: 0, Currency: m.Currency}, nil
+	}
+
+	product := m.Amount * factor
+	if product/factor != m.Amount || (m.Amount == -1 && factor == math.MinInt64) || (factor == -1 && m.Amount == math.MinInt64) {
+		return Money{}, fmt.Errorf("%w: %d * %d", ErrOverflow, m.Amount, factor)
+	}
+
+	return Money{Amount: product, Currency: m.Currency}, nil
+}
+
+// Decimal formats the amount in major units, e.g. "-12.34", using the currency exponent.
+func (m Money) Decimal() string {
+	exponent := 0
+	if currency, err := LookupCurrency(m.Currency); err == nil {
+		exponent = currency.Exponent
+	}
+
+	sign := ""
+	// Format the absolute value as uint64 so that math.MinInt64 doesn't overflow.
+	abs := uint64(m.Amount)
+	if m.Amount < 0 {
+		sign = "-"
+		abs = uint64(-(m.Amount + 1)) + 1
+	}
+
+	digits := strconv.FormatUint(abs, 10)
+	if exponent == 0 {
+		return sign + digits
+	}
+
+	if len(digits) <= exponent {
+		digits = strings.Repeat("0", exponent-len(digits)+1) + digits
+	}
+
+	return sign + digits[:len(digits)-exponent] + "." + digits[len(digits)-exponent:]
+}
+
+// String formats the amount as "12.34 EUR", the format accepted by Parse.
+func (m Money) String() string {
+	return m.Decimal() + " " + m.Currency
+}
+
+// Display formats the amount for humans with the currency symbol, e.g. "-€12.34".
+func (m Money) Display() string {
+	currency, err := LookupCurrency(m.Currency)
+	if err != nil {
+		return m.String()
+	}
+
+	decimal := m.Decimal()
+	if strings.HasPrefix(decimal, "-") {
+		return "-" + currency.Symbol + decimal[1:]
+	}
+
+	return currency.Symbol + decimal
+}
+
+// Parse parses an amount in major units followed by a currency code, e.g. "12.34 EUR".
+// The amount may not have more decimals than the currency exponent.
+func Parse(s string) (Money, error) {
+	fields := strings.Fields(s)
+	if len(fields) != 2 {
+		return Money{}, fmt.Errorf("%w: %q must look like \"12.34 EUR\"", ErrInvalidAmount, s)
+	}
+
+	return ParseDecimal(fields[0], fields[1])
+}
+
+// ParseDecimal parses an amount in major units, e.g. "12.34", in the given currency.
+func ParseDecimal(decimal, code string) (Money, error) {
+	currency, err := LookupCurrency(code)
+	if err != nil {
+		return Money{}, err
+	}
+
+	digits := decimal
+	negative := false
+	if strings.HasPrefix(digits, "-") || strings.HasPrefix(digits, "+") {
+		negative = digits[0] == '-'
+		digits = digits[1:]
+	}
+
+	whole, fraction, hasPoint := strings.Cut(digits, ".")
+	if whole == "" || (hasPoint && fraction == "") || len(fraction) > currency.Exponent ||
+		!isDigits(whole) || !isDigits(fraction) {
+		return Money{}, fmt.Errorf("%w: %q for %s", ErrInvalidAmount, decimal, code)
+	}
+
+	minor := whole + fraction + strings.Repeat("0", currency.Exponent-len(fraction))
+	if negative {
+		minor = "-" + minor
+	}
+
+	amount, err := strconv.ParseInt(minor, 10, 64)
+	if err != nil {
+		if errors.Is(err, strconv.ErrRange) {
+			return Money{}, fmt.Errorf("%w: %q for %s", ErrOverflow, decimal, code)
+		}
+		return Money{}, fmt.Errorf("%w: %q for %s", ErrInvalidAmount, decimal, code)
+	}
+
+	return Money{Amount: amount, Currency: code}, nil
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+
+	return true
+}
+
+type jsonMoney struct {
+	Amount   json.Number `json:"amount"`
+	Currency string      `json:"currency"`
+}
+
+// MarshalJSON encodes the amount in major units as a string, so no precision is lost in clients
+// that decode JSON numbers as floats: {"amount":"12.34","currency":"EUR"}.
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Amount   string `json:"amount"`
+		Currency string `json:"currency"`
+	}{
+		Amount:   m.Decimal(),
+		Currency: m.Currency,
+	})
+}
+
+// UnmarshalJSON accepts the object produced by MarshalJSON, with the amount given either
+// as a string or as a number, and the "12.34 EUR" string form.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		parsed, err := Parse(s)
+		if err != nil {
+			return err
+		}
+		*m = parsed
+		return nil
+	}
+
+	var v jsonMoney
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	parsed, err := ParseDecimal(v.Amount.String(), v.Currency)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+
+	return nil
+}
@@ -0,0 +1,221 @@
+package money
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	m, err := New(1234, "EUR")
+	require.NoError(t, err)
+	require.Equal(t, Money{Amount: 1234, Currency: "EUR"}, m)
+
+	_, err = New(1234, "XXX")
+	require.ErrorIs(t, err, ErrUnknownCurrency)
+}
+
+func TestAdd(t *testing.T) {
+	testCases := []struct {
+		name     string
+		a, b     Money
+		expected Money
+		err      error
+	}{
+		{
+			name:     "OK",
+			a:        Money{Amount: 150, Currency: "USD"},
+			b:        Money{Amount: -50, Currency: "USD"},
+			expected: Money{Amount: 100, Currency: "USD"},
+		},
+		{
+			name: "CurrencyMismatch",
+			a:    Money{Amount: 1, Currency: "USD"},
+			b:    Money{Amount: 1, Currency: "EUR"},
+			err:  ErrCurrencyMismatch,
+		},
+		{
+			name: "Overflow",
+			a:    Money{Amount: math.MaxInt64, Currency: "USD"},
+			b:    Money{Amount: 1, Currency: "USD"},
+			err:  ErrOverflow,
+		},
+		{
+			name: "Underflow",
+			a:    Money{Amount: math.MinInt64, Currency: "USD"},
+			b:    Money{Amount: -1, Currency: "USD"},
+			err:  ErrOverflow,
+		},
+		{
+			name:     "MaxValue",
+			a:        Money{Amount: math.MaxInt64 - 1, Currency: "USD"},
+			b:        Money{Amount: 1, Currency: "USD"},
+			expected: Money{Amount: math.MaxInt64, Currency: "USD"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			sum, err := tc.a.Add(tc.b)
+			if tc.err != nil {
+				require.ErrorIs(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, sum)
+		})
+	}
+}
+
+func TestSub(t *testing.T) {
+	diff, err := Money{Amount: 100, Currency: "EUR"}.Sub(Money{Amount: 250, Currency: "EUR"})
+	require.NoError(t, err)
+	require.Equal(t, Money{Amount: -150, Currency: "EUR"}, diff)
+
+	_, err = Money{Amount: 0, Currency: "EUR"}.Sub(Money{Amount: math.MinInt64, Currency: "EUR"})
+	require.ErrorIs(t, err, ErrOverflow)
+
+	_, err = Money{Amount: math.MinInt64, Currency: "EUR"}.Sub(Money{Amount: 1, Currency: "EUR"})
+	require.ErrorIs(t, err, ErrOverflow)
+
+	_, err = Money{Amount: 1, Currency: "EUR"}.Sub(Money{Amount: 1, Currency: "USD"})
+	require.ErrorIs(t, err, ErrCurrencyMismatch)
+}
+
+func TestNeg(t *testing.T) {
+	neg, err := Money{Amount: 42, Currency: "USD"}.Neg()
+	require.NoError(t, err)
+	require.Equal(t, Money{Amount: -42, Currency: "USD"}, neg)
+
+	_, err = Money{Amount: math.MinInt64, Currency: "USD"}.Neg()
+	require.ErrorIs(t, err, ErrOverflow)
+}
+
+func TestMul(t *testing.T) {
+	product, err := Money{Amount: 125, Currency: "USD"}.Mul(-3)
+	require.NoError(t, err)
+	require.Equal(t, Money{Amount: -375, Currency: "USD"}, product)
+
+	product, err = Money{Amount: math.MaxInt64, Currency: "USD"}.Mul(0)
+	require.NoError(t, err)
+	require.True(t, product.IsZero())
+
+	for _, tc := range []struct{ amount, factor int64 }{
+		{math.MaxInt64, 2},
+		{math.MinInt64, -1},
+		{-1, math.MinInt64},
+		{math.MaxInt64 / 2, 3},
+	} {
+		_, err = Money{Amount: tc.amount, Currency: "USD"}.Mul(tc.factor)
+		require.ErrorIs(t, err, ErrOverflow, "%d * %d", tc.amount, tc.factor)
+	}
+}
+
+func TestSign(t *testing.T) {
+	require.True(t, Money{Amount: 1}.IsPositive())
+	require.False(t, Money{Amount: 0}.IsPositive())
+	require.True(t, Money{Amount: -1}.IsNegative())
+	require.True(t, Money{}.IsZero())
+}
+
+func TestFormat(t *testing.T) {
+	testCases := []struct {
+		money   Money
+		decimal string
+		display string
+	}{
+		{Money{Amount: 1234, Currency: "EUR"}, "12.34", "€12.34"},
+		{Money{Amount: -1234, Currency: "USD"}, "-12.34", "-$12.34"},
+		{Money{Amount: 5, Currency: "USD"}, "0.05", "$0.05"},
+		{Money{Amount: -5, Currency: "USD"}, "-0.05", "-$0.05"},
+		{Money{Amount: 0, Currency: "GBP"}, "0.00", "£0.00"},
+		{Money{Amount: 1500, Currency: "JPY"}, "1500", "¥1500"},
+		{Money{Amount: 1234, Currency: "KWD"}, "1.234", "KD1.234"},
+		{Money{Amount: math.MinInt64, Currency: "USD"}, "-92233720368547758.08", "-$92233720368547758.08"},
+		{Money{Amount: 12, Currency: "XXX"}, "12", "12 XXX"},
+	}
+
+	for _, tc := range testCases {
+		require.Equal(t, tc.decimal, tc.money.Decimal())
+		require.Equal(t, tc.decimal+" "+tc.money.Currency, tc.money.String())
+		require.Equal(t, tc.display, tc.money.Display())
+	}
+}
+
+func TestParse(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected Money
+		err      error
+	}{
+		{input: "12.34 EUR", expected: Money{Amount: 1234, Currency: "EUR"}},
+		{input: "12 EUR", expected: Money{Amount: 1200, Currency: "EUR"}},
+		{input: "12.3 EUR", expected: Money{Amount: 1230, Currency: "EUR"}},
+		{input: "-0.05 USD", expected: Money{Amount: -5, Currency: "USD"}},
+		{input: "+7 USD", expected: Money{Amount: 700, Currency: "USD"}},
+		{input: "  1500   JPY ", expected: Money{Amount: 1500, Currency: "JPY"}},
+		{input: "1.234 KWD", expected: Money{Amount: 1234, Currency: "KWD"}},
+		{input: "92233720368547758.07 USD", expected: Money{Amount: math.MaxInt64, Currency: "USD"}},
+		{input: "92233720368547758.08 USD", err: ErrOverflow},
+		{input: "12.345 EUR", err: ErrInvalidAmount},
+		{input: "12.5 JPY", err: ErrInvalidAmount},
+		{input: "12. EUR", err: ErrInvalidAmount},
+		{input: ".5 EUR", err: ErrInvalidAmount},
+		{input: "1e3 EUR", err: ErrInvalidAmount},
+		{input: "12,34 EUR", err: ErrInvalidAmount},
+		{input: "EUR 12.34", err: ErrUnknownCurrency},
+		{input: "12.34", err: ErrInvalidAmount},
+		{input: "12.34 XXX", err: ErrUnknownCurrency},
+		{input: "", err: ErrInvalidAmount},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.input, func(t *testing.T) {
+			m, err := Parse(tc.input)
+			if tc.err != nil {
+				require.ErrorIs(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, m)
+
+			roundTrip, err := Parse(m.String())
+			require.NoError(t, err)
+			require.Equal(t, m, roundTrip)
+		})
+	}
+}
+
+func TestJSON(t *testing.T) {
+	m := Money{Amount: -1234, Currency: "EUR"}
+
+	data, err := json.Marshal(m)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"amount":"-12.34","currency":"EUR"}`, string(data))
+
+	var got Money
+	require.NoError(t, json.Unmarshal(data, &got))
+	require.Equal(t, m, got)
+
+	for _, input := range []string{
+		`{"amount":-12.34,"currency":"EUR"}`,
+		`"-12.34 EUR"`,
+	} {
+		got = Money{}
+		require.NoError(t, json.Unmarshal([]byte(input), &got), input)
+		require.Equal(t, m, got)
+	}
+
+	for _, input := range []string{
+		`{"amount":"12.345","currency":"EUR"}`,
+		`{"amount":"12.34","currency":"XXX"}`,
+		`{"amount":"abc","currency":"EUR"}`,
+		`{"amount":1e3,"currency":"EUR"}`,
+		`"12.34"`,
+		`42`,
+	} {
+		require.Error(t, json.Unmarshal([]byte(input), &got), input)
+	}
+}
@@ -0,0 +1,146 @@
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrInvalidPublisher is returned when a publisher spec can't be parsed.
+var ErrInvalidPublisher = errors.New("invalid publisher")
+
+// Message is an outbox event as it is published. Consumers must dispatch on Type and Version,
+// and deduplicate on ID: an event may be published more than once.
+type Message struct {
+	ID          int64           `json:"id"`
+	Type        string          `json:"type"`
+	Version     int32           `json:"version"`
+	AggregateID int64           `json:"aggregate_id"`
+	Data        json.RawMessage `json:"data"`
+	CreatedAt   time.Time       `json:"created_at"`
+}
+
+// Publisher delivers messages to downstream services.
+type Publisher interface {
+	// Publish delivers the message. An error means the message must be published again later.
+	Publish(ctx context.Context, msg Message) error
+}
+
+// WriterPublisher writes every message as a line of JSON.
+type WriterPublisher struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewWriterPublisher creates a WriterPublisher writing to w.
+func NewWriterPublisher(w io.Writer) *WriterPublisher {
+	return &WriterPublisher{w: w}
+}
+
+// NewStdoutPublisher creates a WriterPublisher writing to the standard output.
+func NewStdoutPublisher() *WriterPublisher {
+	return NewWriterPublisher(os.Stdout)
+}
+
+// NewFilePublisher creates a WriterPublisher appending to the given file, which is created if needed.
+func NewFilePublisher(path string) (*WriterPublisher, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewWriterPublisher(file), nil
+}
+
+// Publish implements Publisher.
+func (p *WriterPublisher) Publish(_ context.Context, msg Message) error {
+	line, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	_, err = p.w.Write(append(line, '\n'))
+
+	return err
+}
+
+// WebhookPublisher POSTs every message as JSON to a URL. Any response status other than 2xx is a failure.
+// The message ID is also sent in the X-Event-ID header, so that the receiver can deduplicate deliveries.
+type WebhookPublisher struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhookPublisher creates a WebhookPublisher posting to url with the given client,
+// or with a client timing out after 10 seconds if it is nil.
+func NewWebhookPublisher(url string, client *http.Client) *WebhookPublisher {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	return &WebhookPublisher{url: url, client: client}
+}
+
+// Publish implements Publisher.
+func (p *WebhookPublisher) Publish(ctx context.Context, msg Message) error {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Event-ID", strconv.FormatInt(msg.ID, 10))
+
+	res, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	// Drain the body so that the connection can be reused.
+	_, _ = io.Copy(io.Discard, io.LimitReader(res.Body, 1<<16))
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("webhook %s responded %s", p.url, res.Status)
+	}
+
+	return nil
+}
+
+// NewPublisher creates the publisher described by spec:
+//
+//	stdout                    writes to the standard output
+//	file:<path>               appends to the file at path
+//	http://... or https://... posts to the webhook URL
+func NewPublisher(spec string) (Publisher, error) {
+	switch {
+	case spec == "stdout":
+		return NewStdoutPublisher(), nil
+	case strings.HasPrefix(spec, "file:"):
+		path := strings.TrimPrefix(spec, "file:")
+		if path == "" {
+			return nil, fmt.Errorf("%w: %q has no path", ErrInvalidPublisher, spec)
+		}
+		return NewFilePublisher(path)
+	case strings.HasPrefix(spec, "http://"), strings.HasPrefix(spec, "https://"):
+		return NewWebhookPublisher(spec, nil), nil
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrInvalidPublisher, spec)
+	}
+}
@@ -0,0 +1,150 @@
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func randomMessage(id int64) Message {
+	return Message{
+		ID:          id,
+		Type:        "TransferCompleted",
+		Version:     1,
+		AggregateID: id * 10,
+		Data:        json.RawMessage(`{"transfer_id":1}`),
+		CreatedAt:   time.Now().Truncate(time.Second).UTC(),
+	}
+}
+
+func requireLinesMatchMessages(t *testing.T, data []byte, messages ...Message) {
+	lines := bytes.Split(bytes.TrimSuffix(data, []byte("\n")), []byte("\n"))
+	require.Len(t, lines, len(messages))
+
+	for i, line := range lines {
+		var got Message
+		require.NoError(t, json.Unmarshal(line, &got))
+		require.Equal(t, messages[i], got)
+	}
+}
+
+func TestWriterPublisher(t *testing.T) {
+	var buf bytes.Buffer
+	publisher := NewWriterPublisher(&buf)
+
+	msg1, msg2 := randomMessage(1), randomMessage(2)
+	require.NoError(t, publisher.Publish(context.Background(), msg1))
+	require.NoError(t, publisher.Publish(context.Background(), msg2))
+
+	requireLinesMatchMessages(t, buf.Bytes(), msg1, msg2)
+}
+
+func TestFilePublisher(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+
+	msg1, msg2 := randomMessage(1), randomMessage(2)
+
+	// The file is appended to, so events published before a restart are kept.
+	for _, msg := range []Message{msg1, msg2} {
+		publisher, err := NewFilePublisher(path)
+		require.NoError(t, err)
+		require.NoError(t, publisher.Publish(context.Background(), msg))
+	}
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	requireLinesMatchMessages(t, data, msg1, msg2)
+}
+
+func TestWebhookPublisher(t *testing.T) {
+	msg := randomMessage(42)
+
+	testCases := []struct {
+		name    string
+		status  int
+		checkFn func(t *testing.T, err error)
+	}{
+		{
+			name:   "OK",
+			status: http.StatusNoContent,
+			checkFn: func(t *testing.T, err error) {
+				require.NoError(t, err)
+			},
+		},
+		{
+			name:   "ServerError",
+			status: http.StatusInternalServerError,
+			checkFn: func(t *testing.T, err error) {
+				require.Error(t, err)
+			},
+		},
+		{
+			name:   "NotSuccessful",
+			status: http.StatusNotModified,
+			checkFn: func(t *testing.T, err error) {
+				require.Error(t, err)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				require.Equal(t, http.MethodPost, r.Method)
+				require.Equal(t, "application/json", r.Header.Get("Content-Type"))
+				require.Equal(t, "42", r.Header.Get("X-Event-ID"))
+
+				body, err := io.ReadAll(r.Body)
+				require.NoError(t, err)
+
+				var got Message
+				require.NoError(t, json.Unmarshal(body, &got))
+				require.Equal(t, msg, got)
+
+				w.WriteHeader(tc.status)
+			}))
+			defer server.Close()
+
+			publisher := NewWebhookPublisher(server.URL, server.Client())
+			tc.checkFn(t, publisher.Publish(context.Background(), msg))
+		})
+	}
+}
+
+func TestNewPublisher(t *testing.T) {
+	dir := t.TempDir()
+
+	testCases := []struct {
+		spec     string
+		expected interface{}
+	}{
+		{spec: "stdout", expected: &WriterPublisher{}},
+		{spec: "file:" + filepath.Join(dir, "events.jsonl"), expected: &WriterPublisher{}},
+		{spec: "https://example.com/events", expected: &WebhookPublisher{}},
+		{spec: "file:"},
+		{spec: "kafka://localhost:9092"},
+		{spec: ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.spec, func(t *testing.T) {
+			publisher, err := NewPublisher(tc.spec)
+			if tc.expected == nil {
+				require.ErrorIs(t, err, ErrInvalidPublisher)
+				return
+			}
+
+			require.NoError(t, err)
+			require.IsType(t, tc.expected, publisher)
+		})
+	}
+}
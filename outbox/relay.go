@@ -0,0 +1,129 @@
+// Package outbox publishes the events that the store writes to the outbox table
+// in the same transactions as the changes they describe.
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"sort"
+	"time"
+
+	"tech-school/backoff"
+	db "tech-school/db/sqlc"
+)
+
+// Relay publishes the pending outbox events and marks them delivered.
+// Delivery is at least once: an event is published again when it can't be marked delivered,
+// or when its lease runs out before it is. A failed event is retried with an exponential backoff,
+// so events may be published out of order.
+type Relay struct {
+	q         db.Querier
+	publisher Publisher
+
+	// BatchSize is the maximum number of events claimed at once.
+	BatchSize int32
+	// Lease is how long the claimed events are hidden from other relays while they are published.
+	Lease time.Duration
+	// BaseDelay is the backoff before retrying an event after its first failure. It doubles on every following failure.
+	BaseDelay time.Duration
+	// MaxDelay caps the backoff between two attempts.
+	MaxDelay time.Duration
+}
+
+// NewRelay creates a Relay publishing the events of q with publisher.
+func NewRelay(q db.Querier, publisher Publisher) *Relay {
+	return &Relay{
+		q:         q,
+		publisher: publisher,
+		BatchSize: 100,
+		Lease:     time.Minute,
+		BaseDelay: time.Second,
+		MaxDelay:  time.Hour,
+	}
+}
+
+// RelayBatch claims a batch of pending events and publishes them, in ID order.
+// It returns the number of events claimed. Events still unpublished when the lease runs out are left to be claimed again.
+func (r *Relay) RelayBatch(ctx context.Context) (int, error) {
+	lockedUntil := time.Now().Add(r.Lease)
+
+	events, err := r.q.ClaimOutboxEvents(ctx, db.ClaimOutboxEventsParams{
+		LockedUntil: lockedUntil,
+		MaxEvents:   r.BatchSize,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	sort.Slice(events, func(i, j int) bool { return events[i].ID < events[j].ID })
+
+	publishCtx, cancel := context.WithDeadline(ctx, lockedUntil)
+	defer cancel()
+
+	for _, event := range events {
+		if publishCtx.Err() != nil {
+			break
+		}
+
+		if err := r.publisher.Publish(publishCtx, newMessage(event)); err != nil {
+			if err := r.q.MarkOutboxEventFailed(ctx, db.MarkOutboxEventFailedParams{
+				ID:            event.ID,
+				LastError:     sql.NullString{String: err.Error(), Valid: true},
+				NextAttemptAt: time.Now().Add(r.backoff(event.Attempts)),
+			}); err != nil {
+				return len(events), err
+			}
+			continue
+		}
+
+		if err := r.q.MarkOutboxEventDelivered(ctx, event.ID); err != nil {
+			return len(events), err
+		}
+	}
+
+	return len(events), nil
+}
+
+// Run relays the pending events every interval, until ctx is done.
+// A batch in progress when ctx is done is finished, so that its events aren't left leased.
+// It is safe to run in several replicas at once, since each relay claims different events.
+func (r *Relay) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			// Keep going while the batches are full, there may be more events pending.
+			for ctx.Err() == nil {
+				n, err := r.RelayBatch(context.Background())
+				if err != nil {
+					log.Printf("failed to relay outbox events: %v", err)
+					break
+				}
+				if n < int(r.BatchSize) {
+					break
+				}
+			}
+		}
+	}
+}
+
+// backoff returns the delay before the next attempt to publish an event that failed after the given number of attempts.
+func (r *Relay) backoff(attempts int32) time.Duration {
+	return backoff.Exponential(r.BaseDelay, r.MaxDelay, int(attempts))
+}
+
+func newMessage(event db.OutboxEvent) Message {
+	return Message{
+		ID:          event.ID,
+		Type:        event.EventType,
+		Version:     event.Version,
+		AggregateID: event.AggregateID,
+		Data:        event.Payload,
+		CreatedAt:   event.CreatedAt,
+	}
+}
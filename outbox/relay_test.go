@@ -0,0 +1,156 @@
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+
+	mockdb "tech-school/db/mock"
+	db "tech-school/db/sqlc"
+)
+
+// fakePublisher records the published messages, and fails to publish the ones listed in failures.
+type fakePublisher struct {
+	published []Message
+	failures  map[int64]error
+}
+
+func (p *fakePublisher) Publish(_ context.Context, msg Message) error {
+	if err := p.failures[msg.ID]; err != nil {
+		return err
+	}
+	p.published = append(p.published, msg)
+	return nil
+}
+
+func randomOutboxEvent(id int64, attempts int32) db.OutboxEvent {
+	return db.OutboxEvent{
+		ID:            id,
+		EventType:     db.EventTransferCompleted,
+		Version:       db.TransferEventVersion,
+		AggregateID:   id * 10,
+		Payload:       json.RawMessage(`{"transfer_id":1}`),
+		Attempts:      attempts,
+		NextAttemptAt: time.Now(),
+		CreatedAt:     time.Now().Truncate(time.Second).UTC(),
+	}
+}
+
+func TestRelayBatch(t *testing.T) {
+	event1 := randomOutboxEvent(1, 0)
+	event2 := randomOutboxEvent(2, 3)
+	event3 := randomOutboxEvent(3, 0)
+
+	errUnavailable := errors.New("service unavailable")
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	publisher := &fakePublisher{failures: map[int64]error{event2.ID: errUnavailable}}
+
+	relay := NewRelay(store, publisher)
+	relay.BatchSize = 10
+
+	start := time.Now()
+
+	// The claimed events aren't necessarily returned in order.
+	store.EXPECT().
+		ClaimOutboxEvents(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(_ context.Context, arg db.ClaimOutboxEventsParams) ([]db.OutboxEvent, error) {
+			require.Equal(t, int32(10), arg.MaxEvents)
+			require.WithinDuration(t, start.Add(relay.Lease), arg.LockedUntil, time.Second)
+			return []db.OutboxEvent{event3, event1, event2}, nil
+		})
+
+	gomock.InOrder(
+		store.EXPECT().MarkOutboxEventDelivered(gomock.Any(), gomock.Eq(event1.ID)).Times(1).Return(nil),
+		store.EXPECT().
+			MarkOutboxEventFailed(gomock.Any(), gomock.Any()).
+			Times(1).
+			DoAndReturn(func(_ context.Context, arg db.MarkOutboxEventFailedParams) error {
+				require.Equal(t, event2.ID, arg.ID)
+				require.Equal(t, sql.NullString{String: errUnavailable.Error(), Valid: true}, arg.LastError)
+				// The fourth failure is retried after 2^3 times the base delay.
+				require.WithinDuration(t, start.Add(8*relay.BaseDelay), arg.NextAttemptAt, time.Second)
+				return nil
+			}),
+		store.EXPECT().MarkOutboxEventDelivered(gomock.Any(), gomock.Eq(event3.ID)).Times(1).Return(nil),
+	)
+
+	n, err := relay.RelayBatch(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 3, n)
+
+	require.Equal(t, []Message{newMessage(event1), newMessage(event3)}, publisher.published)
+}
+
+func TestRelayBatchMarkError(t *testing.T) {
+	event1 := randomOutboxEvent(1, 0)
+	event2 := randomOutboxEvent(2, 0)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	publisher := &fakePublisher{}
+
+	store.EXPECT().ClaimOutboxEvents(gomock.Any(), gomock.Any()).Times(1).Return([]db.OutboxEvent{event1, event2}, nil)
+	store.EXPECT().MarkOutboxEventDelivered(gomock.Any(), gomock.Eq(event1.ID)).Times(1).Return(sql.ErrConnDone)
+
+	// The second event is left to be claimed again when its lease runs out.
+	_, err := NewRelay(store, publisher).RelayBatch(context.Background())
+	require.ErrorIs(t, err, sql.ErrConnDone)
+	require.Len(t, publisher.published, 1)
+}
+
+// cancelingPublisher cancels the context of the relay when it publishes its first message.
+type cancelingPublisher struct {
+	fakePublisher
+	cancel context.CancelFunc
+}
+
+func (p *cancelingPublisher) Publish(ctx context.Context, msg Message) error {
+	p.cancel()
+	return p.fakePublisher.Publish(ctx, msg)
+}
+
+func TestRelayRunFinishesBatch(t *testing.T) {
+	event1 := randomOutboxEvent(1, 0)
+	event2 := randomOutboxEvent(2, 0)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	store := mockdb.NewMockStore(ctrl)
+	publisher := &cancelingPublisher{cancel: cancel}
+
+	store.EXPECT().ClaimOutboxEvents(gomock.Any(), gomock.Any()).Times(1).Return([]db.OutboxEvent{event1, event2}, nil)
+	store.EXPECT().MarkOutboxEventDelivered(gomock.Any(), gomock.Eq(event1.ID)).Times(1).Return(nil)
+	store.EXPECT().MarkOutboxEventDelivered(gomock.Any(), gomock.Eq(event2.ID)).Times(1).Return(nil)
+
+	// The relay is stopped while it publishes the batch: the batch is finished, and no other one is claimed.
+	NewRelay(store, publisher).Run(ctx, time.Millisecond)
+
+	require.Equal(t, []Message{newMessage(event1), newMessage(event2)}, publisher.published)
+}
+
+func TestRelayBackoff(t *testing.T) {
+	relay := &Relay{BaseDelay: time.Second, MaxDelay: time.Minute}
+
+	require.Equal(t, time.Second, relay.backoff(0))
+	require.Equal(t, 2*time.Second, relay.backoff(1))
+	require.Equal(t, 32*time.Second, relay.backoff(5))
+	require.Equal(t, time.Minute, relay.backoff(6))
+	require.Equal(t, time.Minute, relay.backoff(1000))
+}
@@ -0,0 +1,116 @@
+package recurrence
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func date(s string) time.Time {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		panic(err)
+	}
+
+	return t
+}
+
+func TestNext(t *testing.T) {
+	testCases := []struct {
+		spec     string
+		after    string
+		expected []string
+	}{
+		{
+			spec:     "@every 24h",
+			after:    "2024-01-30T10:15:00Z",
+			expected: []string{"2024-01-31T10:15:00Z", "2024-02-01T10:15:00Z"},
+		},
+		{
+			// Monthly rent on the 1st at 09:00.
+			spec:     "0 9 1 * *",
+			after:    "2024-01-01T09:00:00Z",
+			expected: []string{"2024-02-01T09:00:00Z", "2024-03-01T09:00:00Z"},
+		},
+		{
+			spec:     "@monthly",
+			after:    "2024-12-15T00:00:00Z",
+			expected: []string{"2025-01-01T00:00:00Z", "2025-02-01T00:00:00Z"},
+		},
+		{
+			// The 31st only exists in some months.
+			spec:     "30 12 31 * *",
+			after:    "2024-01-31T12:30:00Z",
+			expected: []string{"2024-03-31T12:30:00Z", "2024-05-31T12:30:00Z"},
+		},
+		{
+			// Every 15 minutes during business hours on weekdays.
+			spec:     "*/15 9-17 * * 1-5",
+			after:    "2024-03-01T17:50:00Z",
+			expected: []string{"2024-03-04T09:00:00Z", "2024-03-04T09:15:00Z"},
+		},
+		{
+			// Sunday may be written 7.
+			spec:     "0 0 * * 7",
+			after:    "2024-03-01T00:00:00Z",
+			expected: []string{"2024-03-03T00:00:00Z", "2024-03-10T00:00:00Z"},
+		},
+		{
+			// When both day fields are restricted, either one matches.
+			spec:     "0 0 13 * 5",
+			after:    "2024-09-01T00:00:00Z",
+			expected: []string{"2024-09-06T00:00:00Z", "2024-09-13T00:00:00Z", "2024-09-20T00:00:00Z"},
+		},
+		{
+			spec:     "0 0 29 2 *",
+			after:    "2024-03-01T00:00:00Z",
+			expected: []string{"2028-02-29T00:00:00Z"},
+		},
+		{
+			// Occurrences are computed in UTC, and seconds are ignored.
+			spec:     "0,30 * * * *",
+			after:    "2024-03-01T10:29:59+02:00",
+			expected: []string{"2024-03-01T08:30:00Z", "2024-03-01T09:00:00Z"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.spec, func(t *testing.T) {
+			schedule, err := Parse(tc.spec)
+			require.NoError(t, err)
+
+			next := date(tc.after)
+			for _, expected := range tc.expected {
+				next = schedule.Next(next)
+				require.Equal(t, date(expected), next)
+			}
+		})
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	specs := []string{
+		"",
+		"@every",
+		"@every 1s",
+		"@every soon",
+		"@fortnightly",
+		"0 9 1 *",
+		"0 9 1 * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"a * * * *",
+		"0 0 30 2 *",
+	}
+
+	for _, spec := range specs {
+		_, err := Parse(spec)
+		require.ErrorIs(t, err, ErrInvalidSchedule, spec)
+	}
+}
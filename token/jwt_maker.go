@@ -0,0 +1,97 @@
+package token
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+const minSecretKeySize = 32
+
+// JWTMaker is a JSON Web Token maker
+type JWTMaker struct {
+	secretKey string
+}
+
+// NewJWTMaker creates a new JWTMaker
+func NewJWTMaker(secretKey string) (Maker, error) {
+	if len(secretKey) < minSecretKeySize {
+		return nil, fmt.Errorf("invalid key size: must be at least %d characters", minSecretKeySize)
+	}
+
+	return &JWTMaker{secretKey: secretKey}, nil
+}
+
+// CreateToken creates a new token for a specific username and duration
+func (m *JWTMaker) CreateToken(username string, duration time.Duration) (string, *Payload, error) {
+	payload, err := NewPayload(username, duration)
+	if err != nil {
+		return "", nil, err
+	}
+
+	jwtToken := jwt.NewWithClaims(jwt.SigningMethodHS256, newJWTClaims(payload))
+
+	token, err := jwtToken.SignedString([]byte(m.secretKey))
+	if err != nil {
+		return "", nil, err
+	}
+
+	return token, payload, nil
+}
+
+// VerifyToken checks if the token is valid or not
+func (m *JWTMaker) VerifyToken(token string) (*Payload, error) {
+	keyFunc := func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, ErrInvalidToken
+		}
+		return []byte(m.secretKey), nil
+	}
+
+	var claims jwtClaims
+	if _, err := jwt.ParseWithClaims(token, &claims, keyFunc, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()})); err != nil {
+		if errors.Is(err, jwt.ErrTokenExpired) {
+			return nil, ErrExpiredToken
+		}
+		return nil, ErrInvalidToken
+	}
+
+	return claims.payload()
+}
+
+// jwtClaims maps a Payload onto the registered JWT claims.
+type jwtClaims struct {
+	jwt.RegisteredClaims
+}
+
+func newJWTClaims(payload *Payload) jwtClaims {
+	return jwtClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        payload.ID.String(),
+			Subject:   payload.Username,
+			IssuedAt:  jwt.NewNumericDate(payload.IssuedAt),
+			ExpiresAt: jwt.NewNumericDate(payload.ExpiredAt),
+		},
+	}
+}
+
+func (c jwtClaims) payload() (*Payload, error) {
+	if c.IssuedAt == nil || c.ExpiresAt == nil {
+		return nil, ErrInvalidToken
+	}
+
+	tokenID, err := uuid.Parse(c.ID)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	return &Payload{
+		ID:        tokenID,
+		Username:  c.Subject,
+		IssuedAt:  c.IssuedAt.Time,
+		ExpiredAt: c.ExpiresAt.Time,
+	}, nil
+}
@@ -0,0 +1,33 @@
+package token
+
+import (
+	"fmt"
+	"time"
+)
+
+// Supported token types
+const (
+	TypeJWT    = "jwt"
+	TypePaseto = "paseto"
+)
+
+// Maker is an interface for managing tokens
+type Maker interface {
+	// CreateToken creates a new token for a specific username and duration
+	CreateToken(username string, duration time.Duration) (string, *Payload, error)
+
+	// VerifyToken checks if the token is valid or not
+	VerifyToken(token string) (*Payload, error)
+}
+
+// NewMaker creates a token maker of the given type
+func NewMaker(tokenType, symmetricKey string) (Maker, error) {
+	switch tokenType {
+	case TypeJWT:
+		return NewJWTMaker(symmetricKey)
+	case TypePaseto, "":
+		return NewPasetoMaker(symmetricKey)
+	default:
+		return nil, fmt.Errorf("unsupported token type: %q", tokenType)
+	}
+}
@@ -0,0 +1,89 @@
+package token
+
+import (
+	"fmt"
+	"time"
+
+	"aidanwoods.dev/go-paseto"
+	"github.com/google/uuid"
+)
+
+// PasetoMaker is a PASETO v4.local token maker
+type PasetoMaker struct {
+	symmetricKey paseto.V4SymmetricKey
+}
+
+// NewPasetoMaker creates a new PasetoMaker
+func NewPasetoMaker(symmetricKey string) (Maker, error) {
+	key, err := paseto.V4SymmetricKeyFromBytes([]byte(symmetricKey))
+	if err != nil {
+		return nil, fmt.Errorf("invalid key size: must be exactly 32 characters: %w", err)
+	}
+
+	return &PasetoMaker{symmetricKey: key}, nil
+}
+
+// CreateToken creates a new token for a specific username and duration
+func (m *PasetoMaker) CreateToken(username string, duration time.Duration) (string, *Payload, error) {
+	payload, err := NewPayload(username, duration)
+	if err != nil {
+		return "", nil, err
+	}
+
+	token := paseto.NewToken()
+	token.SetJti(payload.ID.String())
+	token.SetSubject(payload.Username)
+	token.SetIssuedAt(payload.IssuedAt)
+	token.SetExpiration(payload.ExpiredAt)
+
+	return token.V4Encrypt(m.symmetricKey, nil), payload, nil
+}
+
+// VerifyToken checks if the token is valid or not
+func (m *PasetoMaker) VerifyToken(token string) (*Payload, error) {
+	// Expiry is checked by the payload itself, so it can be reported as ErrExpiredToken.
+	parser := paseto.NewParserWithoutExpiryCheck()
+
+	parsed, err := parser.ParseV4Local(m.symmetricKey, token, nil)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	jti, err := parsed.GetJti()
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	tokenID, err := uuid.Parse(jti)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	username, err := parsed.GetSubject()
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	issuedAt, err := parsed.GetIssuedAt()
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	expiredAt, err := parsed.GetExpiration()
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	payload := &Payload{
+		ID:        tokenID,
+		Username:  username,
+		IssuedAt:  issuedAt,
+		ExpiredAt: expiredAt,
+	}
+
+	if err := payload.Valid(); err != nil {
+		return nil, err
+	}
+
+	return payload, nil
+}
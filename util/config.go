@@ -1,11 +1,35 @@
 package util
 
-import "github.com/spf13/viper"
+import (
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// DefaultDBTxMaxRetries is the number of times a failed transaction is retried when DB_TX_MAX_RETRIES is not set.
+// It matches db.DefaultRetryPolicy.
+const DefaultDBTxMaxRetries = 3
 
 type Config struct {
-	DBDriver string `mapstructure:"DB_DRIVER"`
-	DBSource string `mapstructure:"DB_SOURCE"`
-	Address  string `mapstructure:"ADDRESS"`
+	DBDriver            string        `mapstructure:"DB_DRIVER"`
+	DBSource            string        `mapstructure:"DB_SOURCE"`
+	DBTxMaxRetries      int           `mapstructure:"DB_TX_MAX_RETRIES"`
+	Address             string        `mapstructure:"ADDRESS"`
+	GRPCAddress         string        `mapstructure:"GRPC_ADDRESS"`
+	GatewayAddress      string        `mapstructure:"GATEWAY_ADDRESS"`
+	TrustedProxies      []string      `mapstructure:"TRUSTED_PROXIES"`
+	TokenType           string        `mapstructure:"TOKEN_TYPE"`
+	TokenSymmetricKey   string        `mapstructure:"TOKEN_SYMMETRIC_KEY"`
+	AccessTokenDuration time.Duration `mapstructure:"ACCESS_TOKEN_DURATION"`
+	CursorSigningKey    string        `mapstructure:"CURSOR_SIGNING_KEY"`
+	Currencies          []string      `mapstructure:"CURRENCIES"`
+	FXRatesFile         string        `mapstructure:"FX_RATES_FILE"`
+	FeesFile            string        `mapstructure:"FEES_FILE"`
+	HoldExpiryInterval  time.Duration `mapstructure:"HOLD_EXPIRY_INTERVAL"`
+	SchedulerInterval   time.Duration `mapstructure:"SCHEDULER_INTERVAL"`
+	OutboxPublisher     string        `mapstructure:"OUTBOX_PUBLISHER"`
+	OutboxRelayInterval time.Duration `mapstructure:"OUTBOX_RELAY_INTERVAL"`
+	WebhookInterval     time.Duration `mapstructure:"WEBHOOK_INTERVAL"`
 }
 
 func LoadConfig(path string) (Config, error) {
@@ -17,6 +41,9 @@ func LoadConfig(path string) (Config, error) {
 
 	viper.AutomaticEnv()
 
+	// Without it, an unset DB_TX_MAX_RETRIES would turn the transaction retries off.
+	viper.SetDefault("DB_TX_MAX_RETRIES", DefaultDBTxMaxRetries)
+
 	if err := viper.ReadInConfig(); err != nil {
 		return Config{}, err
 	}
@@ -25,5 +52,9 @@ func LoadConfig(path string) (Config, error) {
 		return Config{}, err
 	}
 
+	if len(cfg.Currencies) == 0 {
+		cfg.Currencies = DefaultCurrencies
+	}
+
 	return cfg, nil
 }
@@ -0,0 +1,20 @@
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadConfigDefaultDBTxMaxRetries(t *testing.T) {
+	dir := t.TempDir()
+	err := os.WriteFile(filepath.Join(dir, "app.env"), []byte("DB_DRIVER=postgres\n"), 0o600)
+	require.NoError(t, err)
+
+	cfg, err := LoadConfig(dir)
+	require.NoError(t, err)
+	require.Equal(t, "postgres", cfg.DBDriver)
+	require.Equal(t, DefaultDBTxMaxRetries, cfg.DBTxMaxRetries)
+}
@@ -0,0 +1,64 @@
+package util
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"tech-school/money"
+)
+
+// DefaultCurrencies are the currencies enabled when the config doesn't list any.
+var DefaultCurrencies = []string{"USD", "EUR", "CAD"}
+
+var supportedCurrencies = struct {
+	sync.RWMutex
+	codes []string
+}{codes: DefaultCurrencies}
+
+// SetSupportedCurrencies replaces the registry of enabled currencies.
+// Every code must be a known ISO 4217 currency.
+func SetSupportedCurrencies(codes ...string) error {
+	if len(codes) == 0 {
+		return errors.New("at least one currency must be enabled")
+	}
+
+	enabled := make([]string, 0, len(codes))
+	seen := make(map[string]bool, len(codes))
+	for _, code := range codes {
+		if _, err := money.LookupCurrency(code); err != nil {
+			return err
+		}
+		if seen[code] {
+			return fmt.Errorf("currency %s is listed twice", code)
+		}
+		seen[code] = true
+		enabled = append(enabled, code)
+	}
+
+	supportedCurrencies.Lock()
+	defer supportedCurrencies.Unlock()
+
+	supportedCurrencies.codes = enabled
+
+	return nil
+}
+
+// SupportedCurrencies returns the enabled currency codes in registry order.
+func SupportedCurrencies() []string {
+	supportedCurrencies.RLock()
+	defer supportedCurrencies.RUnlock()
+
+	return append([]string(nil), supportedCurrencies.codes...)
+}
+
+// IsSupportedCurrency reports whether the currency is enabled.
+func IsSupportedCurrency(currency string) bool {
+	for _, code := range SupportedCurrencies() {
+		if code == currency {
+			return true
+		}
+	}
+
+	return false
+}
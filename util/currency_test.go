@@ -0,0 +1,36 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"tech-school/money"
+)
+
+func TestSupportedCurrencies(t *testing.T) {
+	defer func() {
+		require.NoError(t, SetSupportedCurrencies(DefaultCurrencies...))
+	}()
+
+	err := SetSupportedCurrencies("JPY", "GBP")
+	require.NoError(t, err)
+	require.Equal(t, []string{"JPY", "GBP"}, SupportedCurrencies())
+	require.True(t, IsSupportedCurrency("JPY"))
+	require.False(t, IsSupportedCurrency("USD"))
+
+	for i := 0; i < 10; i++ {
+		require.True(t, IsSupportedCurrency(RandomCurrency()))
+	}
+
+	err = SetSupportedCurrencies("XXX")
+	require.ErrorIs(t, err, money.ErrUnknownCurrency)
+
+	err = SetSupportedCurrencies("EUR", "EUR")
+	require.Error(t, err)
+
+	err = SetSupportedCurrencies()
+	require.Error(t, err)
+
+	require.Equal(t, []string{"JPY", "GBP"}, SupportedCurrencies())
+}
@@ -1,6 +1,7 @@
 package util
 
 import (
+	"fmt"
 	"math/rand"
 	"strings"
 	"time"
@@ -40,9 +41,14 @@ func RandomMoney() int64 {
 	return RandomInt(0, 1000)
 }
 
-// RandomCurrency generetes a random currency code
+// RandomCurrency generetes a random currency code among the supported currencies
 func RandomCurrency() string {
-	currencies := []string{"EUR", "USD", "CAD"}
+	currencies := SupportedCurrencies()
 
 	return currencies[rand.Intn(len(currencies))]
 }
+
+// RandomEmail generates a random email
+func RandomEmail() string {
+	return fmt.Sprintf("%s@email.com", RandomString(6))
+}
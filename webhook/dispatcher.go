@@ -0,0 +1,193 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"tech-school/backoff"
+	db "tech-school/db/sqlc"
+	"tech-school/outbox"
+)
+
+// Dispatcher POSTs the pending webhook deliveries to the URLs of their subscriptions.
+// The body is the event as published by the outbox relay, see outbox.Message, signed with the subscription secret.
+// Delivery is at least once. A failed delivery is retried with an exponential backoff, and is dead-lettered
+// after MaxAttempts attempts; it can still be replayed by its owner.
+type Dispatcher struct {
+	q      db.Querier
+	client *http.Client
+
+	// BatchSize is the maximum number of deliveries claimed at once.
+	BatchSize int32
+	// Lease is how long the claimed deliveries are hidden from other dispatchers while they are attempted.
+	Lease time.Duration
+	// MaxAttempts is the number of attempts after which a delivery is dead.
+	MaxAttempts int32
+	// BaseDelay is the backoff before retrying a delivery after its first failure. It doubles on every following failure.
+	BaseDelay time.Duration
+	// MaxDelay caps the backoff between two attempts.
+	MaxDelay time.Duration
+}
+
+// NewDispatcher creates a Dispatcher attempting the deliveries of q with the given client or, if it is nil,
+// with a client timing out after 10 seconds that only connects to public addresses and doesn't follow redirects.
+func NewDispatcher(q db.Querier, client *http.Client) *Dispatcher {
+	if client == nil {
+		client = newClient(10 * time.Second)
+	}
+
+	return &Dispatcher{
+		q:           q,
+		client:      client,
+		BatchSize:   100,
+		Lease:       5 * time.Minute,
+		MaxAttempts: 10,
+		BaseDelay:   30 * time.Second,
+		MaxDelay:    6 * time.Hour,
+	}
+}
+
+// DispatchBatch claims a batch of pending deliveries and attempts them, in ID order.
+// It returns the number of deliveries claimed. Deliveries still unattempted when the lease runs out are left to be claimed again.
+func (d *Dispatcher) DispatchBatch(ctx context.Context) (int, error) {
+	lockedUntil := time.Now().Add(d.Lease)
+
+	deliveries, err := d.q.ClaimWebhookDeliveries(ctx, db.ClaimWebhookDeliveriesParams{
+		LockedUntil:   lockedUntil,
+		MaxDeliveries: d.BatchSize,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	sort.Slice(deliveries, func(i, j int) bool { return deliveries[i].ID < deliveries[j].ID })
+
+	attemptCtx, cancel := context.WithDeadline(ctx, lockedUntil)
+	defer cancel()
+
+	for _, delivery := range deliveries {
+		if attemptCtx.Err() != nil {
+			break
+		}
+
+		status, err := d.deliver(attemptCtx, delivery)
+		responseStatus := sql.NullInt32{Int32: int32(status), Valid: status != 0}
+
+		if err == nil {
+			err = d.q.MarkWebhookDeliveryDelivered(ctx, db.MarkWebhookDeliveryDeliveredParams{
+				ID:             delivery.ID,
+				ResponseStatus: responseStatus,
+			})
+		} else {
+			err = d.q.MarkWebhookDeliveryFailed(ctx, d.failure(delivery, responseStatus, err))
+		}
+		if err != nil {
+			return len(deliveries), err
+		}
+	}
+
+	return len(deliveries), nil
+}
+
+// deliver POSTs the signed event to the subscription URL. It returns the response status, if any,
+// and an error unless the status is 2xx.
+func (d *Dispatcher) deliver(ctx context.Context, delivery db.ClaimWebhookDeliveriesRow) (int, error) {
+	body, err := json.Marshal(outbox.Message{
+		ID:          delivery.EventID,
+		Type:        delivery.EventType,
+		Version:     delivery.Version,
+		AggregateID: delivery.AggregateID,
+		Data:        delivery.Payload,
+		CreatedAt:   delivery.EventCreatedAt,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.Url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventIDHeader, strconv.FormatInt(delivery.EventID, 10))
+	req.Header.Set(EventTypeHeader, delivery.EventType)
+	req.Header.Set(TimestampHeader, strconv.FormatInt(now.Unix(), 10))
+	req.Header.Set(SignatureHeader, Sign(delivery.Secret, now, body))
+
+	res, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+
+	// Drain the body so that the connection can be reused.
+	_, _ = io.Copy(io.Discard, io.LimitReader(res.Body, 1<<16))
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return res.StatusCode, fmt.Errorf("responded %s", res.Status)
+	}
+
+	return res.StatusCode, nil
+}
+
+// failure returns the update recording a failed attempt of the delivery: it is retried after a backoff,
+// unless it has reached MaxAttempts.
+func (d *Dispatcher) failure(delivery db.ClaimWebhookDeliveriesRow, responseStatus sql.NullInt32, err error) db.MarkWebhookDeliveryFailedParams {
+	arg := db.MarkWebhookDeliveryFailedParams{
+		ID:             delivery.ID,
+		Status:         db.WebhookDeliveryStatusPending,
+		ResponseStatus: responseStatus,
+		LastError:      sql.NullString{String: err.Error(), Valid: true},
+		NextAttemptAt:  time.Now().Add(d.backoff(delivery.Attempts)),
+	}
+
+	if delivery.Attempts+1 >= d.MaxAttempts {
+		arg.Status = db.WebhookDeliveryStatusDead
+		arg.NextAttemptAt = time.Now()
+	}
+
+	return arg
+}
+
+// backoff returns the delay before the next attempt of a delivery that failed after the given number of attempts.
+func (d *Dispatcher) backoff(attempts int32) time.Duration {
+	return backoff.Exponential(d.BaseDelay, d.MaxDelay, int(attempts))
+}
+
+// Run attempts the pending deliveries every interval, until ctx is done.
+// A batch in progress when ctx is done is finished, so that its deliveries aren't left leased.
+// It is safe to run in several replicas at once, since each dispatcher claims different deliveries.
+func (d *Dispatcher) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			// Keep going while the batches are full, there may be more deliveries pending.
+			for ctx.Err() == nil {
+				n, err := d.DispatchBatch(context.Background())
+				if err != nil {
+					log.Printf("failed to dispatch webhook deliveries: %v", err)
+					break
+				}
+				if n < int(d.BatchSize) {
+					break
+				}
+			}
+		}
+	}
+}
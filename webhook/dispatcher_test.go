@@ -0,0 +1,179 @@
+package webhook
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+
+	mockdb "tech-school/db/mock"
+	db "tech-school/db/sqlc"
+	"tech-school/outbox"
+)
+
+func randomDelivery(url string, attempts int32) db.ClaimWebhookDeliveriesRow {
+	return db.ClaimWebhookDeliveriesRow{
+		ID:             7,
+		Attempts:       attempts,
+		Url:            url,
+		Secret:         "0123456789abcdef",
+		EventID:        42,
+		EventType:      db.EventTransferCompleted,
+		Version:        db.TransferEventVersion,
+		AggregateID:    420,
+		Payload:        json.RawMessage(`{"transfer_id":420}`),
+		EventCreatedAt: time.Now().Truncate(time.Second).UTC(),
+	}
+}
+
+// newReceiver starts a webhook receiver that verifies the deliveries as a subscriber would, then responds with status.
+func newReceiver(t *testing.T, delivery func() db.ClaimWebhookDeliveriesRow, status int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		expected := delivery()
+
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+
+		err = Verify(expected.Secret, r.Header.Get(TimestampHeader), r.Header.Get(SignatureHeader), body, time.Now(), time.Minute)
+		require.NoError(t, err)
+
+		require.Equal(t, http.MethodPost, r.Method)
+		require.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		require.Equal(t, strconv.FormatInt(expected.EventID, 10), r.Header.Get(EventIDHeader))
+		require.Equal(t, expected.EventType, r.Header.Get(EventTypeHeader))
+
+		var msg outbox.Message
+		require.NoError(t, json.Unmarshal(body, &msg))
+		require.Equal(t, outbox.Message{
+			ID:          expected.EventID,
+			Type:        expected.EventType,
+			Version:     expected.Version,
+			AggregateID: expected.AggregateID,
+			Data:        expected.Payload,
+			CreatedAt:   expected.EventCreatedAt,
+		}, msg)
+
+		w.WriteHeader(status)
+	}))
+}
+
+func TestDispatchBatch(t *testing.T) {
+	testCases := []struct {
+		name       string
+		status     int
+		attempts   int32
+		buildStubs func(t *testing.T, store *mockdb.MockStore, dispatcher *Dispatcher)
+	}{
+		{
+			name:   "Delivered",
+			status: http.StatusOK,
+			buildStubs: func(t *testing.T, store *mockdb.MockStore, dispatcher *Dispatcher) {
+				store.EXPECT().
+					MarkWebhookDeliveryDelivered(gomock.Any(), gomock.Eq(db.MarkWebhookDeliveryDeliveredParams{
+						ID:             7,
+						ResponseStatus: sql.NullInt32{Int32: http.StatusOK, Valid: true},
+					})).
+					Times(1).
+					Return(nil)
+			},
+		},
+		{
+			name:     "Failed",
+			status:   http.StatusServiceUnavailable,
+			attempts: 2,
+			buildStubs: func(t *testing.T, store *mockdb.MockStore, dispatcher *Dispatcher) {
+				start := time.Now()
+				store.EXPECT().
+					MarkWebhookDeliveryFailed(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.MarkWebhookDeliveryFailedParams) error {
+						require.Equal(t, int64(7), arg.ID)
+						require.Equal(t, db.WebhookDeliveryStatusPending, arg.Status)
+						require.Equal(t, sql.NullInt32{Int32: http.StatusServiceUnavailable, Valid: true}, arg.ResponseStatus)
+						require.True(t, arg.LastError.Valid)
+						// The third failure is retried after 2^2 times the base delay.
+						require.WithinDuration(t, start.Add(4*dispatcher.BaseDelay), arg.NextAttemptAt, time.Second)
+						return nil
+					})
+			},
+		},
+		{
+			name:     "DeadLettered",
+			status:   http.StatusBadRequest,
+			attempts: 9,
+			buildStubs: func(t *testing.T, store *mockdb.MockStore, dispatcher *Dispatcher) {
+				store.EXPECT().
+					MarkWebhookDeliveryFailed(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.MarkWebhookDeliveryFailedParams) error {
+						require.Equal(t, db.WebhookDeliveryStatusDead, arg.Status)
+						require.Equal(t, sql.NullInt32{Int32: http.StatusBadRequest, Valid: true}, arg.ResponseStatus)
+						return nil
+					})
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+
+			var receiverURL string
+			delivery := func() db.ClaimWebhookDeliveriesRow { return randomDelivery(receiverURL, tc.attempts) }
+
+			receiver := newReceiver(t, delivery, tc.status)
+			defer receiver.Close()
+			receiverURL = receiver.URL
+
+			dispatcher := NewDispatcher(store, receiver.Client())
+
+			store.EXPECT().
+				ClaimWebhookDeliveries(gomock.Any(), gomock.Any()).
+				Times(1).
+				Return([]db.ClaimWebhookDeliveriesRow{delivery()}, nil)
+			tc.buildStubs(t, store, dispatcher)
+
+			n, err := dispatcher.DispatchBatch(context.Background())
+			require.NoError(t, err)
+			require.Equal(t, 1, n)
+		})
+	}
+}
+
+func TestDispatchBatchUnreachable(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+
+	receiver := httptest.NewServer(http.NotFoundHandler())
+	receiver.Close()
+
+	store.EXPECT().
+		ClaimWebhookDeliveries(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return([]db.ClaimWebhookDeliveriesRow{randomDelivery(receiver.URL, 0)}, nil)
+	store.EXPECT().
+		MarkWebhookDeliveryFailed(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(_ context.Context, arg db.MarkWebhookDeliveryFailedParams) error {
+			require.Equal(t, db.WebhookDeliveryStatusPending, arg.Status)
+			require.False(t, arg.ResponseStatus.Valid)
+			require.True(t, arg.LastError.Valid)
+			return nil
+		})
+
+	_, err := NewDispatcher(store, nil).DispatchBatch(context.Background())
+	require.NoError(t, err)
+}
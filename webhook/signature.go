@@ -0,0 +1,70 @@
+// Package webhook delivers the outbox events to the webhooks subscribed to them by account owners.
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Headers sent with every delivery.
+const (
+	// EventIDHeader carries the ID of the event, which receivers use to deduplicate deliveries.
+	EventIDHeader = "X-Webhook-Event-ID"
+	// EventTypeHeader carries the type of the event.
+	EventTypeHeader = "X-Webhook-Event-Type"
+	// TimestampHeader carries the time of the delivery attempt, in Unix seconds.
+	TimestampHeader = "X-Webhook-Timestamp"
+	// SignatureHeader carries the signature of the timestamp and body, see Sign.
+	SignatureHeader = "X-Webhook-Signature"
+)
+
+const signaturePrefix = "v1="
+
+var (
+	// ErrInvalidSignature is returned when a delivery isn't signed with the secret of the subscription.
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+	// ErrTimestampOutOfTolerance is returned when a delivery is too old, or too far in the future, to be trusted.
+	ErrTimestampOutOfTolerance = errors.New("webhook timestamp out of tolerance")
+)
+
+// Sign returns the signature of a delivery: the hex-encoded HMAC-SHA256, keyed with the subscription secret,
+// of the timestamp in Unix seconds, a dot and the body, prefixed with the scheme version "v1=".
+// Signing the timestamp lets receivers reject replayed deliveries.
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp.Unix(), 10)))
+	mac.Write([]byte{'.'})
+	mac.Write(body)
+
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks the timestamp and signature headers of a delivery, as a receiver would.
+// The timestamp must be within tolerance of now.
+func Verify(secret, timestamp, signature string, body []byte, now time.Time, tolerance time.Duration) error {
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: %q", ErrTimestampOutOfTolerance, timestamp)
+	}
+
+	sent := time.Unix(unix, 0)
+	if sent.Before(now.Add(-tolerance)) || sent.After(now.Add(tolerance)) {
+		return fmt.Errorf("%w: sent at %s", ErrTimestampOutOfTolerance, sent.UTC())
+	}
+
+	if !strings.HasPrefix(signature, signaturePrefix) {
+		return ErrInvalidSignature
+	}
+
+	if !hmac.Equal([]byte(signature), []byte(Sign(secret, sent, body))) {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
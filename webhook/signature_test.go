@@ -0,0 +1,114 @@
+package webhook
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSign(t *testing.T) {
+	timestamp := time.Unix(1700000000, 0)
+	body := []byte(`{"id":1}`)
+
+	// echo -n '1700000000.{"id":1}' | openssl dgst -sha256 -hmac secret
+	require.Equal(t, "v1=3dd1b9aef568d75f6790a84bd2e5dfa1f44409eef3cbdbd3f10b837376100c11", Sign("secret", timestamp, body))
+
+	require.NotEqual(t, Sign("secret", timestamp, body), Sign("other", timestamp, body))
+	require.NotEqual(t, Sign("secret", timestamp, body), Sign("secret", timestamp.Add(time.Second), body))
+}
+
+func TestVerify(t *testing.T) {
+	now := time.Now()
+	secret := "0123456789abcdef"
+	body := []byte(`{"id":1,"type":"TransferCompleted"}`)
+
+	unix := func(t time.Time) string {
+		return strconv.FormatInt(t.Unix(), 10)
+	}
+
+	testCases := []struct {
+		name      string
+		secret    string
+		timestamp string
+		signature string
+		body      []byte
+		expected  error
+	}{
+		{
+			name:      "OK",
+			secret:    secret,
+			timestamp: unix(now),
+			signature: Sign(secret, now, body),
+			body:      body,
+		},
+		{
+			name:      "WrongSecret",
+			secret:    "fedcba9876543210",
+			timestamp: unix(now),
+			signature: Sign(secret, now, body),
+			body:      body,
+			expected:  ErrInvalidSignature,
+		},
+		{
+			name:      "TamperedBody",
+			secret:    secret,
+			timestamp: unix(now),
+			signature: Sign(secret, now, body),
+			body:      []byte(`{"id":2,"type":"TransferCompleted"}`),
+			expected:  ErrInvalidSignature,
+		},
+		{
+			name:      "TamperedTimestamp",
+			secret:    secret,
+			timestamp: unix(now.Add(time.Second)),
+			signature: Sign(secret, now, body),
+			body:      body,
+			expected:  ErrInvalidSignature,
+		},
+		{
+			name:      "MissingPrefix",
+			secret:    secret,
+			timestamp: unix(now),
+			signature: Sign(secret, now, body)[len(signaturePrefix):],
+			body:      body,
+			expected:  ErrInvalidSignature,
+		},
+		{
+			name:      "Replayed",
+			secret:    secret,
+			timestamp: unix(now.Add(-10 * time.Minute)),
+			signature: Sign(secret, now.Add(-10*time.Minute), body),
+			body:      body,
+			expected:  ErrTimestampOutOfTolerance,
+		},
+		{
+			name:      "FromTheFuture",
+			secret:    secret,
+			timestamp: unix(now.Add(10 * time.Minute)),
+			signature: Sign(secret, now.Add(10*time.Minute), body),
+			body:      body,
+			expected:  ErrTimestampOutOfTolerance,
+		},
+		{
+			name:      "InvalidTimestamp",
+			secret:    secret,
+			timestamp: "yesterday",
+			signature: Sign(secret, now, body),
+			body:      body,
+			expected:  ErrTimestampOutOfTolerance,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := Verify(tc.secret, tc.timestamp, tc.signature, tc.body, now, 5*time.Minute)
+			if tc.expected == nil {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorIs(t, err, tc.expected)
+		})
+	}
+}
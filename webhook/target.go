@@ -0,0 +1,128 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+// ErrForbiddenTarget is returned for webhook targets on a loopback, private, link-local or otherwise
+// non-public address: deliveries to them would let subscribers probe the internal network.
+var ErrForbiddenTarget = errors.New("webhook target is not a public address")
+
+// forbiddenNetworks are the non-public networks not already covered by the net.IP predicates.
+var forbiddenNetworks = mustParseCIDRs(
+	"0.0.0.0/8",      // "this" network
+	"100.64.0.0/10",  // carrier-grade NAT
+	"192.0.0.0/24",   // IETF protocol assignments
+	"198.18.0.0/15",  // benchmarking
+	"240.0.0.0/4",    // reserved, and broadcast
+	"64:ff9b:1::/48", // local-use NAT64
+	"2001:db8::/32",  // documentation
+)
+
+// Resolver looks up the addresses of a host, as net.DefaultResolver does.
+type Resolver interface {
+	LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error)
+}
+
+// ValidateURL checks that rawURL is an absolute http or https URL whose host only resolves to public addresses.
+// It returns an error wrapping ErrForbiddenTarget otherwise. The check is repeated when the deliveries are dialed,
+// see NewDispatcher, since the host may resolve to other addresses later on.
+func ValidateURL(ctx context.Context, resolver Resolver, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return fmt.Errorf("url %q must be an absolute http or https URL", rawURL)
+	}
+
+	host := u.Hostname()
+
+	if ip := net.ParseIP(host); ip != nil {
+		return checkIP(ip)
+	}
+
+	addrs, err := resolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return fmt.Errorf("failed to resolve host %q: %w", host, err)
+	}
+
+	for _, addr := range addrs {
+		if err := checkIP(addr.IP); err != nil {
+			return fmt.Errorf("host %q: %w", host, err)
+		}
+	}
+
+	return nil
+}
+
+// newClient returns a client that only connects to public addresses, checked once resolved,
+// and that doesn't follow redirects, which could lead anywhere.
+func newClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: 5 * time.Second,
+		Control: func(_, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+
+			ip := net.ParseIP(host)
+			if ip == nil {
+				return fmt.Errorf("%w: %s", ErrForbiddenTarget, host)
+			}
+
+			return checkIP(ip)
+		},
+	}
+
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			// No proxy: the dialed address must be the one of the target.
+			Proxy:                 nil,
+			DialContext:           dialer.DialContext,
+			ForceAttemptHTTP2:     true,
+			MaxIdleConns:          100,
+			IdleConnTimeout:       90 * time.Second,
+			TLSHandshakeTimeout:   5 * time.Second,
+			ExpectContinueTimeout: time.Second,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// checkIP returns an error wrapping ErrForbiddenTarget if ip is not a public unicast address.
+func checkIP(ip net.IP) error {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return fmt.Errorf("%w: %s", ErrForbiddenTarget, ip)
+	}
+
+	for _, network := range forbiddenNetworks {
+		if network.Contains(ip) {
+			return fmt.Errorf("%w: %s", ErrForbiddenTarget, ip)
+		}
+	}
+
+	return nil
+}
+
+func mustParseCIDRs(cidrs ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, len(cidrs))
+	for i, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks[i] = network
+	}
+
+	return networks
+}
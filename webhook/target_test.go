@@ -0,0 +1,111 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakeResolver resolves the hosts it knows, and fails on the others.
+type fakeResolver map[string][]string
+
+func (r fakeResolver) LookupIPAddr(_ context.Context, host string) ([]net.IPAddr, error) {
+	ips, ok := r[host]
+	if !ok {
+		return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+
+	addrs := make([]net.IPAddr, len(ips))
+	for i, ip := range ips {
+		addrs[i] = net.IPAddr{IP: net.ParseIP(ip)}
+	}
+
+	return addrs, nil
+}
+
+func TestValidateURL(t *testing.T) {
+	resolver := fakeResolver{
+		"example.com":          {"93.184.216.34", "2606:2800:220:1:248:1893:25c8:1946"},
+		"metadata.example.com": {"169.254.169.254"},
+		"mixed.example.com":    {"93.184.216.34", "10.0.0.1"},
+		"localhost":            {"127.0.0.1", "::1"},
+	}
+
+	testCases := []struct {
+		name      string
+		url       string
+		forbidden bool
+		invalid   bool
+	}{
+		{name: "PublicHost", url: "https://example.com/hooks"},
+		{name: "PublicIP", url: "http://93.184.216.34:8080/hooks"},
+		{name: "PublicIPv6", url: "https://[2606:2800:220:1:248:1893:25c8:1946]/hooks"},
+		{name: "Loopback", url: "http://127.0.0.1/hooks", forbidden: true},
+		{name: "LoopbackIPv6", url: "http://[::1]/hooks", forbidden: true},
+		{name: "MappedLoopback", url: "http://[::ffff:127.0.0.1]/hooks", forbidden: true},
+		{name: "Localhost", url: "http://localhost:8080/hooks", forbidden: true},
+		{name: "Private", url: "http://10.1.2.3/hooks", forbidden: true},
+		{name: "Private172", url: "http://172.16.0.1/hooks", forbidden: true},
+		{name: "Private192", url: "http://192.168.1.1/hooks", forbidden: true},
+		{name: "UniqueLocalIPv6", url: "http://[fd00::1]/hooks", forbidden: true},
+		{name: "LinkLocal", url: "http://169.254.169.254/latest/meta-data", forbidden: true},
+		{name: "LinkLocalHost", url: "http://metadata.example.com/", forbidden: true},
+		{name: "CarrierGradeNAT", url: "http://100.64.0.1/hooks", forbidden: true},
+		{name: "Unspecified", url: "http://0.0.0.0/hooks", forbidden: true},
+		{name: "AnyPrivateAddress", url: "https://mixed.example.com/hooks", forbidden: true},
+		{name: "UnknownHost", url: "https://unknown.example.com/hooks", invalid: true},
+		{name: "NotHTTP", url: "ftp://example.com/hooks", invalid: true},
+		{name: "NoHost", url: "https:///hooks", invalid: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateURL(context.Background(), resolver, tc.url)
+
+			switch {
+			case tc.forbidden:
+				require.ErrorIs(t, err, ErrForbiddenTarget)
+			case tc.invalid:
+				require.Error(t, err)
+				require.False(t, errors.Is(err, ErrForbiddenTarget))
+			default:
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestClientRefusesPrivateAddresses(t *testing.T) {
+	var called bool
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer target.Close()
+
+	_, err := newClient(time.Second).Post(target.URL, "application/json", nil)
+	require.ErrorIs(t, err, ErrForbiddenTarget)
+	require.False(t, called)
+}
+
+func TestClientDoesNotFollowRedirects(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://169.254.169.254/latest/meta-data", http.StatusFound)
+	}))
+	defer target.Close()
+
+	// The test server is on a loopback address: only the redirect policy of the client is checked here.
+	client := newClient(time.Second)
+	client.Transport = target.Client().Transport
+
+	res, err := client.Post(target.URL, "application/json", nil)
+	require.NoError(t, err)
+	defer res.Body.Close()
+
+	require.Equal(t, http.StatusFound, res.StatusCode)
+}